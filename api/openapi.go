@@ -0,0 +1,26 @@
+// Package api contains the OpenAPI document describing the API, which is served to the integrators.
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// document is the hand-maintained OpenAPI document, which must be kept along with the routes registered by
+// the Setup functions.
+//
+//go:embed openapi.json
+var document []byte
+
+// Setup setups the route serving the OpenAPI document, under the given API base path.
+func Setup(router *chi.Mux, basePath string) {
+	router.Get(basePath+"/openapi.json", GetDocument)
+}
+
+// GetDocument handles the request to return the OpenAPI document.
+func GetDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(document)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/auth"
+	"hospital-booking/internal/calendar"
+	"hospital-booking/internal/configs"
+	"hospital-booking/internal/mock"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		SecuritySchemes map[string]struct {
+			Type   string `json:"type"`
+			Scheme string `json:"scheme"`
+		} `json:"securitySchemes"`
+	} `json:"components"`
+}
+
+func mustGetDocument(t *testing.T) openAPIDocument {
+	router := chi.NewRouter()
+	Setup(router, "/api/v1")
+
+	req, _ := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+	}
+	document := openAPIDocument{}
+	if err := json.NewDecoder(recorder.Body).Decode(&document); err != nil {
+		t.Fatalf("response body is not a valid JSON document: %v", err)
+	}
+	return document
+}
+
+func TestGetDocument(t *testing.T) {
+	document := mustGetDocument(t)
+	if !strings.HasPrefix(document.OpenAPI, "3.") {
+		t.Errorf("openapi version is incorrect, got %s, want 3.x", document.OpenAPI)
+	}
+	if _, found := document.Paths["/api/v1/auth/login"]["post"]; !found {
+		t.Errorf("document should describe the login path")
+	}
+	bearerAuth, found := document.Components.SecuritySchemes["bearerAuth"]
+	if !found || bearerAuth.Type != "http" || bearerAuth.Scheme != "bearer" {
+		t.Errorf("document should describe the JWT bearer security scheme, got %+v", document.Components.SecuritySchemes)
+	}
+}
+
+func TestDocumentDescribesTheRoutes(t *testing.T) {
+	document := mustGetDocument(t)
+	config := configs.MustLoad("./../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+	logger := log.New(ioutil.Discard, "", log.LstdFlags)
+
+	router := chi.NewRouter()
+	auth.Setup(router, logger, config, dbConn)
+	calendar.Setup(router, logger, auth.NewService(config, dbConn), config, dbConn)
+
+	err := chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if _, found := document.Paths[route][strings.ToLower(method)]; !found {
+			t.Errorf("document should describe the route %s %s", method, route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,3 +1,13 @@
+-- Versions of the schema migrations applied, checked by the API on startup
+CREATE TABLE schema_migrations
+(
+    version    BIGINT    NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT now(),
+    CONSTRAINT schema_migrations_version_pk PRIMARY KEY (version)
+);
+
+INSERT INTO schema_migrations (version) VALUES (1), (2), (3), (4), (5), (6), (7), (8), (9);
+
 CREATE TABLE tb_user
 (
     id       BIGSERIAL    NOT NULL,
@@ -14,10 +24,12 @@ CREATE TABLE tb_patient
 (
     id           BIGSERIAL    NOT NULL,
     uuid         UUID         NOT NULL,
-    user_id      BIGINT       NOT NULL,
+    user_id      BIGINT,
     name         VARCHAR(250) NOT NULL,
     email        VARCHAR(250) NOT NULL,
     mobile_phone VARCHAR(12),
+    consent_given BOOLEAN     NOT NULL DEFAULT FALSE,
+    deleted_at   TIMESTAMP,
     CONSTRAINT tb_patient_id_pk PRIMARY KEY (id),
     CONSTRAINT tb_patient_uuid_uk UNIQUE (uuid),
     CONSTRAINT tb_patient_email_uk UNIQUE (email),
@@ -28,17 +40,23 @@ CREATE TABLE tb_doctor
 (
     id           BIGSERIAL    NOT NULL,
     uuid         UUID         NOT NULL,
-    user_id      BIGINT       NOT NULL,
+    user_id      BIGINT,
     name         VARCHAR(250) NOT NULL,
     email        VARCHAR(250) NOT NULL,
     mobile_phone VARCHAR(12),
     specialty    VARCHAR(259),
+    active       BOOLEAN      NOT NULL DEFAULT TRUE,
+    deleted_at   TIMESTAMP,
     CONSTRAINT tb_doctor_id_pk PRIMARY KEY (id),
     CONSTRAINT tb_doctor_uuid_uk UNIQUE (uuid),
     CONSTRAINT tb_doctor_email_uk UNIQUE (email),
     CONSTRAINT tb_doctor_user_id_fk FOREIGN KEY (user_id) REFERENCES tb_user (id)
 );
 
+-- Trigram index used by the case-insensitive (ILIKE) specialty search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX tb_doctor_specialty_trgm_idx ON tb_doctor USING gin (specialty gin_trgm_ops);
+
 CREATE TABLE tb_block_period
 (
     id          BIGSERIAL NOT NULL,
@@ -47,11 +65,25 @@ CREATE TABLE tb_block_period
     start_date  TIMESTAMP NOT NULL,
     end_date    TIMESTAMP NOT NULL,
     description VARCHAR(250),
+    inclusive   BOOLEAN   NOT NULL DEFAULT FALSE,
     CONSTRAINT tb_block_period_id_pk PRIMARY KEY (id),
     CONSTRAINT tb_block_period_uuid_uk UNIQUE (uuid),
     CONSTRAINT tb_block_period_doctor_id_fk FOREIGN KEY (doctor_id) REFERENCES tb_doctor (id)
 );
 
+CREATE TABLE tb_availability_exception
+(
+    id          BIGSERIAL NOT NULL,
+    uuid        UUID      NOT NULL,
+    doctor_id   BIGINT    NOT NULL,
+    start_date  TIMESTAMP NOT NULL,
+    end_date    TIMESTAMP NOT NULL,
+    description VARCHAR(250),
+    CONSTRAINT tb_availability_exception_id_pk PRIMARY KEY (id),
+    CONSTRAINT tb_availability_exception_uuid_uk UNIQUE (uuid),
+    CONSTRAINT tb_availability_exception_doctor_id_fk FOREIGN KEY (doctor_id) REFERENCES tb_doctor (id)
+);
+
 CREATE TABLE tb_appointment
 (
     id         BIGSERIAL NOT NULL,
@@ -59,10 +91,39 @@ CREATE TABLE tb_appointment
     doctor_id  BIGINT    NOT NULL,
     patient_id BIGINT    NOT NULL,
     date       TIMESTAMP NOT NULL,
+    notes      VARCHAR(500),
+    deleted_at TIMESTAMP,
+    status     VARCHAR(20) NOT NULL DEFAULT 'REQUESTED',
+    duration   SMALLINT  NOT NULL DEFAULT 1,
+    version    INTEGER   NOT NULL DEFAULT 1,
     CONSTRAINT tb_appointment_id_pk PRIMARY KEY (id),
     CONSTRAINT tb_appointment_uuid_uk UNIQUE (uuid),
     CONSTRAINT tb_appointment_doctor_id_fk FOREIGN KEY (doctor_id) REFERENCES tb_doctor (id),
-    CONSTRAINT tb_appointment_patient_id_fk FOREIGN KEY (patient_id) REFERENCES tb_doctor (id)
+    CONSTRAINT tb_appointment_patient_id_fk FOREIGN KEY (patient_id) REFERENCES tb_patient (id)
+);
+
+CREATE TABLE tb_idempotency
+(
+    id               BIGSERIAL    NOT NULL,
+    idempotency_key  VARCHAR(255) NOT NULL,
+    patient_id       BIGINT       NOT NULL,
+    appointment_uuid UUID         NOT NULL,
+    created_at       TIMESTAMP    NOT NULL DEFAULT now(),
+    CONSTRAINT tb_idempotency_id_pk PRIMARY KEY (id),
+    CONSTRAINT tb_idempotency_patient_id_key_uk UNIQUE (patient_id, idempotency_key),
+    CONSTRAINT tb_idempotency_patient_id_fk FOREIGN KEY (patient_id) REFERENCES tb_patient (id),
+    CONSTRAINT tb_idempotency_appointment_uuid_fk FOREIGN KEY (appointment_uuid) REFERENCES tb_appointment (uuid)
+);
+
+-- Record of every login attempt, for security review
+CREATE TABLE tb_auth_audit
+(
+    id         BIGSERIAL    NOT NULL,
+    email      VARCHAR(250) NOT NULL,
+    success    BOOLEAN      NOT NULL,
+    ip         VARCHAR(45),
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+    CONSTRAINT tb_auth_audit_id_pk PRIMARY KEY (id)
 );
 
 
@@ -72,11 +133,11 @@ INSERT INTO tb_user (uuid, email, password, role) VALUES
 ('f5ec116d-7ed6-4c3c-850a-cbd91b203381', 'doctor@hospital.com', '$2a$10$mgvh1tur98fACPDMtKNao.KrdxdXRCttfmLn9QDnehpXpZ1vRaAZG', 'DOCTOR');
 
 -- Seeding patients
-INSERT INTO tb_patient (uuid, user_id, name, email, mobile_phone)
-SELECT '672b8ea1-5b09-4974-b97b-afb623648789', u.id, 'John Doe', 'patient@hospital.com', '351123123123'
+INSERT INTO tb_patient (uuid, user_id, name, email, mobile_phone, consent_given)
+SELECT '672b8ea1-5b09-4974-b97b-afb623648789', u.id, 'John Doe', 'patient@hospital.com', '351123123123', TRUE
 FROM tb_user u WHERE u.uuid = '9f1aab10-dc04-4ab5-9911-87da9b6a9c76';
 
 -- Seeding doctors
 INSERT INTO tb_doctor (uuid, user_id, name, email, mobile_phone, specialty)
-SELECT '293691a7-9d90-47f9-a502-ff196f9d50e0', u.id, 'Doe John', 'doctor@hospital.com', '351351351351', 'Cardiologist'
+SELECT '293691a7-9d90-47f9-a502-ff196f9d50e0', u.id, 'Doe John', 'doctor@hospital.com', '351351351351', 'cardiology'
 FROM tb_user u WHERE u.uuid = 'f5ec116d-7ed6-4c3c-850a-cbd91b203381';
\ No newline at end of file
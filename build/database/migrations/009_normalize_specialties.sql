@@ -0,0 +1,22 @@
+-- Maps the free-text specialties stored before they were normalized, e.g. 'Cardiologist', to the known ones, so the
+-- specialty search still finds the doctors registered back then. Unknown values are only trimmed and lowercased.
+BEGIN;
+
+UPDATE tb_doctor
+SET specialty = CASE
+    WHEN lower(trim(specialty)) LIKE 'cardio%' THEN 'cardiology'
+    WHEN lower(trim(specialty)) LIKE 'dermato%' THEN 'dermatology'
+    WHEN lower(trim(specialty)) LIKE 'gyn%' THEN 'gynecology'
+    WHEN lower(trim(specialty)) LIKE 'neuro%' THEN 'neurology'
+    WHEN lower(trim(specialty)) LIKE 'onco%' THEN 'oncology'
+    WHEN lower(trim(specialty)) LIKE 'ophthalm%' THEN 'ophthalmology'
+    WHEN lower(trim(specialty)) LIKE 'orthop%' THEN 'orthopedics'
+    WHEN lower(trim(specialty)) LIKE 'pediatr%' OR lower(trim(specialty)) LIKE 'paediatr%' THEN 'pediatrics'
+    WHEN lower(trim(specialty)) LIKE 'psychiatr%' THEN 'psychiatry'
+    ELSE lower(trim(specialty))
+END
+WHERE specialty IS NOT NULL;
+
+INSERT INTO schema_migrations (version) VALUES (9);
+
+COMMIT;
@@ -4,61 +4,73 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
-	"encoding/gob"
 	"encoding/pem"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
-	"os"
+	"path/filepath"
 )
 
-var dir = flag.String("dir", "", "Directory where the keys will be stored")
+// minBits is the minimum size of the generated RSA keys, as smaller ones are no longer considered safe.
+const minBits = 2048
 
-func writeFile(filename string, key interface{}) {
-	file, err := os.Create(filename)
+const (
+	privateKeyFile = "private.pem"
+	publicKeyFile  = "public.pem"
+)
+
+var (
+	dir  = flag.String("dir", "configs", "Directory where the keys will be stored")
+	bits = flag.Int("bits", minBits, "Size of the RSA keys, in bits")
+)
+
+// encodePrivateKey encodes the given private key as a PKCS1 PEM block.
+func encodePrivateKey(privateKey *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+}
+
+// encodePublicKey encodes the given public key as a PKIX PEM block, which is enough to verify the tokens.
+func encodePublicKey(publicKey *rsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
 	if err != nil {
-		log.Fatalln(err)
-	}
-	encoder := gob.NewEncoder(file)
-	if err = encoder.Encode(key); err != nil {
-		log.Fatalln(err)
-		return
-	}
-	if err = file.Close(); err != nil {
-		log.Fatalln(err)
-		return
+		return nil, err
 	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}), nil
 }
 
-func main() {
-	flag.Parse()
-	if *dir == "" {
-		log.Fatal("no directory was given")
+// generateKeys generates a new RSA key of the given size, writing its private and public PEM files into the given
+// directory. The private one is the file given to the service through PRIVATE_KEY_FILE.
+func generateKeys(dir string, bits int) error {
+	if bits < minBits {
+		return fmt.Errorf("the keys must have at least %d bits", minBits)
 	}
-
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-
-	publicKey := &privateKey.PublicKey
-
-	writeFile(fmt.Sprintf("%s/%s", *dir, "private.key"), privateKey)
-	writeFile(fmt.Sprintf("%s/%s", *dir, "public.key"), publicKey)
-
-	pemKey := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	if err = ioutil.WriteFile(filepath.Join(dir, privateKeyFile), encodePrivateKey(privateKey), 0600); err != nil {
+		return err
 	}
-
-	pemFile, err := os.Create(fmt.Sprintf("%s/%s", *dir, "private.pem"))
+	publicPEM, err := encodePublicKey(&privateKey.PublicKey)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	if err = pem.Encode(pemFile, pemKey); err != nil {
-		log.Fatalln(err)
+	return ioutil.WriteFile(filepath.Join(dir, publicKeyFile), publicPEM, 0644)
+}
+
+func main() {
+	flag.Parse()
+	if *dir == "" {
+		log.Fatal("no directory was given")
 	}
-	if err = pemFile.Close(); err != nil {
+	if err := generateKeys(*dir, *bits); err != nil {
 		log.Fatalln(err)
 	}
 }
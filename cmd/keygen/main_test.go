@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"hospital-booking/internal/configs"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodePublicKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM, err := encodePublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(publicPEM)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		t.Fatalf("the public key should be encoded as a PUBLIC KEY PEM block, got %s", publicPEM)
+	}
+	parsedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, isRSA := parsedKey.(*rsa.PublicKey)
+	if !isRSA {
+		t.Fatalf("the public key should be an RSA key, got %T", parsedKey)
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		t.Error("the public key should match the private key")
+	}
+}
+
+func TestGeneratedKeysAreLoaded(t *testing.T) {
+	dir := t.TempDir()
+	if err := generateKeys(dir, minBits); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.json")
+	configJSON := fmt.Sprintf(`{"port": 8080, "database_driver": "postgres", "database_dsn": "postgresql://localhost", "private_key_file": %q}`, filepath.Join(dir, privateKeyFile))
+	if err := ioutil.WriteFile(configFile, []byte(configJSON), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := configs.Load(configFile)
+	if err != nil {
+		t.Fatalf("the generated private key should be loaded: %v", err)
+	}
+	publicPEM, err := ioutil.ReadFile(filepath.Join(dir, publicKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(publicPEM)
+	if block == nil {
+		t.Fatal("the generated public key should be a PEM block")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !publicKey.(*rsa.PublicKey).Equal(config.PrivateKey().Public()) {
+		t.Error("the generated public key should match the loaded private key")
+	}
+}
+
+func TestGenerateKeysTooSmall(t *testing.T) {
+	if err := generateKeys(t.TempDir(), 1024); err == nil {
+		t.Error("keys smaller than the minimum should be rejected")
+	}
+}
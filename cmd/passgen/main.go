@@ -7,15 +7,21 @@ import (
 	"log"
 )
 
-var pass = flag.String("pass", "", "Password to encrypt")
+var (
+	pass   = flag.String("pass", "", "Password to encrypt")
+	hasher = flag.String("hasher", auth.BcryptHasher, "Algorithm used to hash the password, either bcrypt or argon2id")
+)
 
 func main() {
 	flag.Parse()
 	if *pass == "" {
 		log.Fatal("no password was given")
 	}
+	if *hasher != auth.BcryptHasher && *hasher != auth.Argon2idHasher {
+		log.Fatalf("the hasher %q must be either %s or %s", *hasher, auth.BcryptHasher, auth.Argon2idHasher)
+	}
 
-	passHash, err := auth.EncryptPassword(*pass)
+	passHash, err := auth.NewPasswordHasher(*hasher).Hash(*pass)
 	if err != nil {
 		log.Fatal(err)
 	}
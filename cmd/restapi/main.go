@@ -1,14 +1,27 @@
 package main
 
 import (
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"hospital-booking/api"
+	"hospital-booking/internal/apierrors"
 	"hospital-booking/internal/auth"
+	"hospital-booking/internal/bodylimit"
 	"hospital-booking/internal/calendar"
+	"hospital-booking/internal/compression"
 	"hospital-booking/internal/configs"
+	"hospital-booking/internal/contenttype"
+	"hospital-booking/internal/cors"
 	"hospital-booking/internal/database"
+	"hospital-booking/internal/health"
+	"hospital-booking/internal/inflight"
+	"hospital-booking/internal/logging"
 	"hospital-booking/internal/metrics"
+	"hospital-booking/internal/timeout"
 	"log"
 	"net/http"
 	"os"
@@ -16,6 +29,9 @@ import (
 	"syscall"
 	"time"
 
+	// embeds the timezone database, so the clinic timezone can be loaded on images without it
+	_ "time/tzdata"
+
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/go-chi/chi/v5"
@@ -24,6 +40,16 @@ import (
 
 var configPath = flag.String("config", "", "Config file path")
 
+const (
+	ErrRouteNotFound    = "route not found"
+	ErrMethodNotAllowed = "method not allowed"
+)
+
+const (
+	CodeRouteNotFound    = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+)
+
 // loadConfigurations loads system configurations based on the given config file.
 func loadConfigurations() configs.Config {
 	config, err := configs.Load(*configPath)
@@ -48,40 +74,68 @@ func main() {
 	config := loadConfigurations()
 	dbConn := createDBConnection(config)
 
+	// Refuse to serve while the database schema is behind the code
+	if err := health.CheckSchema(context.Background(), dbConn); err != nil {
+		log.Fatal(err)
+	}
+
 	// Init Authorizer service
 	authorizer := auth.NewService(config, dbConn)
 
 	// Init error logger
-	logger := log.New(os.Stdout, "", log.LstdFlags)
+	logger := logging.WithLevel(logging.New(config.LogFormat(), os.Stdout), logging.Level(config.LogLevel()))
+
+	// Tracks the requests being handled, so the ones cut off on shutdown are known
+	requests := inflight.NewCounter()
 
 	// Setup the HTTP router
 	router := chi.NewRouter()
 	router.Use(middleware.Heartbeat("/health"))
+	router.Use(requests.Middleware)
+	// reuses the X-Request-ID given by an upstream gateway, if any, so the logs can be correlated
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
+	router.Use(cors.Middleware(cors.WithAllowedOrigins(config.AllowedOrigins()...)))
 	router.Use(metrics.PrometheusMiddleware)
+	router.Use(bodylimit.Middleware(config.MaxRequestBodyBytes()))
+	router.Use(compression.Middleware(gzip.DefaultCompression))
 	router.Use(middleware.SetHeader("Content-type", "application/json"))
+	router.Use(contenttype.Middleware)
+	router.Use(timeout.Middleware(config.RequestTimeout()))
+	if config.DebugLogBodies() {
+		router.Use(logging.BodyMiddleware(logger))
+	}
+
+	// Answers the unknown routes and methods with the same JSON body as the other errors
+	setupFallbackHandlers(router)
+
+	// Readiness endpoint, which also checks the database connectivity
+	health.Setup(router, dbConn)
+
+	// OpenAPI document describing the API
+	api.Setup(router, config.APIBasePath())
 
 	// Prometheus endpoint
 	router.Handle("/prometheus", promhttp.Handler())
 
+	// Calendars cache, shared so the deleted doctors accounts drop their calendars
+	calendarCache := calendar.NewCalendarCache(config)
+
 	// Setup Auth routes
-	auth.Setup(router, logger, config, dbConn)
+	auth.Setup(router, logger, config, dbConn, auth.WithCalendarInvalidator(calendarCache))
 
-	// Setup Calendar routes
-	calendar.Setup(router, logger, authorizer, config, dbConn)
+	// Setup Calendar routes, notifying the appointments through the webhook when it is configured
+	calendarOptions := []calendar.ServiceOption{calendar.WithCalendarCache(calendarCache)}
+	if config.AppointmentWebhookURL() != "" {
+		client := &http.Client{Timeout: 5 * time.Second}
+		calendarOptions = append(calendarOptions, calendar.WithNotifier(calendar.NewWebhookNotifier(config.AppointmentWebhookURL(), client)))
+	}
+	calendar.Setup(router, logger, authorizer, config, dbConn, calendarOptions...)
 
 	// Creates the HTTP server
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.ServerPort()),
-		Handler:      router,
-		ErrorLog:     logger,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  15 * time.Second,
-	}
+	srv := newServer(config, router, logger)
 
 	// Channel to listen OS signalling in order to gracefully shutdown the HTTP server and other resources
 	exit := make(chan os.Signal, 1)
@@ -100,16 +154,48 @@ func main() {
 	<-exit
 	log.Println(logger, "server stopped")
 
-	// Creates a timeout to handle resources release
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Creates a timeout to drain the in-flight requests and handle resources release
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout())
 	defer func() {
 		dbConn.Close()
 		cancel()
 	}()
 
 	if err := srv.Shutdown(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logging.PrintlnWarn(logger, fmt.Sprintf("%d requests were still in flight when the shutdown timeout was hit", requests.Count()))
+		}
 		logger.Fatal(fmt.Errorf("an error occurred while server is shutting down: %w", err))
 	}
 
 	log.Println(logger, "server shutdown successfully")
 }
+
+// setupFallbackHandlers makes the router answer the unmatched paths with 404 and the unsupported methods of a known
+// path with 405, both with an API error as body. The routers mounted afterwards inherit them.
+func setupFallbackHandlers(router chi.Router) {
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, apierrors.NewAPIError(apierrors.WithDetail(ErrRouteNotFound), apierrors.WithCode(CodeRouteNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound)))
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, apierrors.NewAPIError(apierrors.WithDetail(ErrMethodNotAllowed), apierrors.WithCode(CodeMethodNotAllowed), apierrors.WithHTTPStatusCode(http.StatusMethodNotAllowed)))
+	})
+}
+
+func writeAPIError(w http.ResponseWriter, err *apierrors.APIError) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(err.HTTPStatusCode())
+	_ = json.NewEncoder(w).Encode(err)
+}
+
+// newServer creates the HTTP server serving the given handler, with the configured port and timeouts.
+func newServer(config configs.Config, handler http.Handler, logger *log.Logger) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%d", config.ServerPort()),
+		Handler:      handler,
+		ErrorLog:     logger,
+		ReadTimeout:  config.ReadTimeout(),
+		WriteTimeout: config.WriteTimeout(),
+		IdleTimeout:  config.IdleTimeout(),
+	}
+}
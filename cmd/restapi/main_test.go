@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/configs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestNewServer(t *testing.T) {
+	tests := []struct {
+		name             string
+		configPath       string
+		wantReadTimeout  time.Duration
+		wantWriteTimeout time.Duration
+		wantIdleTimeout  time.Duration
+	}{
+		{
+			name:             "should apply the default timeouts",
+			configPath:       "./../../test/testdata/config_valid.json",
+			wantReadTimeout:  5 * time.Second,
+			wantWriteTimeout: 10 * time.Second,
+			wantIdleTimeout:  15 * time.Second,
+		},
+		{
+			name:             "should apply the configured timeouts",
+			configPath:       "./../../test/testdata/config_server_timeouts.json",
+			wantReadTimeout:  30 * time.Second,
+			wantWriteTimeout: time.Minute,
+			wantIdleTimeout:  2 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			srv := newServer(configs.MustLoad(tt.configPath), http.NewServeMux(), log.New(os.Stderr, "", log.LstdFlags))
+			if srv.Addr != ":8080" {
+				t.Errorf("server address is incorrect, got %s, want %s", srv.Addr, ":8080")
+			}
+			if srv.ReadTimeout != tt.wantReadTimeout {
+				t.Errorf("read timeout is incorrect, got %v, want %v", srv.ReadTimeout, tt.wantReadTimeout)
+			}
+			if srv.WriteTimeout != tt.wantWriteTimeout {
+				t.Errorf("write timeout is incorrect, got %v, want %v", srv.WriteTimeout, tt.wantWriteTimeout)
+			}
+			if srv.IdleTimeout != tt.wantIdleTimeout {
+				t.Errorf("idle timeout is incorrect, got %v, want %v", srv.IdleTimeout, tt.wantIdleTimeout)
+			}
+		})
+	}
+}
+
+func TestFallbackHandlers(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		want     int
+		wantCode string
+	}{
+		{
+			name:     "should answer an unknown path with a JSON 404",
+			method:   "GET",
+			path:     "/api/v1/unknown",
+			want:     http.StatusNotFound,
+			wantCode: CodeRouteNotFound,
+		},
+		{
+			name:     "should answer an unknown path of a mounted router with a JSON 404",
+			method:   "GET",
+			path:     "/api/v1/calendar/unknown",
+			want:     http.StatusNotFound,
+			wantCode: CodeRouteNotFound,
+		},
+		{
+			name:     "should answer an unsupported method on a known path with a JSON 405",
+			method:   "DELETE",
+			path:     "/api/v1/calendar/doctors",
+			want:     http.StatusMethodNotAllowed,
+			wantCode: CodeMethodNotAllowed,
+		},
+		{
+			name:   "should still serve the known routes",
+			method: "GET",
+			path:   "/api/v1/calendar/doctors",
+			want:   http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			setupFallbackHandlers(router)
+			router.Route("/api/v1/calendar", func(r chi.Router) {
+				r.Get("/doctors", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})
+			})
+
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if contentType := recorder.Header().Get("Content-type"); contentType != "application/json" {
+				t.Errorf("content type is incorrect, got %s, want application/json", contentType)
+			}
+			body := struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			}{}
+			if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("error code is incorrect, got %s, want %s", body.Code, tt.wantCode)
+			}
+			if body.Message == "" {
+				t.Error("error message should not be empty")
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package apierrors
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // ValidationError represents the errors returned during some model's validation.
@@ -21,11 +22,37 @@ func (v ValidationError) Error() string {
 	return fmt.Sprintf("%s: %s", v.Field, v.Tag)
 }
 
+// ValidationErrors aggregates the errors found while validating every field of some model, so the client learns
+// about all of them at once.
+type ValidationErrors []*ValidationError
+
+// Add adds a new ValidationError based on the given params.
+func (v *ValidationErrors) Add(field string, tag string) {
+	*v = append(*v, NewValidationError(field, tag))
+}
+
+// ErrorOrNil returns the errors found, or nil if there is none, so the result can be returned as an error.
+func (v ValidationErrors) ErrorOrNil() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, err := range v {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
 type APIErrorOption func(err *APIError)
 
 type APIError struct {
 	source         error
 	detail         string
+	code           string
 	httpStatusCode int
 }
 
@@ -37,6 +64,11 @@ func (e *APIError) Detail() string {
 	return e.detail
 }
 
+// Code gets the machine-stable code of the error, which clients can rely on instead of the detail.
+func (e *APIError) Code() string {
+	return e.code
+}
+
 func (e *APIError) HTTPStatusCode() int {
 	return e.httpStatusCode
 }
@@ -64,6 +96,12 @@ func WithDetail(detail string) APIErrorOption {
 	}
 }
 
+func WithCode(code string) APIErrorOption {
+	return func(err *APIError) {
+		err.code = code
+	}
+}
+
 func WithHTTPStatusCode(httpStatusCode int) APIErrorOption {
 	return func(err *APIError) {
 		err.httpStatusCode = httpStatusCode
@@ -81,8 +119,10 @@ func NewAPIError(opts ...APIErrorOption) *APIError {
 func (e *APIError) MarshalJSON() ([]byte, error) {
 	err := &struct {
 		Message string `json:"message"`
+		Code    string `json:"code,omitempty"`
 	}{
 		Message: e.detail,
+		Code:    e.code,
 	}
 	return json.Marshal(err)
 }
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"hospital-booking/internal/database"
+)
+
+const insertAuthAuditQuery = "INSERT INTO tb_auth_audit (email, success, ip) VALUES ($1, $2, $3)"
+
+// AuthAudit records the authentication attempts, for security review.
+type AuthAudit interface {
+
+	// RecordLogin records a login attempt with the given email, from the given IP, and whether it succeeded.
+	RecordLogin(ctx context.Context, email string, success bool, ip string) error
+}
+
+// noopAuthAudit is used when the authentication attempts should not be recorded.
+type noopAuthAudit struct{}
+
+func (n noopAuthAudit) RecordLogin(ctx context.Context, email string, success bool, ip string) error {
+	return nil
+}
+
+type dbAuthAudit struct {
+	dbConn database.Connection
+}
+
+// NewDBAuthAudit creates an AuthAudit that records the authentication attempts into the tb_auth_audit table.
+func NewDBAuthAudit(dbConn database.Connection) AuthAudit {
+	return &dbAuthAudit{dbConn: dbConn}
+}
+
+func (d dbAuthAudit) RecordLogin(ctx context.Context, email string, success bool, ip string) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = email
+	params[1] = success
+	params[2] = ip
+	_, err := database.Exec(ctx, d.dbConn.DB(), "insert_auth_audit", insertAuthAuditQuery, params...)
+	return err
+}
@@ -2,21 +2,115 @@
 // and authorization.
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
 
-// EncryptPassword encrypts a given string.
-func EncryptPassword(pass string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	BcryptHasher   = "bcrypt"
+	Argon2idHasher = "argon2id"
+)
+
+// PasswordHasher hashes the passwords and compares them to their hashes.
+type PasswordHasher interface {
+
+	// Hash hashes the given password.
+	Hash(password string) (string, error)
+
+	// Compare checks if the given password matches the given hash.
+	Compare(hash, password string) bool
+}
+
+// NewPasswordHasher creates the PasswordHasher with the given name, either BcryptHasher or Argon2idHasher, which
+// defaults to bcrypt.
+func NewPasswordHasher(name string) PasswordHasher {
+	if name == Argon2idHasher {
+		return argon2idPasswordHasher{}
+	}
+	return bcryptPasswordHasher{}
+}
+
+// hasherOf gets the PasswordHasher able to compare the given hash, detected by its format, so the hashes generated
+// before switching to another hasher keep working.
+func hasherOf(hash string) PasswordHasher {
+	if strings.HasPrefix(hash, "$"+Argon2idHasher+"$") {
+		return argon2idPasswordHasher{}
+	}
+	return bcryptPasswordHasher{}
+}
+
+type bcryptPasswordHasher struct{}
+
+func (b bcryptPasswordHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
 	}
 	return string(hash), nil
 }
 
+func (b bcryptPasswordHasher) Compare(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// parameters of the argon2id hashes, as recommended by the argon2 package.
+const (
+	argon2idTime    uint32 = 1
+	argon2idMemory  uint32 = 64 * 1024
+	argon2idThreads uint8  = 4
+	argon2idKeyLen  uint32 = 32
+	argon2idSaltLen        = 16
+)
+
+// argon2idPasswordHasher encodes its hashes as $argon2id$v=19$m=65536,t=1,p=4$salt$key, with the salt and the key
+// in unpadded base64, so the parameters can be changed without breaking the hashes already stored.
+type argon2idPasswordHasher struct{}
+
+func (a argon2idPasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2idSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2idHasher, argon2.Version, argon2idMemory, argon2idTime,
+		argon2idThreads, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a argon2idPasswordHasher) Compare(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2idHasher {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
 // ComparePasswords compares a given encrypted password and a string, in order to check
-// their equivalences.
+// their equivalences. The hasher is detected by the format of the encrypted password.
 func ComparePasswords(hashedPass, plainPass string) bool {
-	byteHash := []byte(hashedPass)
-	err := bcrypt.CompareHashAndPassword(byteHash, []byte(plainPass))
-	return err == nil
+	return hasherOf(hashedPass).Compare(hashedPass, plainPass)
 }
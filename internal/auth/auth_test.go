@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"database/sql/driver"
+	"hospital-booking/internal/configs"
+	"hospital-booking/internal/mock"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	for _, name := range []string{BcryptHasher, Argon2idHasher} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			hasher := NewPasswordHasher(name)
+			hash, err := hasher.Hash(plainTestPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !hasher.Compare(hash, plainTestPassword) {
+				t.Error("the password should match its hash")
+			}
+			if hasher.Compare(hash, "wrong") {
+				t.Error("a wrong password should not match the hash")
+			}
+			if !ComparePasswords(hash, plainTestPassword) {
+				t.Error("the hasher should be detected by the format of the hash")
+			}
+		})
+	}
+}
+
+func TestComparePasswordsInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "testing", "$argon2id$v=19$m=65536,t=1,p=4$salt", "$argon2id$v=1$m=65536,t=1,p=4$c2FsdA$a2V5"} {
+		if ComparePasswords(hash, plainTestPassword) {
+			t.Errorf("the invalid hash %q should not match any password", hash)
+		}
+	}
+}
+
+// hashedWith matches the password hashes generated by the given hasher.
+type hashedWith string
+
+func (h hashedWith) Match(value driver.Value) bool {
+	hash, isString := value.(string)
+	return isString && strings.HasPrefix(hash, "$"+string(h)+"$")
+}
+
+func TestBcryptHashAfterSwitchingHasher(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_password_hasher.json")
+	user := User{ID: 1, UUID: uuid.UUID{}, Email: "patient@hospital.com", Role: PatientRole}
+
+	t.Run("should authenticate with a bcrypt hash", func(t *testing.T) {
+		t.Parallel()
+		dbConn := mock.MustCreateConnectionMock()
+		mock.MockDBResults(dbConn,
+			withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(user.ID, user.UUID, user.Email, user.Role)),
+			withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+		)
+		service := NewService(config, dbConn)
+		if _, err := service.Authenticate(context.Background(), Credentials{Email: user.Email, Password: plainTestPassword}); err != nil {
+			t.Errorf("the bcrypt hash should still be compared, got %v", err)
+		}
+	})
+
+	t.Run("should hash the new password with the configured hasher", func(t *testing.T) {
+		t.Parallel()
+		dbConn := mock.MustCreateConnectionMock()
+		mock.MockDBResults(dbConn,
+			withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+			func(dbConn mock.Connection) {
+				dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updatePasswordQuery)).WithArgs(hashedWith(Argon2idHasher), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		)
+		service := NewService(config, dbConn)
+		if err := service.ChangePassword(context.Background(), user, PasswordChange{OldPassword: plainTestPassword, NewPassword: "n3wpassword"}); err != nil {
+			t.Fatalf("the password should be changed, got %v", err)
+		}
+		if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+}
@@ -1,5 +1,24 @@
 package auth
 
+const (
+	ErrMissingAuthorizationHeader = "missing authorization header"
+	ErrInvalidToken               = "invalid token"
+	ErrTokenExpired               = "token expired"
+	ErrNotAuthenticated           = "user not authenticated"
+	ErrInsufficientRole           = "insufficient role"
+	ErrEmailAlreadyRegistered     = "email already registered"
+)
+
+// Codes of the errors, which are stable so clients can rely on them.
+const (
+	CodeEmailAlreadyRegistered     = "EMAIL_ALREADY_REGISTERED"
+	CodeMissingAuthorizationHeader = "MISSING_AUTHORIZATION_HEADER"
+	CodeInvalidToken               = "INVALID_TOKEN"
+	CodeTokenExpired               = "TOKEN_EXPIRED"
+	CodeNotAuthenticated           = "NOT_AUTHENTICATED"
+	CodeInsufficientRole           = "INSUFFICIENT_ROLE"
+)
+
 // UnauthorizedError represents the errors returned if the user is not authorized.
 type UnauthorizedError struct{}
 
@@ -10,3 +29,15 @@ func NewUnauthorizedError() *UnauthorizedError {
 func (v UnauthorizedError) Error() string {
 	return "not authorized"
 }
+
+// TokenExpiredError represents the errors returned if the token is valid but expired, so the clients know they
+// can refresh it.
+type TokenExpiredError struct{}
+
+func NewTokenExpiredError() *TokenExpiredError {
+	return &TokenExpiredError{}
+}
+
+func (v TokenExpiredError) Error() string {
+	return "token expired"
+}
@@ -1,75 +1,126 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"hospital-booking/internal/apierrors"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/database"
+	"hospital-booking/internal/jsonbody"
 	"hospital-booking/internal/logging"
+	"hospital-booking/internal/ratelimit"
 	"log"
 	"net/http"
-
-	"github.com/go-chi/chi/v5/middleware"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type httpHandler struct {
 	service Service
-	logger  *log.Logger
 }
 
-// Setup setups the routes handled by auth context.
-func Setup(router *chi.Mux, logger *log.Logger, config configs.Config, dbConn database.Connection) {
-	handler := &httpHandler{logger: logger, service: NewService(config, dbConn)}
+// Setup setups the routes handled by auth context, under the configured API base path. The given options are
+// used to create the auth service, along with the one recording the login attempts.
+func Setup(router *chi.Mux, logger *log.Logger, config configs.Config, dbConn database.Connection, opts ...ServiceOption) {
+	opts = append([]ServiceOption{WithAuthAudit(NewDBAuthAudit(dbConn))}, opts...)
+	handler := &httpHandler{service: NewService(config, dbConn, opts...)}
+	loginLimiter := ratelimit.NewLimiter(config.LoginRateLimit(), time.Minute)
 
-	// public routes
-	router.Group(func(group chi.Router) {
-		group.Post("/api/v1/auth/login", handler.Authenticate)
-		group.Put("/api/v1/auth/token", handler.RefreshToken)
+	router.Route(config.APIBasePath()+"/auth", func(authRouter chi.Router) {
+		// public routes, throttled per IP to mitigate credential stuffing and mass registrations
+		authRouter.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(ratelimit.Middleware(loginLimiter))
+			group.Post("/login", handler.Authenticate)
+			group.Post("/register", handler.Register)
+			group.Put("/token", handler.RefreshToken)
+		})
+
+		// protected routes
+		authRouter.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(JwtValidator(handler.service))
+			group.Get("/me", handler.GetAuthenticatedUser)
+			group.Delete("/me", handler.DeleteAccount)
+			group.Put("/password", handler.ChangePassword)
+			group.Get("/token", handler.IntrospectToken)
+		})
+
+		// protected routes, only for patients
+		authRouter.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(JwtValidator(handler.service))
+			group.Use(AllowedRole(handler.service, PatientRole))
+			group.Put("/consent", handler.SetConsent)
+		})
 	})
 
-	// protected routes
+	// public keys used by external services to verify the tokens, which stay at the well-known location
 	router.Group(func(group chi.Router) {
-		group.Use(JwtValidator(handler.service))
-		group.Get("/api/v1/auth/me", handler.GetAuthenticatedUser)
+		group.Use(logging.Middleware(logger))
+		group.Get("/.well-known/jwks.json", handler.GetKeySet)
 	})
 }
 
 func (h httpHandler) writeResponseError(w http.ResponseWriter, r *http.Request, err error) {
-	logging.PrintlnError(h.logger, fmt.Sprint(r.Context().Value(middleware.RequestIDKey), " ", err))
-	switch err.(type) {
+	logging.FromContext(r.Context()).Error(err)
+	switch errType := err.(type) {
 	case *UnauthorizedError:
 		w.WriteHeader(http.StatusUnauthorized)
 		return
-	case *apierrors.ValidationError:
+	case *apierrors.ValidationError, apierrors.ValidationErrors:
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(err)
 		return
+	case *apierrors.APIError:
+		w.WriteHeader(errType.HTTPStatusCode())
+		_ = json.NewEncoder(w).Encode(err)
+		return
 	}
 	w.WriteHeader(http.StatusInternalServerError)
 }
 
-// Authenticate handles the request to authenticate a user.
+// Authenticate handles the request to authenticate a user, returning the authenticated user along with the
+// tokens when the include=user query parameter is given.
 func (h httpHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
 	credentials := &Credentials{}
-	if err := json.NewDecoder(r.Body).Decode(credentials); err != nil {
+	if err := jsonbody.Decode(r, credentials); err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	tokens, err := h.service.Authenticate(r.Context(), *credentials)
+	ctx := context.WithValue(r.Context(), ClientIPContextKey, ratelimit.ClientIP(r))
+	authentication, err := h.service.Authenticate(ctx, *credentials)
 	if err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	_ = json.NewEncoder(w).Encode(tokens)
+	if r.URL.Query().Get("include") != "user" {
+		authentication.User = nil
+	}
+	_ = json.NewEncoder(w).Encode(authentication)
+}
+
+// Register handles the request to register a new patient.
+func (h httpHandler) Register(w http.ResponseWriter, r *http.Request) {
+	registration := &Registration{}
+	if err := jsonbody.Decode(r, registration); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.service.Register(r.Context(), *registration)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(user)
 }
 
 // RefreshToken handles the request to return a new refresh token to the authenticated user.
 func (h httpHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	tokens := &Tokens{}
-	if err := json.NewDecoder(r.Body).Decode(tokens); err != nil {
+	if err := jsonbody.Decode(r, tokens); err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
@@ -81,12 +132,100 @@ func (h httpHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(tokens)
 }
 
-// GetAuthenticatedUser handles the request to return data about the authenticated user.
+// IntrospectToken handles the request to return the non-sensitive claims of the presented bearer token, which
+// helps debugging the token issues. The signature is never returned.
+func (h httpHandler) IntrospectToken(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.service.IntrospectToken(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(claims)
+}
+
+// GetKeySet handles the request to return the public keys used to verify the tokens, as a JWK set.
+func (h httpHandler) GetKeySet(w http.ResponseWriter, r *http.Request) {
+	keySet, err := h.service.GetKeySet()
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(keySet)
+}
+
+// GetAuthenticatedUser handles the request to return data about the authenticated user, along with its
+// doctor or patient profile when the expand=profile query parameter is given.
 func (h httpHandler) GetAuthenticatedUser(w http.ResponseWriter, r *http.Request) {
 	user, err := h.service.GetAuthenticatedUser(r.Context())
 	if err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	_ = json.NewEncoder(w).Encode(user)
+	if r.URL.Query().Get("expand") != "profile" {
+		_ = json.NewEncoder(w).Encode(user)
+		return
+	}
+	profile, err := h.service.GetProfile(r.Context(), user)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(UserProfile{User: user, Profile: profile})
+}
+
+// ChangePassword handles the request to change the password of the authenticated user.
+func (h httpHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetAuthenticatedUser(r.Context())
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	change := &PasswordChange{}
+	if err = jsonbody.Decode(r, change); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.ChangePassword(r.Context(), user, *change); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount handles the request of the authenticated user to delete its own account.
+func (h httpHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetAuthenticatedUser(r.Context())
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	deletion := &AccountDeletion{}
+	if err = jsonbody.Decode(r, deletion); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.DeleteAccount(r.Context(), user, *deletion); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetConsent handles the request of the authenticated patient to give or withdraw its consent.
+func (h httpHandler) SetConsent(w http.ResponseWriter, r *http.Request) {
+	user, err := h.service.GetAuthenticatedUser(r.Context())
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	consent := &Consent{}
+	if err = jsonbody.Decode(r, consent); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.SetConsent(r.Context(), user, *consent); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -3,22 +3,30 @@ package auth
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"hospital-booking/internal/bodylimit"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/mock"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/lib/pq"
 )
 
 const (
@@ -52,6 +60,47 @@ func (m mockAuthorizer) GetAuthenticatedUser(ctx context.Context) (User, error)
 	return m.mockGetAuthenticatedUser(ctx)
 }
 
+// mustSignTokensWithSubject signs an access and a refresh token with the given subject, which GenerateTokens always
+// sets to the user's UUID.
+func mustSignTokensWithSubject(config configs.Config, subject string) *Tokens {
+	accessToken, err := NewJwtToken(GetDefaultAccessTokenOptions(WithSubject(subject), WithRole(PatientRole))...)
+	if err != nil {
+		panic(err)
+	}
+	refreshToken, err := NewJwtToken(GetDefaultRefreshTokenOptions(WithSubject(subject), WithRole(PatientRole))...)
+	if err != nil {
+		panic(err)
+	}
+	signedAccessToken, err := SignToken(accessToken, config.PrivateKey())
+	if err != nil {
+		panic(err)
+	}
+	signedRefreshToken, err := SignToken(refreshToken, config.PrivateKey())
+	if err != nil {
+		panic(err)
+	}
+	return &Tokens{
+		AccessToken:  signedAccessToken,
+		RefreshToken: signedRefreshToken,
+	}
+}
+
+// unsignedRefreshToken builds a refresh token with the given subject and no signature, as anyone could forge it.
+func unsignedRefreshToken(subject string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	claims := fmt.Sprintf(`{"typ":%q,"sub":%q,"iss":%q,"aud":[%q],"exp":%d}`, RefreshTokenType, subject, IssuerDefault, AudienceDefault, time.Now().Add(time.Hour).Unix())
+	return header + "." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+}
+
+// mustSignTokensWithAnotherKey signs the tokens of the given user with a key other than the configured one.
+func mustSignTokensWithAnotherKey(user User) *Tokens {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return MustGenerateTokens(context.TODO(), privateKey, user)
+}
+
 func withFindUserByEmailResult(rows *sqlmock.Rows) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
 		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findUserByEmailQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
@@ -70,6 +119,79 @@ func withFindUserByUUIDResult(rows *sqlmock.Rows) mock.DBResultOption {
 	}
 }
 
+func withInsertUserResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(insertUserQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), PatientRole).WillReturnRows(rows)
+	}
+}
+
+func withInsertUserError(err error) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(insertUserQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), PatientRole).WillReturnError(err)
+	}
+}
+
+func withInsertPatientResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertPatientQuery)).WithArgs(sqlmock.AnyArg(), 1, "Patient", "new.patient@hospital.com", "").WillReturnResult(result)
+	}
+}
+
+func withGetPasswordHashResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(getPasswordHashQuery)).WithArgs(1).WillReturnRows(rows)
+	}
+}
+
+func withGetPasswordHashError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(getPasswordHashQuery)).WithArgs(1).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withUpdatePasswordResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updatePasswordQuery)).WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(result)
+	}
+}
+
+func withDeleteAccountResult(profileQueries []string, anonymizeQuery string, err error) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectBegin()
+		for _, query := range profileQueries {
+			dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		if err != nil {
+			dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(anonymizeQuery)).WithArgs(1).WillReturnError(err)
+			dbConn.SQLMock.ExpectRollback()
+			return
+		}
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(anonymizeQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(deleteUserQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbConn.SQLMock.ExpectCommit()
+	}
+}
+
+// withDeleteAccountWithHistoryResult mocks the deletion of an account having only past appointments, so nothing is
+// deleted along with it and the profile they reference is anonymized by the given query.
+func withDeleteAccountWithHistoryResult(profileQueries []string, anonymizeQuery string) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectBegin()
+		for _, query := range profileQueries {
+			dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(anonymizeQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(deleteUserQuery)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbConn.SQLMock.ExpectCommit()
+	}
+}
+
+func withUpdateConsentResult(consentGiven bool, result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updateConsentQuery)).WithArgs(consentGiven, 1).WillReturnResult(result)
+	}
+}
+
 func withFindUserByEmailError() mock.DBResultOption {
 	return func(dbConn mock.Connection) {
 		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findUserByEmailQuery)).WithArgs(sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
@@ -253,155 +375,97 @@ func TestAuthenticate(t *testing.T) {
 	}
 }
 
-func TestGetAuthenticatedUser(t *testing.T) {
+func TestAuthenticateNormalizesEmail(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
-	type args struct {
-		config        configs.Config
-		dbConn        mock.Connection
-		dbMockOptions []mock.DBResultOption
-		user          *User
-		tokens        *Tokens
-	}
 	tests := []struct {
-		name         string
-		args         args
-		want         int
-		wantResponse string
+		name        string
+		credentials Credentials
+		want        int
 	}{
 		{
-			name: "should get the authenticated user",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
-				},
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}),
-			},
-			want:         http.StatusOK,
-			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}\n",
-		},
-		{
-			name: "should not get the authenticated because the user was not found",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
-				},
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}),
-			},
-			want:         http.StatusUnauthorized,
-			wantResponse: "",
-		},
-		{
-			name: "should not get the authenticated user due to a database error while searching for the user",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDError(),
-				},
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}),
-			},
-			want:         http.StatusUnauthorized,
-			wantResponse: "",
+			name:        "should authenticate with a mixed-case, space-padded email",
+			credentials: Credentials{Email: "  Patient@Hospital.COM ", Password: plainTestPassword},
+			want:        http.StatusOK,
 		},
 		{
-			name: "should not get the authenticated user due to a database error while parsing the user",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(-1, false, "patient@hospital.com", PatientRole)),
-				},
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}),
-			},
-			want:         http.StatusUnauthorized,
-			wantResponse: "",
+			name:        "should not trim the password",
+			credentials: Credentials{Email: "Patient@Hospital.com", Password: " " + plainTestPassword + " "},
+			want:        http.StatusUnauthorized,
 		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			// the queries only match the stored lowercase email
+			dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findUserByEmailQuery)).WithArgs("patient@hospital.com").WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.New(), "patient@hospital.com", PatientRole))
+			dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(checkUserPasswordQuery)).WithArgs("patient@hospital.com").WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword))
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, config, dbConn)
+
+			body, _ := json.Marshal(tt.credentials)
+			req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewReader(body))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRegisterNormalizesEmail(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+	dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findUserByEmailQuery)).WithArgs("new.patient@hospital.com").WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}))
+	dbConn.SQLMock.ExpectBegin()
+	dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(insertUserQuery)).WithArgs(sqlmock.AnyArg(), "new.patient@hospital.com", sqlmock.AnyArg(), PatientRole).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	withInsertPatientResult(sqlmock.NewResult(1, 1))(dbConn)
+	dbConn.SQLMock.ExpectCommit()
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, config, dbConn)
+
+	body, _ := json.Marshal(Registration{
+		Credentials: Credentials{Email: " New.Patient@Hospital.com ", Password: "s3cretpass"},
+		Name:        "Patient",
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthenticateValidationErrors(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tests := []struct {
+		name         string
+		credentials  Credentials
+		wantResponse string
+	}{
 		{
-			name: "should not get the authenticated user because the authorization header is missing",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: nil,
-			},
-			want:         http.StatusUnauthorized,
-			wantResponse: "",
+			name:         "should return the errors of both the email and the password",
+			credentials:  Credentials{},
+			wantResponse: "[{\"field\":\"email\",\"tag\":\"required\"},{\"field\":\"password\",\"tag\":\"required\"}]\n",
 		},
 		{
-			name: "should not get the authenticated user because the given token is expired",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}, []TokenOption{func(token jwt.Token) error {
-					return token.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Hour))
-				}}...),
-			},
-			want:         http.StatusUnauthorized,
-			wantResponse: "",
+			name:         "should return only the error of the password",
+			credentials:  Credentials{Email: "patient@hospital.com"},
+			wantResponse: "[{\"field\":\"password\",\"tag\":\"required\"}]\n",
 		},
 	}
 	for _, tt := range tests {
@@ -410,64 +474,1013 @@ func TestGetAuthenticatedUser(t *testing.T) {
 			t.Parallel()
 
 			router := chi.NewRouter()
-			Setup(router, logger, tt.args.config, tt.args.dbConn)
-
-			mock.MockDBResults(tt.args.dbConn, tt.args.dbMockOptions...)
-
-			req, _ := http.NewRequest("GET", "/api/v1/auth/me", nil)
-
-			token := ""
-			if tt.args.tokens != nil {
-				token = fmt.Sprintf("Bearer %s", tt.args.tokens.AccessToken)
-			}
+			Setup(router, logger, config, mock.MustCreateConnectionMock())
 
-			req.Header.Add("Authorization", token)
+			body, _ := json.Marshal(tt.credentials)
+			req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
 
 			recorder := httptest.NewRecorder()
 			router.ServeHTTP(recorder, req)
-			response := recorder.Result()
-
-			if response.StatusCode != tt.want {
-				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
-			}
 
-			buf := new(bytes.Buffer)
-			_, err := buf.ReadFrom(response.Body)
-			if err != nil {
-				t.Errorf("an error occurred while reading response body: %v", err)
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusBadRequest)
 			}
-
-			responseBody := buf.String()
-
-			if tt.wantResponse != responseBody {
-				t.Errorf("response body is incorrect, got %s, want %s", responseBody, tt.wantResponse)
+			if got := recorder.Body.String(); got != tt.wantResponse {
+				t.Errorf("response is incorrect, got %s, want %s", got, tt.wantResponse)
 			}
 		})
 	}
 }
 
-func TestRefreshToken(t *testing.T) {
+func TestAuthenticateIncludingUser(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
-	type args struct {
-		config        configs.Config
-		dbConn        mock.Connection
-		dbMockOptions []mock.DBResultOption
-		changeToken   func(tokens *Tokens)
-		user          *User
-		tokens        *Tokens
-	}
 	tests := []struct {
-		name string
-		args args
-		want int
+		name     string
+		url      string
+		wantUser bool
 	}{
 		{
-			name: "should refresh tokens",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+			name:     "should return only the tokens by default",
+			url:      "/api/v1/auth/login",
+			wantUser: false,
+		},
+		{
+			name:     "should return the tokens along with the user when it is included",
+			url:      "/api/v1/auth/login?include=user",
+			wantUser: true,
+		},
+		{
+			name:     "should return only the tokens when something else is included",
+			url:      "/api/v1/auth/login?include=profile",
+			wantUser: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+			)
+
+			body, _ := json.Marshal(Credentials{Email: "patient@hospital.com", Password: plainTestPassword})
+			req, _ := http.NewRequest("POST", tt.url, bytes.NewBuffer(body))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			response := make(map[string]json.RawMessage)
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if _, hasAccessToken := response["access_token"]; !hasAccessToken {
+				t.Errorf("response should have the access token, got %s", recorder.Body.String())
+			}
+			if _, hasRefreshToken := response["refresh_token"]; !hasRefreshToken {
+				t.Errorf("response should have the refresh token, got %s", recorder.Body.String())
+			}
+			userBlock, hasUser := response["user"]
+			if hasUser != tt.wantUser {
+				t.Fatalf("response user block is incorrect, got %s, want user %v", recorder.Body.String(), tt.wantUser)
+			}
+			if !tt.wantUser {
+				return
+			}
+			wantUser := "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}"
+			if got := string(userBlock); got != wantUser {
+				t.Errorf("user is incorrect, got %s, want %s", got, wantUser)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithConfiguredExpiration(t *testing.T) {
+	tests := []struct {
+		name           string
+		configPath     string
+		wantAccessTTL  time.Duration
+		wantRefreshTTL time.Duration
+	}{
+		{
+			name:           "should generate the tokens with the default expiration",
+			configPath:     "./../../test/testdata/config_valid.json",
+			wantAccessTTL:  AccessTokenExpiration,
+			wantRefreshTTL: RefreshTokenExpiration,
+		},
+		{
+			name:           "should generate the tokens with the configured expiration",
+			configPath:     "./../../test/testdata/config_token_ttl.json",
+			wantAccessTTL:  time.Hour,
+			wantRefreshTTL: 48 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, configs.MustLoad(tt.configPath), dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+			)
+
+			body, _ := json.Marshal(Credentials{Email: "patient@hospital.com", Password: plainTestPassword})
+			req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			tokens := &Tokens{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), tokens); err != nil {
+				t.Fatal(err)
+			}
+			for token, want := range map[string]time.Duration{tokens.AccessToken: tt.wantAccessTTL, tokens.RefreshToken: tt.wantRefreshTTL} {
+				parsedToken, err := jwt.Parse([]byte(token))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := parsedToken.Expiration().Sub(parsedToken.IssuedAt()); got != want {
+					t.Errorf("%s token expiration is incorrect, got %v, want %v", tokenType(parsedToken), got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticateRecordsLoginIntoDatabase(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+
+	router := chi.NewRouter()
+	Setup(router, logger, config, dbConn)
+
+	mock.MockDBResults(dbConn,
+		withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+		withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+		func(dbConn mock.Connection) {
+			dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAuthAuditQuery)).WithArgs("patient@hospital.com", false, "10.0.0.1").WillReturnResult(sqlmock.NewResult(1, 1))
+		},
+	)
+
+	body, _ := json.Marshal(Credentials{Email: "patient@hospital.com", Password: "wrong"})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+	req.RemoteAddr = "10.0.0.1:54321"
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	registration := Registration{
+		Credentials: Credentials{
+			Email:    "new.patient@hospital.com",
+			Password: "s3cretpass",
+		},
+		Name: "Patient",
+	}
+	weakRegistration := registration
+	weakRegistration.Password = "secret"
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		registration  Registration
+		want          int
+	}{
+		{
+			name: "should register the patient",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectBegin() },
+				withInsertUserResult(sqlmock.NewRows([]string{"id"}).AddRow(1)),
+				withInsertPatientResult(sqlmock.NewResult(1, 1)),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectCommit() },
+			},
+			registration: registration,
+			want:         http.StatusCreated,
+		},
+		{
+			name: "should not register the patient because the email is already registered",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.New(), "new.patient@hospital.com", PatientRole)),
+			},
+			registration: registration,
+			want:         http.StatusConflict,
+		},
+		{
+			name: "should not register the patient because the email was registered concurrently",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectBegin() },
+				withInsertUserError(&pq.Error{Code: "23505"}),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectRollback() },
+			},
+			registration: registration,
+			want:         http.StatusConflict,
+		},
+		{
+			name: "should not register the patient because the patient could not be inserted",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectBegin() },
+				withInsertUserResult(sqlmock.NewRows([]string{"id"}).AddRow(1)),
+				withInsertPatientResult(sqlmock.NewResult(0, 0)),
+				func(dbConn mock.Connection) { dbConn.SQLMock.ExpectRollback() },
+			},
+			registration: registration,
+			want:         http.StatusInternalServerError,
+		},
+		{
+			name: "should not register the patient because of an error finding the user",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailError(),
+			},
+			registration: registration,
+			want:         http.StatusInternalServerError,
+		},
+		{
+			name:         "should not register the patient because the password is weak",
+			registration: weakRegistration,
+			want:         http.StatusBadRequest,
+		},
+		{
+			name:         "should not register the patient because the name is empty",
+			registration: Registration{Credentials: registration.Credentials},
+			want:         http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			body, _ := json.Marshal(tt.registration)
+			req, _ := http.NewRequest("POST", "/api/v1/auth/register", bytes.NewBuffer(body))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("the registration should be performed in a transaction: %v", err)
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+			user := &User{}
+			if err := json.NewDecoder(recorder.Body).Decode(user); err != nil {
+				t.Fatal(err)
+			}
+			if user.UUID == uuid.Nil || user.Role != PatientRole || user.Password != "" {
+				t.Errorf("the registered user is incorrect, got %+v", user)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnknownField(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+
+	router := chi.NewRouter()
+	Setup(router, logger, config, dbConn)
+
+	body := []byte(`{"email":"patient@hospital.com","password":"test","bogus":true}`)
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the user should not be looked up: %v", err)
+	}
+}
+
+func TestAuthenticateBodyTooLarge(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+
+	router := chi.NewRouter()
+	router.Use(bodylimit.Middleware(64))
+	Setup(router, logger, config, dbConn)
+
+	body, _ := json.Marshal(Credentials{
+		Email:    "patient@hospital.com",
+		Password: strings.Repeat("a", 128),
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusRequestEntityTooLarge)
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the user should not be looked up: %v", err)
+	}
+}
+
+func TestAPIBasePath(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_api_base_path.json")
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{
+			name: "should reach the login route under the configured base path",
+			path: "/api/v2/auth/login",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not reach the login route under the default base path",
+			path: "/api/v1/auth/login",
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should keep the key set at its well-known location",
+			path: "/.well-known/jwks.json",
+			want: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			Setup(router, logger, config, mock.MustCreateConnectionMock())
+
+			body, _ := json.Marshal(Credentials{Email: "patient@hospital.com"})
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBuffer(body))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRateLimit(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	router := chi.NewRouter()
+	Setup(router, logger, config, mock.MustCreateConnectionMock())
+
+	for i := 1; i <= config.LoginRateLimit()+1; i++ {
+		body, _ := json.Marshal(Credentials{Email: "patient@hospital.com"})
+		req, _ := http.NewRequest("POST", "/api/v1/auth/login", bytes.NewBuffer(body))
+		req.RemoteAddr = "10.0.0.1:1234"
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		want := http.StatusBadRequest
+		if i > config.LoginRateLimit() {
+			want = http.StatusTooManyRequests
+		}
+		if recorder.Code != want {
+			t.Errorf("request %d: response status is incorrect, got %d, want %d", i, recorder.Code, want)
+		}
+	}
+}
+
+func TestGetKeySet(t *testing.T) {
+	tests := []struct {
+		name       string
+		configPath string
+	}{
+		{
+			name:       "should get the RSA public key used to verify the tokens",
+			configPath: "./../../test/testdata/config_valid.json",
+		},
+		{
+			name:       "should get the EC public key used to verify the tokens",
+			configPath: "./../../test/testdata/config_ec_private_key.json",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := configs.MustLoad(tt.configPath)
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, mock.MustCreateConnectionMock())
+
+			req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			keySet, err := jwk.Parse(recorder.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keySet.Len() != 1 {
+				t.Fatalf("key set length is incorrect, got %d, want %d", keySet.Len(), 1)
+			}
+			key, _ := keySet.Get(0)
+			if _, isPrivate := key.Get("d"); isPrivate {
+				t.Fatal("key set should not expose the private key")
+			}
+
+			tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), User{UUID: uuid.UUID{}, Role: PatientRole})
+			if _, err = jwt.Parse([]byte(tokens.AccessToken), jwt.WithKeySet(keySet)); err != nil {
+				t.Errorf("token should be verified with the key set, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetAuthenticatedUser(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tenantConfig := configs.MustLoad("./../../test/testdata/config_token_audience.json")
+	noLeewayConfig := configs.MustLoad("./../../test/testdata/config_no_token_leeway.json")
+	type args struct {
+		config        configs.Config
+		dbConn        mock.Connection
+		dbMockOptions []mock.DBResultOption
+		user          *User
+		tokens        *Tokens
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         int
+		wantResponse string
+	}{
+		{
+			name: "should get the authenticated user",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}\n",
+		},
+		{
+			name: "should not get the authenticated because the user was not found",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user due to a database error while searching for the user",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDError(),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user due to a database error while parsing the user",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(-1, false, "patient@hospital.com", PatientRole)),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user because the authorization header is missing",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: nil,
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrMissingAuthorizationHeader, CodeMissingAuthorizationHeader),
+		},
+		{
+			name: "should not get the authenticated user because the given token is expired",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, []TokenOption{func(token jwt.Token) error {
+					return token.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Hour))
+				}}...),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrTokenExpired, CodeTokenExpired),
+		},
+		{
+			name: "should not get the authenticated user because the given token is not valid yet",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithNotBefore(time.Now().Add(10*time.Minute))),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user because the given token is a refresh token",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: func() *Tokens {
+					tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+						ID:    1,
+						UUID:  uuid.UUID{},
+						Email: "patient@hospital.com",
+						Role:  PatientRole,
+					})
+					tokens.AccessToken = tokens.RefreshToken
+					return tokens
+				}(),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user because the given token was minted for another audience",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithAudience([]string{"another_audience"})),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user because the given token was issued by another issuer",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithIssuer("another_issuer")),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should not get the authenticated user because the given token was not minted for the configured audience",
+			args: args{
+				config: tenantConfig,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+		{
+			name: "should get the authenticated user because the given token was minted for one of the allowed audiences",
+			args: args{
+				config: tenantConfig,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithAudience([]string{"hospital_booking_mobile"})),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}\n",
+		},
+		{
+			name: "should get the authenticated user because the given token is already valid",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				},
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithNotBefore(time.Now().Add(-10*time.Minute))),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}\n",
+		},
+		{
+			name: "should get the authenticated user because the given token expired within the leeway",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithExpiration(-10*time.Second)),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\"}\n",
+		},
+		{
+			name: "should not get the authenticated user because the given token expired and there is no leeway",
+			args: args{
+				config: noLeewayConfig,
+				dbConn: mock.MustCreateConnectionMock(),
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}, WithExpiration(-10*time.Second)),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrTokenExpired, CodeTokenExpired),
+		},
+		{
+			name: "should not get the authenticated user because the given token subject is not a UUID",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				tokens: mustSignTokensWithSubject(config, "not-a-uuid"),
+			},
+			want:         http.StatusUnauthorized,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidToken, CodeInvalidToken),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router := chi.NewRouter()
+			Setup(router, logger, tt.args.config, tt.args.dbConn)
+
+			mock.MockDBResults(tt.args.dbConn, tt.args.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", "/api/v1/auth/me", nil)
+
+			token := ""
+			if tt.args.tokens != nil {
+				token = fmt.Sprintf("Bearer %s", tt.args.tokens.AccessToken)
+			}
+
+			req.Header.Add("Authorization", token)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			response := recorder.Result()
+
+			if response.StatusCode != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+
+			buf := new(bytes.Buffer)
+			_, err := buf.ReadFrom(response.Body)
+			if err != nil {
+				t.Errorf("an error occurred while reading response body: %v", err)
+			}
+
+			responseBody := buf.String()
+
+			if tt.wantResponse != responseBody {
+				t.Errorf("response body is incorrect, got %s, want %s", responseBody, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestIntrospectToken(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	user := User{
+		ID:    1,
+		UUID:  uuid.New(),
+		Email: "patient@hospital.com",
+		Role:  PatientRole,
+	}
+	tests := []struct {
+		name          string
+		token         string
+		dbMockOptions []mock.DBResultOption
+		want          int
+	}{
+		{
+			name:  "should return the claims of a valid token",
+			token: MustGenerateTokens(context.TODO(), config.PrivateKey(), user).AccessToken,
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(user.ID, user.UUID, user.Email, user.Role)),
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:  "should not return the claims of a garbage token",
+			token: "garbage",
+			want:  http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", "/api/v1/auth/token", nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tt.token))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if strings.Contains(recorder.Body.String(), tt.token) {
+				t.Error("the token itself should not be returned")
+			}
+			claims := TokenClaims{}
+			if err := json.NewDecoder(recorder.Body).Decode(&claims); err != nil {
+				t.Fatal(err)
+			}
+			if claims.Subject != user.UUID.String() || claims.Role != user.Role || claims.Type != AccessTokenType {
+				t.Errorf("the claims are incorrect, got %+v", claims)
+			}
+			if claims.JTI == "" || claims.IssuedAt == 0 || claims.Expiration <= claims.IssuedAt {
+				t.Errorf("the token identifier and times are incorrect, got %+v", claims)
+			}
+		})
+	}
+}
+
+// withoutExpiration removes the expiration claim set by the default token options.
+func withoutExpiration() TokenOption {
+	return func(token jwt.Token) error {
+		return token.Remove(jwt.ExpirationKey)
+	}
+}
+
+func TestTokenWithoutExpiration(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	user := User{
+		ID:    1,
+		UUID:  uuid.UUID{},
+		Email: "patient@hospital.com",
+		Role:  PatientRole,
+	}
+	tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), user, withoutExpiration())
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		body    func() []byte
+		headers map[string]string
+	}{
+		{
+			name:    "should not get the authenticated user because the access token has no expiration",
+			method:  "GET",
+			url:     "/api/v1/auth/me",
+			body:    func() []byte { return nil },
+			headers: map[string]string{"Authorization": fmt.Sprintf("Bearer %s", tokens.AccessToken)},
+		},
+		{
+			name:   "should not refresh tokens because the refresh token has no expiration",
+			method: "PUT",
+			url:    "/api/v1/auth/token",
+			body: func() []byte {
+				body, _ := json.Marshal(Tokens{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken, GrantType: "refresh_token"})
+				return body
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			output := new(bytes.Buffer)
+			router := chi.NewRouter()
+			Setup(router, log.New(output, "", log.LstdFlags), config, mock.MustCreateConnectionMock())
+
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewReader(tt.body()))
+			for key, value := range tt.headers {
+				req.Header.Add(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusUnauthorized {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusUnauthorized)
+			}
+			if !strings.Contains(output.String(), "has no expiration claim") {
+				t.Errorf("the missing expiration should be logged, got %s", output.String())
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	type args struct {
+		config        configs.Config
+		dbConn        mock.Connection
+		dbMockOptions []mock.DBResultOption
+		changeToken   func(tokens *Tokens)
+		user          *User
+		tokens        *Tokens
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "should refresh tokens",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
 				},
 				user: &User{
 					ID:    1,
@@ -507,13 +1520,109 @@ func TestRefreshToken(t *testing.T) {
 				changeToken: func(tokens *Tokens) {
 				},
 			},
-			want: http.StatusBadRequest,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not refresh tokens because the grant_type is different from expected",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+				changeToken: func(tokens *Tokens) {
+					tokens.GrantType = "invalid"
+				},
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not refresh tokens because the given tokens contains no refresh token",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+				changeToken: func(tokens *Tokens) {
+					tokens.RefreshToken = ""
+				},
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not refresh tokens because the given tokens contains no access token",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+				changeToken: func(tokens *Tokens) {
+					tokens.AccessToken = ""
+				},
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not refresh tokens because the given refresh_token is invalid",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				user: &User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				},
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
+					ID:    1,
+					UUID:  uuid.UUID{},
+					Email: "patient@hospital.com",
+					Role:  PatientRole,
+				}),
+				changeToken: func(tokens *Tokens) {
+					tokens.RefreshToken = "invalid"
+					tokens.GrantType = "refresh_token"
+				},
+			},
+			want: http.StatusUnauthorized,
 		},
 		{
-			name: "should not refresh tokens because the grant_type is different from expected",
+			name: "should not refresh token due to a database error while searching for the user",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDError(),
+				},
 				user: &User{
 					ID:    1,
 					UUID:  uuid.UUID{},
@@ -527,16 +1636,19 @@ func TestRefreshToken(t *testing.T) {
 					Role:  PatientRole,
 				}),
 				changeToken: func(tokens *Tokens) {
-					tokens.GrantType = "invalid"
+					tokens.GrantType = "refresh_token"
 				},
 			},
-			want: http.StatusBadRequest,
+			want: http.StatusInternalServerError,
 		},
 		{
-			name: "should not refresh tokens because the given tokens contains no refresh token",
+			name: "should not refresh token due to a database error while parsing the user found",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(-1, false, "patient@hospital.com", PatientRole)),
+				},
 				user: &User{
 					ID:    1,
 					UUID:  uuid.UUID{},
@@ -550,16 +1662,19 @@ func TestRefreshToken(t *testing.T) {
 					Role:  PatientRole,
 				}),
 				changeToken: func(tokens *Tokens) {
-					tokens.RefreshToken = ""
+					tokens.GrantType = "refresh_token"
 				},
 			},
-			want: http.StatusBadRequest,
+			want: http.StatusInternalServerError,
 		},
 		{
-			name: "should not refresh tokens because the given tokens contains no access token",
+			name: "should not refresh token because the user associated to it no longer exists",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
+				dbMockOptions: []mock.DBResultOption{
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+				},
 				user: &User{
 					ID:    1,
 					UUID:  uuid.UUID{},
@@ -573,13 +1688,13 @@ func TestRefreshToken(t *testing.T) {
 					Role:  PatientRole,
 				}),
 				changeToken: func(tokens *Tokens) {
-					tokens.AccessToken = ""
+					tokens.GrantType = "refresh_token"
 				},
 			},
-			want: http.StatusBadRequest,
+			want: http.StatusUnauthorized,
 		},
 		{
-			name: "should not refresh tokens because the given refresh_token is invalid",
+			name: "should not refresh token because the given token is expired",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
@@ -594,22 +1709,20 @@ func TestRefreshToken(t *testing.T) {
 					UUID:  uuid.UUID{},
 					Email: "patient@hospital.com",
 					Role:  PatientRole,
-				}),
+				}, []TokenOption{func(token jwt.Token) error {
+					return token.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Hour))
+				}}...),
 				changeToken: func(tokens *Tokens) {
-					tokens.RefreshToken = "invalid"
 					tokens.GrantType = "refresh_token"
 				},
 			},
 			want: http.StatusUnauthorized,
 		},
 		{
-			name: "should not refresh token due to a database error while searching for the user",
+			name: "should not refresh token because the given refresh_token is an access token",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDError(),
-				},
 				user: &User{
 					ID:    1,
 					UUID:  uuid.UUID{},
@@ -624,50 +1737,53 @@ func TestRefreshToken(t *testing.T) {
 				}),
 				changeToken: func(tokens *Tokens) {
 					tokens.GrantType = "refresh_token"
+					tokens.RefreshToken = tokens.AccessToken
 				},
 			},
-			want: http.StatusInternalServerError,
+			want: http.StatusUnauthorized,
 		},
 		{
-			name: "should not refresh token due to a database error while parsing the user found",
+			name: "should refresh tokens because the given refresh_token is valid from within the leeway",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
 				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(-1, false, "patient@hospital.com", PatientRole)),
-				},
-				user: &User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
+					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)),
 				},
 				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
 					ID:    1,
 					UUID:  uuid.UUID{},
 					Email: "patient@hospital.com",
 					Role:  PatientRole,
-				}),
+				}, WithNotBefore(time.Now().Add(config.TokenLeeway()-5*time.Second))),
 				changeToken: func(tokens *Tokens) {
 					tokens.GrantType = "refresh_token"
 				},
 			},
-			want: http.StatusInternalServerError,
+			want: http.StatusOK,
 		},
 		{
-			name: "should not refresh token because the user associated to it no longer exists",
+			name: "should not refresh token because the given refresh_token is only valid from beyond the leeway",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
-				dbMockOptions: []mock.DBResultOption{
-					withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
-				},
-				user: &User{
+				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
 					ID:    1,
 					UUID:  uuid.UUID{},
 					Email: "patient@hospital.com",
 					Role:  PatientRole,
+				}, WithNotBefore(time.Now().Add(config.TokenLeeway()+5*time.Second))),
+				changeToken: func(tokens *Tokens) {
+					tokens.GrantType = "refresh_token"
 				},
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "should not refresh token because the given refresh_token is not signed",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
 				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
 					ID:    1,
 					UUID:  uuid.UUID{},
@@ -676,29 +1792,34 @@ func TestRefreshToken(t *testing.T) {
 				}),
 				changeToken: func(tokens *Tokens) {
 					tokens.GrantType = "refresh_token"
+					tokens.RefreshToken = unsignedRefreshToken(uuid.UUID{}.String())
 				},
 			},
 			want: http.StatusUnauthorized,
 		},
 		{
-			name: "should not refresh token because the given token is expired",
+			name: "should not refresh token because the given refresh_token is signed by another key",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
-				user: &User{
+				tokens: mustSignTokensWithAnotherKey(User{
 					ID:    1,
 					UUID:  uuid.UUID{},
 					Email: "patient@hospital.com",
 					Role:  PatientRole,
+				}),
+				changeToken: func(tokens *Tokens) {
+					tokens.GrantType = "refresh_token"
 				},
-				tokens: MustGenerateTokens(context.TODO(), config.PrivateKey(), User{
-					ID:    1,
-					UUID:  uuid.UUID{},
-					Email: "patient@hospital.com",
-					Role:  PatientRole,
-				}, []TokenOption{func(token jwt.Token) error {
-					return token.Set(jwt.ExpirationKey, time.Now().Add(-10*time.Hour))
-				}}...),
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "should not refresh token because the given refresh_token subject is not a UUID",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				tokens: mustSignTokensWithSubject(config, "not-a-uuid"),
 				changeToken: func(tokens *Tokens) {
 					tokens.GrantType = "refresh_token"
 				},
@@ -737,3 +1858,371 @@ func TestRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestChangePassword(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	user := User{
+		ID:    1,
+		UUID:  uuid.UUID{},
+		Email: "patient@hospital.com",
+		Role:  PatientRole,
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		change        PasswordChange
+		want          int
+	}{
+		{
+			name: "should change the password",
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withUpdatePasswordResult(sqlmock.NewResult(0, 1)),
+			},
+			change: PasswordChange{OldPassword: plainTestPassword, NewPassword: "n3wpassword"},
+			want:   http.StatusNoContent,
+		},
+		{
+			name: "should not change the password because the old password is wrong",
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+			},
+			change: PasswordChange{OldPassword: "wrong", NewPassword: "n3wpassword"},
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "should not change the password because the new password is weak",
+			change: PasswordChange{OldPassword: plainTestPassword, NewPassword: "weak"},
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "should not change the password because the old password is empty",
+			change: PasswordChange{NewPassword: "n3wpassword"},
+			want:   http.StatusBadRequest,
+		},
+		{
+			name: "should not change the password due to a database error while getting the password",
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashError(),
+			},
+			change: PasswordChange{OldPassword: plainTestPassword, NewPassword: "n3wpassword"},
+			want:   http.StatusInternalServerError,
+		},
+		{
+			name: "should not change the password because it was not updated",
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withUpdatePasswordResult(sqlmock.NewResult(0, 0)),
+			},
+			change: PasswordChange{OldPassword: plainTestPassword, NewPassword: "n3wpassword"},
+			want:   http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(user.ID, user.UUID, user.Email, user.Role)))
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), user)
+			body, _ := json.Marshal(tt.change)
+			req, _ := http.NewRequest("PUT", "/api/v1/auth/password", bytes.NewBuffer(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	patient := User{
+		ID:    1,
+		UUID:  uuid.UUID{},
+		Email: "patient@hospital.com",
+		Role:  PatientRole,
+	}
+	doctor := User{
+		ID:    1,
+		UUID:  uuid.UUID{},
+		Email: "doctor@hospital.com",
+		Role:  DoctorRole,
+	}
+	tests := []struct {
+		name          string
+		user          User
+		dbMockOptions []mock.DBResultOption
+		deletion      AccountDeletion
+		want          int
+	}{
+		{
+			name: "should delete the patient account",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deletePatientQueries, anonymizePatientQuery, nil),
+			},
+			deletion: AccountDeletion{Password: plainTestPassword},
+			want:     http.StatusNoContent,
+		},
+		{
+			name: "should delete the doctor account",
+			user: doctor,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deleteDoctorQueries, anonymizeDoctorQuery, nil),
+			},
+			deletion: AccountDeletion{Password: plainTestPassword},
+			want:     http.StatusNoContent,
+		},
+		{
+			name: "should delete the patient account with past appointments",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountWithHistoryResult(deletePatientQueries, anonymizePatientQuery),
+			},
+			deletion: AccountDeletion{Password: plainTestPassword},
+			want:     http.StatusNoContent,
+		},
+		{
+			name: "should delete the doctor account with past appointments",
+			user: doctor,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountWithHistoryResult(deleteDoctorQueries, anonymizeDoctorQuery),
+			},
+			deletion: AccountDeletion{Password: plainTestPassword},
+			want:     http.StatusNoContent,
+		},
+		{
+			name: "should not delete the account because the password is wrong",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+			},
+			deletion: AccountDeletion{Password: "wrong"},
+			want:     http.StatusUnauthorized,
+		},
+		{
+			name: "should not delete the account because the password is empty",
+			user: patient,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not delete the account due to a database error while deleting the profile",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deletePatientQueries, anonymizePatientQuery, sql.ErrConnDone),
+			},
+			deletion: AccountDeletion{Password: plainTestPassword},
+			want:     http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(tt.user.ID, tt.user.UUID, tt.user.Email, tt.user.Role)))
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), tt.user)
+			body, _ := json.Marshal(tt.deletion)
+			req, _ := http.NewRequest("DELETE", "/api/v1/auth/me", bytes.NewBuffer(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSetConsent(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	patient := User{ID: 1, UUID: uuid.UUID{}, Email: "patient@hospital.com", Role: PatientRole}
+	doctor := User{ID: 1, UUID: uuid.UUID{}, Email: "doctor@hospital.com", Role: DoctorRole}
+	tests := []struct {
+		name          string
+		user          User
+		dbMockOptions []mock.DBResultOption
+		body          string
+		want          int
+	}{
+		{
+			name: "should give the consent",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withUpdateConsentResult(true, sqlmock.NewResult(0, 1)),
+			},
+			body: `{"consent_given":true}`,
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should withdraw the consent",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withUpdateConsentResult(false, sqlmock.NewResult(0, 1)),
+			},
+			body: `{"consent_given":false}`,
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should not set the consent because it is missing",
+			user: patient,
+			body: `{}`,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not set the consent because the user is not a patient",
+			user: doctor,
+			body: `{"consent_given":true}`,
+			want: http.StatusForbidden,
+		},
+		{
+			name: "should not set the consent because it was not updated",
+			user: patient,
+			dbMockOptions: []mock.DBResultOption{
+				withUpdateConsentResult(true, sqlmock.NewResult(0, 0)),
+			},
+			body: `{"consent_given":true}`,
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(tt.user.ID, tt.user.UUID, tt.user.Email, tt.user.Role)))
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), tt.user)
+			req, _ := http.NewRequest("PUT", "/api/v1/auth/consent", strings.NewReader(tt.body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetAuthenticatedUserProfile(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	patient := User{ID: 1, UUID: uuid.UUID{}, Email: "patient@hospital.com", Role: PatientRole}
+	doctor := User{ID: 2, UUID: uuid.UUID{}, Email: "doctor@hospital.com", Role: DoctorRole}
+	tests := []struct {
+		name          string
+		user          User
+		url           string
+		dbMockOptions []mock.DBResultOption
+		wantResponse  string
+	}{
+		{
+			name: "should get the authenticated patient along with its profile",
+			user: patient,
+			url:  "/api/v1/auth/me?expand=profile",
+			dbMockOptions: []mock.DBResultOption{
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientProfileQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "mobile_phone"}).AddRow(uuid.UUID{}, "Patient", "patient@hospital.com", "5511999999999"))
+				},
+			},
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\",\"profile\":{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"Patient\",\"email\":\"patient@hospital.com\",\"mobile_phone\":\"5511999999999\"}}\n",
+		},
+		{
+			name: "should get the authenticated doctor along with its profile",
+			user: doctor,
+			url:  "/api/v1/auth/me?expand=profile",
+			dbMockOptions: []mock.DBResultOption{
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorProfileQuery)).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "mobile_phone", "specialty"}).AddRow(uuid.UUID{}, "John Doe", "doctor@hospital.com", "", "Cardiology"))
+				},
+			},
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"doctor@hospital.com\",\"role\":\"DOCTOR\",\"profile\":{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"email\":\"doctor@hospital.com\",\"mobile_phone\":\"\",\"specialty\":\"Cardiology\"}}\n",
+		},
+		{
+			name: "should get the authenticated user with an empty profile when it has no record",
+			user: patient,
+			url:  "/api/v1/auth/me?expand=profile",
+			dbMockOptions: []mock.DBResultOption{
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientProfileQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "mobile_phone"}))
+				},
+			},
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"patient@hospital.com\",\"role\":\"PATIENT\",\"profile\":null}\n",
+		},
+		{
+			name:         "should get only the authenticated user by default",
+			user:         doctor,
+			url:          "/api/v1/auth/me",
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"email\":\"doctor@hospital.com\",\"role\":\"DOCTOR\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+
+			router := chi.NewRouter()
+			Setup(router, logger, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindUserByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(tt.user.ID, tt.user.UUID, tt.user.Email, tt.user.Role)))
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := MustGenerateTokens(context.TODO(), config.PrivateKey(), tt.user)
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			if got := recorder.Body.String(); got != tt.wantResponse {
+				t.Errorf("response is incorrect, got %s, want %s", got, tt.wantResponse)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"hospital-booking/internal/apierrors"
 	"net/http"
 	"strings"
 )
@@ -10,22 +12,45 @@ type ctxKeyUser string
 
 const UserContextKey ctxKeyUser = "user"
 
+type ctxKeyClientIP string
+
+// ClientIPContextKey is the key of the IP of the client that performed the request, recorded along with the
+// login attempts.
+const ClientIPContextKey ctxKeyClientIP = "client_ip"
+
+// AuthenticateHeader is the header sent along with 401 responses, as defined by RFC 6750.
+const AuthenticateHeader = "WWW-Authenticate"
+
+// writeError aborts the request with the given status, describing the reason through an APIError body along with
+// its code, so the clients can tell the reasons apart.
+func writeError(writer http.ResponseWriter, httpStatusCode int, detail string, code string) {
+	writer.WriteHeader(httpStatusCode)
+	_ = json.NewEncoder(writer).Encode(apierrors.NewAPIError(apierrors.WithDetail(detail), apierrors.WithCode(code), apierrors.WithHTTPStatusCode(httpStatusCode)))
+}
+
 // JwtValidator middleware validates the Authorization header if there is one in the given request and
 // associate the user in the request's context with the key UserContextKey.
 //
-// If no Authorization header was found or if the token is not valid, abort the request with a 403 status.
+// If no Authorization header was found or if the token is not valid or expired, abort the request with a 401
+// status, challenging the client through the WWW-Authenticate header.
 func JwtValidator(service Authorizer) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			ctx := request.Context()
 			authHeader := request.Header.Get("Authorization")
 			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-				writer.WriteHeader(http.StatusUnauthorized)
+				writer.Header().Set(AuthenticateHeader, "Bearer")
+				writeError(writer, http.StatusUnauthorized, ErrMissingAuthorizationHeader, CodeMissingAuthorizationHeader)
 				return
 			}
 			user, err := service.ValidateToken(ctx, authHeader)
 			if err != nil {
-				writer.WriteHeader(http.StatusUnauthorized)
+				writer.Header().Set(AuthenticateHeader, `Bearer error="invalid_token"`)
+				if _, ok := err.(*TokenExpiredError); ok {
+					writeError(writer, http.StatusUnauthorized, ErrTokenExpired, CodeTokenExpired)
+					return
+				}
+				writeError(writer, http.StatusUnauthorized, ErrInvalidToken, CodeInvalidToken)
 				return
 			}
 			ctx = context.WithValue(ctx, UserContextKey, *user)
@@ -36,22 +61,41 @@ func JwtValidator(service Authorizer) func(next http.Handler) http.Handler {
 
 // AllowedRole middleware checks if the authenticated user has the given role.
 //
-// If there is no user authenticated or if the user doesn't have the given role, abort the request
-// with a 403 status.
+// If there is no user authenticated, abort the request with a 401 status, and if the user doesn't have
+// the given role, abort the request with a 403 status.
 func AllowedRole(service Authorizer, role Role) func(next http.Handler) http.Handler {
+	return AllowedRoles(service, role)
+}
+
+// AllowedRoles middleware checks if the authenticated user has any of the given roles, so a route can be
+// shared by users with different roles.
+//
+// If there is no user authenticated, abort the request with a 401 status, and if the user has none of
+// the given roles, abort the request with a 403 status.
+func AllowedRoles(service Authorizer, roles ...Role) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			ctx := request.Context()
 			user, err := service.GetAuthenticatedUser(ctx)
 			if err != nil {
-				writer.WriteHeader(http.StatusUnauthorized)
+				writeError(writer, http.StatusUnauthorized, ErrNotAuthenticated, CodeNotAuthenticated)
 				return
 			}
-			if user.Role != role {
-				writer.WriteHeader(http.StatusForbidden)
+			if !hasAnyRole(user, roles) {
+				writeError(writer, http.StatusForbidden, ErrInsufficientRole, CodeInsufficientRole)
 				return
 			}
 			next.ServeHTTP(writer, request.WithContext(ctx))
 		})
 	}
 }
+
+// hasAnyRole checks if the given user has any of the given roles.
+func hasAnyRole(user User, roles []Role) bool {
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,9 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"hospital-booking/internal/configs"
+	"hospital-booking/internal/mock"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -15,9 +18,11 @@ func TestAllowedRole(t *testing.T) {
 		role    Role
 	}
 	tests := []struct {
-		name string
-		args args
-		want int
+		name        string
+		args        args
+		want        int
+		wantMessage string
+		wantCode    string
 	}{
 		{
 			name: "should allow the request",
@@ -41,7 +46,23 @@ func TestAllowedRole(t *testing.T) {
 				},
 				role: PatientRole,
 			},
-			want: http.StatusForbidden,
+			want:        http.StatusForbidden,
+			wantMessage: ErrInsufficientRole,
+			wantCode:    CodeInsufficientRole,
+		},
+		{
+			name: "should not allow the request due to a missing authenticated user",
+			args: args{
+				service: mockAuthorizer{
+					mockGetAuthenticatedUser: func(ctx context.Context) (User, error) {
+						return User{}, NewUnauthorizedError()
+					},
+				},
+				role: PatientRole,
+			},
+			want:        http.StatusUnauthorized,
+			wantMessage: ErrNotAuthenticated,
+			wantCode:    CodeNotAuthenticated,
 		},
 	}
 	for _, tt := range tests {
@@ -62,6 +83,70 @@ func TestAllowedRole(t *testing.T) {
 			if response.StatusCode != tt.want {
 				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
 			}
+			if tt.wantMessage == "" {
+				return
+			}
+			body := struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			}{}
+			if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("response message is incorrect, got %s, want %s", body.Message, tt.wantMessage)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("response code is incorrect, got %s, want %s", body.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAllowedRoles(t *testing.T) {
+	tests := []struct {
+		name string
+		role Role
+		want int
+	}{
+		{
+			name: "should allow the request if the user has one of the roles",
+			role: AdminRole,
+			want: http.StatusOK,
+		},
+		{
+			name: "should allow the request if the user has another one of the roles",
+			role: DoctorRole,
+			want: http.StatusOK,
+		},
+		{
+			name: "should not allow the request if the user has none of the roles",
+			role: PatientRole,
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			service := mockAuthorizer{
+				mockGetAuthenticatedUser: func(ctx context.Context) (User, error) {
+					return User{Email: "user@hostpital.com", Role: tt.role}, nil
+				},
+			}
+
+			router := chi.NewRouter()
+			router.Use(AllowedRoles(service, DoctorRole, AdminRole))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
 		})
 	}
 }
@@ -72,9 +157,12 @@ func TestJwtValidator(t *testing.T) {
 		authHeader string
 	}
 	tests := []struct {
-		name string
-		args args
-		want int
+		name               string
+		args               args
+		want               int
+		wantMessage        string
+		wantCode           string
+		wantAuthentication string
 	}{
 		{
 			name: "should allow the request and return status 200",
@@ -98,7 +186,10 @@ func TestJwtValidator(t *testing.T) {
 				},
 				authHeader: "",
 			},
-			want: http.StatusUnauthorized,
+			want:               http.StatusUnauthorized,
+			wantMessage:        ErrMissingAuthorizationHeader,
+			wantCode:           CodeMissingAuthorizationHeader,
+			wantAuthentication: "Bearer",
 		},
 		{
 			name: "should not allow the request and return status 401 due to missing user",
@@ -110,7 +201,36 @@ func TestJwtValidator(t *testing.T) {
 				},
 				authHeader: "Bearer testing",
 			},
-			want: http.StatusUnauthorized,
+			want:               http.StatusUnauthorized,
+			wantMessage:        ErrInvalidToken,
+			wantCode:           CodeInvalidToken,
+			wantAuthentication: `Bearer error="invalid_token"`,
+		},
+		{
+			name: "should not allow the request and return status 401 due to a malformed token",
+			args: args{
+				service:    NewService(configs.MustLoad("./../../test/testdata/config_valid.json"), mock.MustCreateConnectionMock()),
+				authHeader: "Bearer not-a-token",
+			},
+			want:               http.StatusUnauthorized,
+			wantMessage:        ErrInvalidToken,
+			wantCode:           CodeInvalidToken,
+			wantAuthentication: `Bearer error="invalid_token"`,
+		},
+		{
+			name: "should not allow the request and return status 401 due to an expired token",
+			args: args{
+				service: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*User, error) {
+						return nil, NewTokenExpiredError()
+					},
+				},
+				authHeader: "Bearer testing",
+			},
+			want:               http.StatusUnauthorized,
+			wantMessage:        ErrTokenExpired,
+			wantCode:           CodeTokenExpired,
+			wantAuthentication: `Bearer error="invalid_token"`,
 		},
 	}
 	for _, tt := range tests {
@@ -132,6 +252,25 @@ func TestJwtValidator(t *testing.T) {
 			if response.StatusCode != tt.want {
 				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
 			}
+			if got := response.Header.Get(AuthenticateHeader); got != tt.wantAuthentication {
+				t.Errorf("authenticate header is incorrect, got %s, want %s", got, tt.wantAuthentication)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			body := struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			}{}
+			if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("response message is incorrect, got %s, want %s", body.Message, tt.wantMessage)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("response code is incorrect, got %s, want %s", body.Code, tt.wantCode)
+			}
 		})
 	}
 }
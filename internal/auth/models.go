@@ -2,6 +2,9 @@ package auth
 
 import (
 	"hospital-booking/internal/apierrors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -11,6 +14,7 @@ type Role string
 const (
 	PatientRole = "PATIENT"
 	DoctorRole  = "DOCTOR"
+	AdminRole   = "ADMIN"
 )
 
 type Credentials struct {
@@ -18,17 +22,123 @@ type Credentials struct {
 	Password string `json:"password,omitempty"`
 }
 
-// Validate validates if the credentials given are valid.
+// NormalizeEmail trims the given email and lowers its case, so it matches the stored one however it is typed.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Normalized returns the credentials with the email normalized. The password is kept as given, since its spaces
+// and case are part of it.
+func (c Credentials) Normalized() Credentials {
+	c.Email = NormalizeEmail(c.Email)
+	return c
+}
+
+// Validate validates if the credentials given are valid, returning the errors of all the fields at once.
 func (c Credentials) Validate() error {
+	var errs apierrors.ValidationErrors
 	if c.Email == "" {
-		return apierrors.NewValidationError("email", "required")
+		errs.Add("email", "required")
 	}
 	if c.Password == "" {
+		errs.Add("password", "required")
+	}
+	return errs.ErrorOrNil()
+}
+
+const (
+	minPasswordLength    = 8
+	maxNameLength        = 250
+	maxMobilePhoneLength = 12
+)
+
+// Registration holds the data given by a patient to register itself.
+type Registration struct {
+	Credentials
+	Name        string `json:"name,omitempty"`
+	MobilePhone string `json:"mobile_phone,omitempty"`
+}
+
+// Validate validates if the registration given is valid. Passwords must have at least 8 characters,
+// mixing letters and digits.
+func (r Registration) Validate() error {
+	if err := r.Credentials.Validate(); err != nil {
+		return err
+	}
+	if !isStrongPassword(r.Password) {
+		return apierrors.NewValidationError("password", "weak")
+	}
+	if r.Name == "" {
+		return apierrors.NewValidationError("name", "required")
+	}
+	if utf8.RuneCountInString(r.Name) > maxNameLength {
+		return apierrors.NewValidationError("name", "max")
+	}
+	if len(r.MobilePhone) > maxMobilePhoneLength {
+		return apierrors.NewValidationError("mobile_phone", "max")
+	}
+	return nil
+}
+
+// isStrongPassword checks if the given password is long enough and mixes letters and digits.
+func isStrongPassword(password string) bool {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return false
+	}
+	hasLetter, hasDigit := false, false
+	for _, char := range password {
+		hasLetter = hasLetter || unicode.IsLetter(char)
+		hasDigit = hasDigit || unicode.IsDigit(char)
+	}
+	return hasLetter && hasDigit
+}
+
+// PasswordChange holds the data given by a user to change its password.
+type PasswordChange struct {
+	OldPassword string `json:"old_password,omitempty"`
+	NewPassword string `json:"new_password,omitempty"`
+}
+
+// Validate validates if the password change given is valid, with the same strength rules of the registration.
+func (p PasswordChange) Validate() error {
+	if p.OldPassword == "" {
+		return apierrors.NewValidationError("old_password", "required")
+	}
+	if p.NewPassword == "" {
+		return apierrors.NewValidationError("new_password", "required")
+	}
+	if !isStrongPassword(p.NewPassword) {
+		return apierrors.NewValidationError("new_password", "weak")
+	}
+	return nil
+}
+
+// AccountDeletion holds the password of the user deleting its account, asked again as a safety check.
+type AccountDeletion struct {
+	Password string `json:"password,omitempty"`
+}
+
+// Validate validates if the password was given.
+func (a AccountDeletion) Validate() error {
+	if a.Password == "" {
 		return apierrors.NewValidationError("password", "required")
 	}
 	return nil
 }
 
+// Consent holds whether a patient consents to the processing of its data, which is required to book appointments.
+type Consent struct {
+	ConsentGiven *bool `json:"consent_given,omitempty"`
+}
+
+// Validate validates if the consent given is valid.
+func (c Consent) Validate() error {
+	if c.ConsentGiven == nil {
+		return apierrors.NewValidationError("consent_given", "required")
+	}
+	return nil
+}
+
 type Tokens struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -52,6 +162,24 @@ func (c Tokens) Validate() error {
 	return nil
 }
 
+// TokenClaims are the non-sensitive claims of a token, returned by its introspection. The times are given in
+// seconds since the epoch, as they are in the token.
+type TokenClaims struct {
+	Subject    string `json:"sub"`
+	Role       Role   `json:"role"`
+	Expiration int64  `json:"exp"`
+	IssuedAt   int64  `json:"iat"`
+	Type       string `json:"typ"`
+	JTI        string `json:"jti"`
+}
+
+// Authentication is the result of a successful authentication, holding the generated tokens and the
+// authenticated user, which is only given when the user is included.
+type Authentication struct {
+	*Tokens
+	User *User `json:"user,omitempty"`
+}
+
 type User struct {
 	ID       int64     `json:"-" dbfield:"id"`
 	UUID     uuid.UUID `json:"uuid" dbfield:"uuid"`
@@ -59,3 +187,28 @@ type User struct {
 	Password string    `json:"password,omitempty" dbfield:"password"`
 	Role     Role      `json:"role" dbfield:"role"`
 }
+
+// Patient holds the patient data created along with its user on registration.
+type Patient struct {
+	ID          int64     `dbfield:"id"`
+	UUID        uuid.UUID `dbfield:"uuid"`
+	UserID      int64     `dbfield:"user_id"`
+	Name        string    `dbfield:"name"`
+	Email       string    `dbfield:"email"`
+	MobilePhone string    `dbfield:"mobile_phone"`
+}
+
+// Profile holds the doctor or patient record linked to a user, based on its role.
+type Profile struct {
+	UUID        uuid.UUID `json:"uuid" dbfield:"uuid"`
+	Name        string    `json:"name" dbfield:"name"`
+	Email       string    `json:"email" dbfield:"email"`
+	MobilePhone string    `json:"mobile_phone" dbfield:"mobile_phone"`
+	Specialty   string    `json:"specialty,omitempty" dbfield:"specialty"`
+}
+
+// UserProfile is the user along with its profile, returned when the profile is expanded.
+type UserProfile struct {
+	User
+	Profile *Profile `json:"profile"`
+}
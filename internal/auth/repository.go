@@ -3,15 +3,43 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"hospital-booking/internal/database"
 
 	"github.com/google/uuid"
 )
 
 const (
-	findUserByUUIDQuery    = "SELECT id, uuid, email, role FROM tb_user WHERE uuid = $1"
-	findUserByEmailQuery   = "SELECT id, uuid, email, role FROM tb_user WHERE email = $1"
-	checkUserPasswordQuery = "SELECT id, password FROM tb_user WHERE email = $1"
+	findUserByUUIDQuery     = "SELECT id, uuid, email, role FROM tb_user WHERE uuid = $1"
+	findUserByEmailQuery    = "SELECT id, uuid, email, role FROM tb_user WHERE email = $1"
+	checkUserPasswordQuery  = "SELECT id, password FROM tb_user WHERE email = $1"
+	insertUserQuery         = "INSERT INTO tb_user (uuid, email, password, role) VALUES ($1, $2, $3, $4) RETURNING id"
+	insertPatientQuery      = "INSERT INTO tb_patient (uuid, user_id, name, email, mobile_phone) VALUES ($1, $2, $3, $4, $5)"
+	getPasswordHashQuery    = "SELECT password FROM tb_user WHERE id = $1"
+	updatePasswordQuery     = "UPDATE tb_user SET password = $1 WHERE id = $2"
+	updateConsentQuery      = "UPDATE tb_patient SET consent_given = $1 WHERE user_id = $2"
+	findPatientProfileQuery = "SELECT uuid, name, email, COALESCE(mobile_phone, '') AS mobile_phone FROM tb_patient WHERE user_id = $1"
+	findDoctorProfileQuery  = "SELECT uuid, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty FROM tb_doctor WHERE user_id = $1"
+	deleteUserQuery         = "DELETE FROM tb_user WHERE id = $1"
+	anonymizePatientQuery   = "UPDATE tb_patient SET user_id = NULL, name = 'Deleted patient', email = uuid::text, mobile_phone = NULL, consent_given = FALSE, deleted_at = now() WHERE user_id = $1 RETURNING id"
+	anonymizeDoctorQuery    = "UPDATE tb_doctor SET user_id = NULL, name = 'Deleted doctor', email = uuid::text, mobile_phone = NULL, specialty = NULL, active = FALSE, deleted_at = now() WHERE user_id = $1 RETURNING id"
+)
+
+// Queries used to delete the rows that depend on the profiles, run in order before anonymizing them. The future
+// appointments are deleted, while the past ones are kept for the records, so the profiles they reference are
+// anonymized and detached from the user instead of deleted.
+var (
+	deletePatientQueries = []string{
+		"DELETE FROM tb_idempotency WHERE patient_id IN (SELECT id FROM tb_patient WHERE user_id = $1)",
+		"DELETE FROM tb_appointment WHERE patient_id IN (SELECT id FROM tb_patient WHERE user_id = $1) AND date > now()",
+	}
+	deleteDoctorQueries = []string{
+		"DELETE FROM tb_idempotency WHERE appointment_uuid IN (SELECT uuid FROM tb_appointment WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1) AND date > now())",
+		"DELETE FROM tb_appointment WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1) AND date > now()",
+		"DELETE FROM tb_block_period WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1)",
+		"DELETE FROM tb_availability_exception WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1)",
+	}
 )
 
 // Repository provides access to auth data.
@@ -25,6 +53,35 @@ type Repository interface {
 
 	// CheckUserPassword checks if the stored password is equals to the given password.
 	CheckUserPassword(ctx context.Context, email string, password string) (bool, error)
+
+	// Transaction runs the given function in a transaction, used to perform the inserts below at once.
+	Transaction(ctx context.Context, fn func(tx *sql.Tx) error) error
+
+	// InsertUser inserts the given user, with its password already encrypted, setting its ID.
+	InsertUser(ctx context.Context, tx *sql.Tx, user *User) error
+
+	// InsertPatient inserts the given patient.
+	InsertPatient(ctx context.Context, tx *sql.Tx, patient Patient) error
+
+	// GetPasswordHash gets the encrypted password of the given user, or an empty string if it doesn't exist.
+	GetPasswordHash(ctx context.Context, userID int64) (string, error)
+
+	// UpdatePassword updates the password of the given user, with the given password already encrypted.
+	UpdatePassword(ctx context.Context, userID int64, hashedPassword string) error
+
+	// UpdateConsent updates whether the patient linked to the given user consents to the processing of its data.
+	UpdateConsent(ctx context.Context, userID int64, consentGiven bool) error
+
+	// FindProfileByUserID finds the doctor or patient record linked to the given user, based on the given role.
+	FindProfileByUserID(ctx context.Context, userID int64, role Role) (*Profile, error)
+
+	// DeleteProfile deletes the future appointments of the doctor or patient record linked to the given user, based
+	// on the given role, anonymizing the record so its past appointments are kept without its personal data. The ID
+	// of the record is returned, or 0 if the user has none.
+	DeleteProfile(ctx context.Context, tx *sql.Tx, userID int64, role Role) (int64, error)
+
+	// DeleteUser deletes the given user.
+	DeleteUser(ctx context.Context, tx *sql.Tx, userID int64) error
 }
 
 type defaultRepository struct {
@@ -41,7 +98,7 @@ func (d defaultRepository) FindUserByUUID(ctx context.Context, uuid uuid.UUID) (
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = uuid.String()
-	rows, err := d.dbConn.DB().QueryContext(ctx, findUserByUUIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_user_by_uuid", findUserByUUIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +120,7 @@ func (d defaultRepository) FindUserByEmail(ctx context.Context, email string) (*
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = email
-	rows, err := d.dbConn.DB().QueryContext(ctx, findUserByEmailQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_user_by_email", findUserByEmailQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -85,14 +142,185 @@ func (d defaultRepository) CheckUserPassword(ctx context.Context, email string,
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = email
-	row := d.dbConn.DB().QueryRowContext(ctx, checkUserPasswordQuery, params...)
-	if row.Err() != nil {
-		return false, row.Err()
-	}
 	id := new(uint64)
 	hashedPass := new(string)
-	if err := row.Scan(id, hashedPass); err != nil && err != sql.ErrNoRows {
+	err := database.Timed("check_user_password", func() error {
+		return database.Retry(ctx, d.dbConn.Retries(), func() error {
+			return d.dbConn.DB().QueryRowContext(ctx, checkUserPasswordQuery, params...).Scan(id, hashedPass)
+		})
+	})
+	if err != nil && err != sql.ErrNoRows {
 		return false, err
 	}
 	return ComparePasswords(*hashedPass, password), nil
 }
+
+func (d defaultRepository) Transaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return database.Transaction(ctx, d.dbConn.DB(), fn)
+}
+
+func (d defaultRepository) InsertUser(ctx context.Context, tx *sql.Tx, user *User) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 4)
+	params[0] = user.UUID.String()
+	params[1] = user.Email
+	params[2] = user.Password
+	params[3] = user.Role
+	return database.Timed("insert_user", func() error {
+		return tx.QueryRowContext(ctx, insertUserQuery, params...).Scan(&user.ID)
+	})
+}
+
+func (d defaultRepository) InsertPatient(ctx context.Context, tx *sql.Tx, patient Patient) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 5)
+	params[0] = patient.UUID.String()
+	params[1] = patient.UserID
+	params[2] = patient.Name
+	params[3] = patient.Email
+	params[4] = patient.MobilePhone
+	result, err := database.Exec(ctx, tx, "insert_patient", insertPatientQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("patient not inserted")
+	}
+	return nil
+}
+
+func (d defaultRepository) GetPasswordHash(ctx context.Context, userID int64) (string, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = userID
+	hashedPassword := ""
+	err := database.Timed("get_password_hash", func() error {
+		return database.Retry(ctx, d.dbConn.Retries(), func() error {
+			return d.dbConn.DB().QueryRowContext(ctx, getPasswordHashQuery, params...).Scan(&hashedPassword)
+		})
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return hashedPassword, nil
+}
+
+func (d defaultRepository) UpdatePassword(ctx context.Context, userID int64, hashedPassword string) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = hashedPassword
+	params[1] = userID
+	result, err := database.Exec(ctx, d.dbConn.DB(), "update_password", updatePasswordQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("password not updated")
+	}
+	return nil
+}
+
+func (d defaultRepository) UpdateConsent(ctx context.Context, userID int64, consentGiven bool) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = consentGiven
+	params[1] = userID
+	result, err := database.Exec(ctx, d.dbConn.DB(), "update_consent", updateConsentQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("consent not updated")
+	}
+	return nil
+}
+
+func (d defaultRepository) FindProfileByUserID(ctx context.Context, userID int64, role Role) (*Profile, error) {
+	query := findPatientProfileQuery
+	if role == DoctorRole {
+		query = findDoctorProfileQuery
+	}
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = userID
+	rows, err := database.Query(ctx, d.dbConn, "find_profile", query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	for rows.Next() {
+		profile := &Profile{}
+		if err = database.TransformRow(rows, profile); err != nil {
+			return nil, err
+		}
+		return profile, nil
+	}
+	return nil, rows.Err()
+}
+
+func (d defaultRepository) DeleteProfile(ctx context.Context, tx *sql.Tx, userID int64, role Role) (int64, error) {
+	var queries []string
+	var anonymizeQuery string
+	switch role {
+	case PatientRole:
+		queries, anonymizeQuery = deletePatientQueries, anonymizePatientQuery
+	case DoctorRole:
+		queries, anonymizeQuery = deleteDoctorQueries, anonymizeDoctorQuery
+	default:
+		return 0, nil
+	}
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = userID
+	for _, query := range queries {
+		if _, err := database.Exec(ctx, tx, "delete_profile", query, params...); err != nil {
+			return 0, err
+		}
+	}
+	var profileID int64
+	err := database.Timed("anonymize_profile", func() error {
+		return tx.QueryRowContext(ctx, anonymizeQuery, params...).Scan(&profileID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return profileID, err
+}
+
+func (d defaultRepository) DeleteUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = userID
+	result, err := database.Exec(ctx, tx, "delete_user", deleteUserQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("user not deleted")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"hospital-booking/internal/mock"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDeleteProfile(t *testing.T) {
+	tests := []struct {
+		name               string
+		role               Role
+		wantQueries        []string
+		wantAnonymizeQuery string
+	}{
+		{
+			name: "should anonymize the patient, deleting the rows that reference it but the past appointments",
+			role: PatientRole,
+			wantQueries: []string{
+				"DELETE FROM tb_idempotency WHERE patient_id IN (SELECT id FROM tb_patient WHERE user_id = $1)",
+				"DELETE FROM tb_appointment WHERE patient_id IN (SELECT id FROM tb_patient WHERE user_id = $1) AND date > now()",
+			},
+			wantAnonymizeQuery: "UPDATE tb_patient SET user_id = NULL, name = 'Deleted patient', email = uuid::text, mobile_phone = NULL, consent_given = FALSE, deleted_at = now() WHERE user_id = $1 RETURNING id",
+		},
+		{
+			name: "should anonymize the doctor, deleting the rows that reference it but the past appointments",
+			role: DoctorRole,
+			wantQueries: []string{
+				"DELETE FROM tb_idempotency WHERE appointment_uuid IN (SELECT uuid FROM tb_appointment WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1) AND date > now())",
+				"DELETE FROM tb_appointment WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1) AND date > now()",
+				"DELETE FROM tb_block_period WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1)",
+				"DELETE FROM tb_availability_exception WHERE doctor_id IN (SELECT id FROM tb_doctor WHERE user_id = $1)",
+			},
+			wantAnonymizeQuery: "UPDATE tb_doctor SET user_id = NULL, name = 'Deleted doctor', email = uuid::text, mobile_phone = NULL, specialty = NULL, active = FALSE, deleted_at = now() WHERE user_id = $1 RETURNING id",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			dbConn.SQLMock.ExpectBegin()
+			for _, query := range tt.wantQueries {
+				dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(tt.wantAnonymizeQuery)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			dbConn.SQLMock.ExpectCommit()
+			repository := newRepository(dbConn)
+			var profileID int64
+			err := repository.Transaction(context.Background(), func(tx *sql.Tx) (err error) {
+				profileID, err = repository.DeleteProfile(context.Background(), tx, 1, tt.role)
+				return err
+			})
+			if err != nil {
+				t.Errorf("DeleteProfile() error = %v", err)
+			}
+			if profileID != 7 {
+				t.Errorf("DeleteProfile() profile ID = %d, want %d", profileID, 7)
+			}
+			if err = dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("DeleteProfile() expectations = %v", err)
+			}
+		})
+	}
+}
@@ -2,21 +2,28 @@ package auth
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"hospital-booking/internal/apierrors"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/database"
+	"hospital-booking/internal/logging"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/lestrrat-go/jwx/jwt"
 )
 
 // Authenticator determines the methods available to users get authenticated.
 type Authenticator interface {
 
-	// Authenticate authenticates a user by its credentials and returns a JWT tokens, otherwise an error.
-	Authenticate(ctx context.Context, credentials Credentials) (*Tokens, error)
+	// Authenticate authenticates a user by its credentials and returns a JWT tokens along with the
+	// authenticated user, otherwise an error. The email is matched ignoring its case and surrounding spaces.
+	Authenticate(ctx context.Context, credentials Credentials) (*Authentication, error)
 }
 
 // Authorizer determines the methods used to authorize a user to perform some action.
@@ -32,25 +39,159 @@ type Authorizer interface {
 	GetAuthenticatedUser(ctx context.Context) (User, error)
 }
 
+// Registrar determines the methods available to users register themselves.
+type Registrar interface {
+
+	// Register registers a new patient, returning its user. The email is stored trimmed and lowercase.
+	Register(ctx context.Context, registration Registration) (*User, error)
+}
+
+// PasswordChanger determines the methods available to users change their passwords.
+type PasswordChanger interface {
+
+	// ChangePassword changes the password of the given user, once its current password is confirmed.
+	ChangePassword(ctx context.Context, user User, change PasswordChange) error
+}
+
+// ConsentManager determines the methods available to patients manage their consent.
+type ConsentManager interface {
+
+	// SetConsent sets whether the given patient consents to the processing of its data, required to book
+	// appointments.
+	SetConsent(ctx context.Context, user User, consent Consent) error
+}
+
+// AccountDeleter determines the methods available to users delete their own accounts.
+type AccountDeleter interface {
+
+	// DeleteAccount deletes the given user along with its future appointments, once its password is confirmed. Its
+	// doctor or patient profile is anonymized, keeping the past appointments for the records.
+	DeleteAccount(ctx context.Context, user User, deletion AccountDeletion) error
+}
+
+// ProfileReader determines the methods used to get the profiles linked to the users.
+type ProfileReader interface {
+
+	// GetProfile gets the doctor or patient profile of the given user, if it has one.
+	GetProfile(ctx context.Context, user User) (*Profile, error)
+}
+
+// TokenIntrospector determines the methods used to inspect the tokens, e.g. while debugging them.
+type TokenIntrospector interface {
+
+	// IntrospectToken validates the given token, returning its non-sensitive claims.
+	IntrospectToken(ctx context.Context, token string) (*TokenClaims, error)
+}
+
+// KeySetPublisher determines the methods used to publish the keys that verify the tokens.
+type KeySetPublisher interface {
+
+	// GetKeySet gets the public keys used to verify the tokens, as a JWK set.
+	GetKeySet() (jwk.Set, error)
+}
+
 type Service interface {
 	Authenticator
 	Authorizer
+	Registrar
+	PasswordChanger
+	ConsentManager
+	AccountDeleter
+	ProfileReader
+	KeySetPublisher
+	TokenIntrospector
+}
+
+// CalendarInvalidator drops the cached calendars of the doctors, which are computed by the calendar package.
+type CalendarInvalidator interface {
+
+	// InvalidateDoctor drops all the cached calendars of the doctor.
+	InvalidateDoctor(doctorID int64)
 }
 
+// noopCalendarInvalidator is used when the calendars are not cached.
+type noopCalendarInvalidator struct{}
+
+func (n noopCalendarInvalidator) InvalidateDoctor(doctorID int64) {}
+
 type defaultService struct {
 	repository Repository
 	config     configs.Config
+	audit      AuthAudit
+	hasher     PasswordHasher
+	calendar   CalendarInvalidator
+}
+
+// ServiceOption determines the Functional Options used to create a new Service.
+type ServiceOption func(s *defaultService)
+
+// WithAuthAudit sets the AuthAudit called on each login attempt. By default, the attempts are not recorded.
+func WithAuthAudit(audit AuthAudit) ServiceOption {
+	return func(s *defaultService) {
+		s.audit = audit
+	}
+}
+
+// WithCalendarInvalidator sets the CalendarInvalidator called once a doctor's account is deleted, so its cached
+// calendars are dropped. By default, nothing is invalidated.
+func WithCalendarInvalidator(calendar CalendarInvalidator) ServiceOption {
+	return func(s *defaultService) {
+		s.calendar = calendar
+	}
 }
 
 // NewService creates a new auth service.
-func NewService(config configs.Config, dbConn database.Connection) Service {
-	return &defaultService{
+func NewService(config configs.Config, dbConn database.Connection, opts ...ServiceOption) Service {
+	service := &defaultService{
 		config:     config,
 		repository: newRepository(dbConn),
+		audit:      noopAuthAudit{},
+		hasher:     NewPasswordHasher(config.PasswordHasher()),
+		calendar:   noopCalendarInvalidator{},
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+// issuer gets the issuer of the tokens, which can be overridden through the configuration.
+func (d defaultService) issuer() string {
+	if issuer := d.config.TokenIssuer(); issuer != "" {
+		return issuer
+	}
+	return IssuerDefault
+}
+
+// audience gets the audience of the tokens, which can be overridden through the configuration.
+func (d defaultService) audience() string {
+	if audience := d.config.TokenAudience(); audience != "" {
+		return audience
 	}
+	return AudienceDefault
 }
 
-func (d defaultService) Authenticate(ctx context.Context, credentials Credentials) (*Tokens, error) {
+// allowedAudiences gets the audiences accepted when validating the tokens: the audience of the tokens plus the
+// configured ones, so the same key can serve several apps.
+func (d defaultService) allowedAudiences() []string {
+	return append([]string{d.audience()}, d.config.AllowedAudiences()...)
+}
+
+// tokenOptions gets the options used to generate the tokens, based on the configuration. The tokens expiration
+// defaults to AccessTokenExpiration and RefreshTokenExpiration, unless overridden.
+func (d defaultService) tokenOptions() []TokenOption {
+	opts := []TokenOption{WithIssuer(d.issuer()), WithAudience([]string{d.audience()})}
+	if ttl := d.config.AccessTokenTTL(); ttl > 0 {
+		opts = append(opts, WithAccessTokenExpiration(ttl))
+	}
+	if ttl := d.config.RefreshTokenTTL(); ttl > 0 {
+		opts = append(opts, WithRefreshTokenExpiration(ttl))
+	}
+	return opts
+}
+
+func (d defaultService) Authenticate(ctx context.Context, credentials Credentials) (*Authentication, error) {
+	credentials = credentials.Normalized()
 	if err := credentials.Validate(); err != nil {
 		return nil, err
 	}
@@ -59,6 +200,7 @@ func (d defaultService) Authenticate(ctx context.Context, credentials Credential
 		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if user == nil {
+		d.recordLogin(ctx, credentials.Email, false)
 		return nil, NewUnauthorizedError()
 	}
 	isValidCredentials, err := d.repository.CheckUserPassword(ctx, credentials.Email, credentials.Password)
@@ -66,21 +208,175 @@ func (d defaultService) Authenticate(ctx context.Context, credentials Credential
 		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if !isValidCredentials {
+		d.recordLogin(ctx, credentials.Email, false)
 		return nil, NewUnauthorizedError()
 	}
-	return GenerateTokens(ctx, d.config.PrivateKey(), *user)
+	tokens, err := GenerateTokens(ctx, d.config.PrivateKey(), *user, d.tokenOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	d.recordLogin(ctx, credentials.Email, true)
+	return &Authentication{Tokens: tokens, User: user}, nil
+}
+
+// recordLogin records the login attempt, from the IP associated to the context with the key ClientIPContextKey.
+func (d defaultService) recordLogin(ctx context.Context, email string, success bool) {
+	ip, _ := ctx.Value(ClientIPContextKey).(string)
+	// the audit is only a record, so a failure to write it must not block the login
+	if err := d.audit.RecordLogin(ctx, email, success, ip); err != nil {
+		logging.FromContext(ctx).Error(fmt.Errorf("an error occurred while recording the login attempt: %w", err))
+	}
+}
+
+// emailAlreadyRegisteredError is returned when the email given on registration is taken.
+func emailAlreadyRegisteredError() error {
+	return apierrors.NewAPIError(apierrors.WithDetail(ErrEmailAlreadyRegistered), apierrors.WithCode(CodeEmailAlreadyRegistered), apierrors.WithHTTPStatusCode(http.StatusConflict))
+}
+
+func (d defaultService) Register(ctx context.Context, registration Registration) (*User, error) {
+	registration.Credentials = registration.Credentials.Normalized()
+	if err := registration.Validate(); err != nil {
+		return nil, err
+	}
+	existingUser, err := d.repository.FindUserByEmail(ctx, registration.Email)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if existingUser != nil {
+		return nil, emailAlreadyRegisteredError()
+	}
+	hashedPassword, err := d.hasher.Hash(registration.Password)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	user := &User{
+		UUID:     uuid.New(),
+		Email:    registration.Email,
+		Password: hashedPassword,
+		Role:     PatientRole,
+	}
+	err = d.repository.Transaction(ctx, func(tx *sql.Tx) error {
+		if err := d.repository.InsertUser(ctx, tx, user); err != nil {
+			return err
+		}
+		return d.repository.InsertPatient(ctx, tx, Patient{
+			UUID:        uuid.New(),
+			UserID:      user.ID,
+			Name:        registration.Name,
+			Email:       registration.Email,
+			MobilePhone: registration.MobilePhone,
+		})
+	})
+	if database.IsUniqueViolation(err) {
+		// the email was taken by a concurrent registration
+		return nil, emailAlreadyRegisteredError()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	user.Password = ""
+	return user, nil
+}
+
+func (d defaultService) ChangePassword(ctx context.Context, user User, change PasswordChange) error {
+	if err := change.Validate(); err != nil {
+		return err
+	}
+	hashedPassword, err := d.repository.GetPasswordHash(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if !ComparePasswords(hashedPassword, change.OldPassword) {
+		return NewUnauthorizedError()
+	}
+	newHashedPassword, err := d.hasher.Hash(change.NewPassword)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if err = d.repository.UpdatePassword(ctx, user.ID, newHashedPassword); err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	return nil
+}
+
+func (d defaultService) SetConsent(ctx context.Context, user User, consent Consent) error {
+	if err := consent.Validate(); err != nil {
+		return err
+	}
+	if err := d.repository.UpdateConsent(ctx, user.ID, *consent.ConsentGiven); err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	return nil
+}
+
+func (d defaultService) DeleteAccount(ctx context.Context, user User, deletion AccountDeletion) error {
+	if err := deletion.Validate(); err != nil {
+		return err
+	}
+	hashedPassword, err := d.repository.GetPasswordHash(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if !ComparePasswords(hashedPassword, deletion.Password) {
+		return NewUnauthorizedError()
+	}
+	var profileID int64
+	err = d.repository.Transaction(ctx, func(tx *sql.Tx) error {
+		if profileID, err = d.repository.DeleteProfile(ctx, tx, user.ID, user.Role); err != nil {
+			return err
+		}
+		return d.repository.DeleteUser(ctx, tx, user.ID)
+	})
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if user.Role == DoctorRole && profileID > 0 {
+		// the doctor's future appointments are gone and its slots must no longer be served
+		d.calendar.InvalidateDoctor(profileID)
+	}
+	return nil
+}
+
+func (d defaultService) GetProfile(ctx context.Context, user User) (*Profile, error) {
+	if user.Role != PatientRole && user.Role != DoctorRole {
+		return nil, nil
+	}
+	profile, err := d.repository.FindProfileByUserID(ctx, user.ID, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	return profile, nil
 }
 
 func (d defaultService) ValidateToken(ctx context.Context, token string) (*User, error) {
 	bearer := strings.TrimPrefix(token, "Bearer ")
-	parsedToken, err := ParseToken(bearer, d.config.PrivateKey().PublicKey)
+	parsedToken, err := ParseToken(bearer, d.config.PrivateKey().Public(), d.issuer(), d.allowedAudiences(), d.config.TokenLeeway())
+	if errors.Is(err, errTokenExpired) {
+		return nil, NewTokenExpiredError()
+	}
 	if err != nil {
 		return nil, NewUnauthorizedError()
 	}
-	if !time.Now().Before(parsedToken.Expiration()) {
+	if !hasExpiration(parsedToken) {
+		logging.FromContext(ctx).Warn("the access token was rejected because it has no expiration claim")
+		return nil, NewUnauthorizedError()
+	}
+	now := time.Now()
+	if !now.Before(parsedToken.Expiration().Add(d.config.TokenLeeway())) {
+		return nil, NewTokenExpiredError()
+	}
+	if now.Add(d.config.TokenLeeway()).Before(parsedToken.NotBefore()) {
+		return nil, NewUnauthorizedError()
+	}
+	if tokenType(parsedToken) != AccessTokenType {
+		return nil, NewUnauthorizedError()
+	}
+	// the token is signed by us, but its subject is still checked so a malformed one can't crash the request
+	userUUID, err := uuid.Parse(parsedToken.Subject())
+	if err != nil {
 		return nil, NewUnauthorizedError()
 	}
-	user, err := d.repository.FindUserByUUID(ctx, uuid.MustParse(parsedToken.Subject()))
+	user, err := d.repository.FindUserByUUID(ctx, userUUID)
 	if err != nil {
 		return nil, NewUnauthorizedError()
 	}
@@ -90,25 +386,65 @@ func (d defaultService) ValidateToken(ctx context.Context, token string) (*User,
 	return user, nil
 }
 
+func (d defaultService) IntrospectToken(ctx context.Context, token string) (*TokenClaims, error) {
+	bearer := strings.TrimPrefix(token, "Bearer ")
+	parsedToken, err := ParseToken(bearer, d.config.PrivateKey().Public(), d.issuer(), d.allowedAudiences(), d.config.TokenLeeway())
+	if err != nil {
+		return nil, NewUnauthorizedError()
+	}
+	role, _ := parsedToken.Get("role")
+	roleString, _ := role.(string)
+	jti, _ := parsedToken.Get(jwt.JwtIDKey)
+	jtiString, _ := jti.(string)
+	return &TokenClaims{
+		Subject:    parsedToken.Subject(),
+		Role:       Role(roleString),
+		Expiration: parsedToken.Expiration().Unix(),
+		IssuedAt:   parsedToken.IssuedAt().Unix(),
+		Type:       tokenType(parsedToken),
+		JTI:        jtiString,
+	}, nil
+}
+
 func (d defaultService) RefreshTokens(ctx context.Context, tokens Tokens) (*Tokens, error) {
 	if err := tokens.Validate(); err != nil {
 		return nil, err
 	}
-	refreshToken, err := jwt.ParseString(tokens.RefreshToken)
+	// the refresh token is verified as the access ones, otherwise its claims would be chosen by the caller
+	refreshToken, err := ParseToken(tokens.RefreshToken, d.config.PrivateKey().Public(), d.issuer(), d.allowedAudiences(), d.config.TokenLeeway())
 	if err != nil {
 		return nil, NewUnauthorizedError()
 	}
-	if !time.Now().Before(refreshToken.Expiration()) {
+	if !hasExpiration(refreshToken) {
+		logging.FromContext(ctx).Warn("the refresh token was rejected because it has no expiration claim")
+		return nil, NewUnauthorizedError()
+	}
+	now := time.Now()
+	if !now.Before(refreshToken.Expiration().Add(d.config.TokenLeeway())) {
+		return nil, NewUnauthorizedError()
+	}
+	if now.Add(d.config.TokenLeeway()).Before(refreshToken.NotBefore()) {
 		return nil, NewUnauthorizedError()
 	}
-	user, err := d.repository.FindUserByUUID(ctx, uuid.MustParse(refreshToken.Subject()))
+	if tokenType(refreshToken) != RefreshTokenType {
+		return nil, NewUnauthorizedError()
+	}
+	userUUID, err := uuid.Parse(refreshToken.Subject())
+	if err != nil {
+		return nil, NewUnauthorizedError()
+	}
+	user, err := d.repository.FindUserByUUID(ctx, userUUID)
 	if err != nil {
 		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if user == nil {
 		return nil, NewUnauthorizedError()
 	}
-	return GenerateTokens(ctx, d.config.PrivateKey(), *user)
+	return GenerateTokens(ctx, d.config.PrivateKey(), *user, d.tokenOptions()...)
+}
+
+func (d defaultService) GetKeySet() (jwk.Set, error) {
+	return NewKeySet(d.config.PrivateKey())
 }
 
 func (d defaultService) GetAuthenticatedUser(ctx context.Context) (User, error) {
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hospital-booking/internal/configs"
+	"hospital-booking/internal/mock"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+// loginAttempt is a login attempt recorded by the mockAuthAudit.
+type loginAttempt struct {
+	email   string
+	success bool
+	ip      string
+}
+
+type mockAuthAudit struct {
+	attempts []loginAttempt
+	err      error
+}
+
+func (m *mockAuthAudit) RecordLogin(ctx context.Context, email string, success bool, ip string) error {
+	m.attempts = append(m.attempts, loginAttempt{email: email, success: success, ip: ip})
+	return m.err
+}
+
+type mockCalendarInvalidator struct {
+	doctorIDs []int64
+}
+
+func (m *mockCalendarInvalidator) InvalidateDoctor(doctorID int64) {
+	m.doctorIDs = append(m.doctorIDs, doctorID)
+}
+
+func TestAuthenticateRecordsLogin(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "email", "role"}).AddRow(1, uuid.UUID{}, "patient@hospital.com", PatientRole)
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		password      string
+		auditErr      error
+		wantErr       bool
+		wantAttempts  []loginAttempt
+	}{
+		{
+			name: "should record the successful login",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(userRows()),
+				withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+			},
+			password:     plainTestPassword,
+			wantAttempts: []loginAttempt{{email: "patient@hospital.com", success: true, ip: "10.0.0.1"}},
+		},
+		{
+			name: "should record the failed login because the password was wrong",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(userRows()),
+				withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+			},
+			password:     "wrong",
+			wantErr:      true,
+			wantAttempts: []loginAttempt{{email: "patient@hospital.com", success: false, ip: "10.0.0.1"}},
+		},
+		{
+			name: "should record the failed login because the user was not found",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(sqlmock.NewRows([]string{"id", "uuid", "email", "role"})),
+			},
+			password:     plainTestPassword,
+			wantErr:      true,
+			wantAttempts: []loginAttempt{{email: "patient@hospital.com", success: false, ip: "10.0.0.1"}},
+		},
+		{
+			name: "should authenticate the user even though the login could not be recorded",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailResult(userRows()),
+				withCheckUserPasswordResult(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, hashedTestPassword)),
+			},
+			password:     plainTestPassword,
+			auditErr:     errors.New("audit unavailable"),
+			wantAttempts: []loginAttempt{{email: "patient@hospital.com", success: true, ip: "10.0.0.1"}},
+		},
+		{
+			name: "should not record the login when the outcome is unknown due to a database error",
+			dbMockOptions: []mock.DBResultOption{
+				withFindUserByEmailError(),
+			},
+			password: plainTestPassword,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+			audit := &mockAuthAudit{err: tt.auditErr}
+			service := NewService(config, dbConn, WithAuthAudit(audit))
+
+			ctx := context.WithValue(context.Background(), ClientIPContextKey, "10.0.0.1")
+			_, err := service.Authenticate(ctx, Credentials{Email: "patient@hospital.com", Password: tt.password})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(audit.attempts) != len(tt.wantAttempts) {
+				t.Fatalf("recorded attempts are incorrect, got %v, want %v", audit.attempts, tt.wantAttempts)
+			}
+			for i, attempt := range audit.attempts {
+				if attempt != tt.wantAttempts[i] {
+					t.Errorf("recorded attempt is incorrect, got %v, want %v", attempt, tt.wantAttempts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteAccountInvalidatesTheDoctorCalendars(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tests := []struct {
+		name          string
+		role          Role
+		dbMockOptions []mock.DBResultOption
+		wantErr       bool
+		wantDoctorIDs []int64
+	}{
+		{
+			name: "should invalidate the calendars of the deleted doctor",
+			role: DoctorRole,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deleteDoctorQueries, anonymizeDoctorQuery, nil),
+			},
+			wantDoctorIDs: []int64{1},
+		},
+		{
+			name: "should not invalidate any calendar when a patient is deleted",
+			role: PatientRole,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deletePatientQueries, anonymizePatientQuery, nil),
+			},
+		},
+		{
+			name: "should not invalidate the calendars when the doctor could not be deleted",
+			role: DoctorRole,
+			dbMockOptions: []mock.DBResultOption{
+				withGetPasswordHashResult(sqlmock.NewRows([]string{"password"}).AddRow(hashedTestPassword)),
+				withDeleteAccountResult(deleteDoctorQueries, anonymizeDoctorQuery, sql.ErrConnDone),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+			calendar := &mockCalendarInvalidator{}
+			service := NewService(config, dbConn, WithCalendarInvalidator(calendar))
+
+			err := service.DeleteAccount(context.Background(), User{ID: 1, Role: tt.role}, AccountDeletion{Password: plainTestPassword})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteAccount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(calendar.doctorIDs) != len(tt.wantDoctorIDs) {
+				t.Fatalf("invalidated doctors are incorrect, got %v, want %v", calendar.doctorIDs, tt.wantDoctorIDs)
+			}
+			for i, doctorID := range calendar.doctorIDs {
+				if doctorID != tt.wantDoctorIDs[i] {
+					t.Errorf("invalidated doctor is incorrect, got %d, want %d", doctorID, tt.wantDoctorIDs[i])
+				}
+			}
+			if err = dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
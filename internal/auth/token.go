@@ -3,8 +3,13 @@ package auth
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -85,6 +90,23 @@ func WithType(typ string) TokenOption {
 	}
 }
 
+// tokenType gets the type of the given token, set through WithType.
+func tokenType(token jwt.Token) string {
+	typ, found := token.Get("typ")
+	if !found {
+		return ""
+	}
+	typString, _ := typ.(string)
+	return typString
+}
+
+// hasExpiration tells whether the given token has the expiration claim. Tokens without it never expire, so they
+// must be rejected, whatever the library does with the missing claim.
+func hasExpiration(token jwt.Token) bool {
+	_, found := token.Get(jwt.ExpirationKey)
+	return found
+}
+
 // WithExpiration determines the token expiration time.
 func WithExpiration(duration time.Duration) TokenOption {
 	return func(token jwt.Token) error {
@@ -92,6 +114,33 @@ func WithExpiration(duration time.Duration) TokenOption {
 	}
 }
 
+// WithAccessTokenExpiration determines the expiration time of the access tokens, leaving the other ones untouched.
+func WithAccessTokenExpiration(duration time.Duration) TokenOption {
+	return withExpirationOf(AccessTokenType, duration)
+}
+
+// WithRefreshTokenExpiration determines the expiration time of the refresh tokens, leaving the other ones untouched.
+func WithRefreshTokenExpiration(duration time.Duration) TokenOption {
+	return withExpirationOf(RefreshTokenType, duration)
+}
+
+// withExpirationOf determines the expiration time of the tokens of the given type, so it must be given after WithType.
+func withExpirationOf(typ string, duration time.Duration) TokenOption {
+	return func(token jwt.Token) error {
+		if tokenType(token) != typ {
+			return nil
+		}
+		return WithExpiration(duration)(token)
+	}
+}
+
+// WithNotBefore determines the time before which the token must not be accepted.
+func WithNotBefore(notBefore time.Time) TokenOption {
+	return func(token jwt.Token) error {
+		return token.Set(jwt.NotBeforeKey, notBefore)
+	}
+}
+
 // WithJTI sets a unique UUID to the token.
 func WithJTI() TokenOption {
 	return func(token jwt.Token) error {
@@ -124,9 +173,38 @@ func WithRole(role Role) TokenOption {
 	}
 }
 
-// getThumbprint gets the thumbprint of the private key in order to generate the token headers.
-func getThumbprint(privateKey rsa.PrivateKey) (string, error) {
-	jwKey, err := jwk.New(privateKey)
+// errTokenExpired is returned by ParseToken when the token is verified but already expired.
+var errTokenExpired = errors.New("the token is expired")
+
+// curveAlgorithms maps the elliptic curves supported to the algorithms used to sign the tokens with them.
+var curveAlgorithms = map[elliptic.Curve]jwa.SignatureAlgorithm{
+	elliptic.P256(): jwa.ES256,
+	elliptic.P384(): jwa.ES384,
+	elliptic.P521(): jwa.ES512,
+}
+
+// signatureAlgorithm gets the algorithm used to sign and verify the tokens with the given public key, based on
+// its type. RSA keys use EncryptionAlgorithmDefault, EC keys the ECDSA algorithm of their curve, and Ed25519
+// keys EdDSA.
+func signatureAlgorithm(publicKey crypto.PublicKey) (jwa.SignatureAlgorithm, error) {
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		return EncryptionAlgorithmDefault, nil
+	case *ecdsa.PublicKey:
+		if algorithm, found := curveAlgorithms[pk.Curve]; found {
+			return algorithm, nil
+		}
+		return "", fmt.Errorf("the elliptic curve %s is not supported", pk.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwa.EdDSA, nil
+	}
+	return "", fmt.Errorf("the key type %T is not supported", publicKey)
+}
+
+// getThumbprint gets the thumbprint of the public key, used as the key ID of the tokens signed by its private key,
+// so the key published on the JWK set can be selected by verifiers.
+func getThumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwKey, err := jwk.New(publicKey)
 	if err != nil {
 		return "", err
 	}
@@ -138,8 +216,8 @@ func getThumbprint(privateKey rsa.PrivateKey) (string, error) {
 }
 
 // generateTokenHeaders generates the token headers based on the given private key.
-func generateTokenHeaders(privateKey rsa.PrivateKey) (jws.Headers, error) {
-	thumbprint, err := getThumbprint(privateKey)
+func generateTokenHeaders(privateKey crypto.Signer) (jws.Headers, error) {
+	thumbprint, err := getThumbprint(privateKey.Public())
 	if err != nil {
 		return nil, err
 	}
@@ -151,30 +229,113 @@ func generateTokenHeaders(privateKey rsa.PrivateKey) (jws.Headers, error) {
 	return headers, nil
 }
 
-// SignToken signs the given token using the given private key.
-func SignToken(token jwt.Token, privateKey rsa.PrivateKey) (string, error) {
+// NewKeySet creates the JWK set holding the public key of the given private key, identified by the same key ID
+// set on the headers of the tokens signed with it.
+func NewKeySet(privateKey crypto.Signer) (jwk.Set, error) {
+	algorithm, err := signatureAlgorithm(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := getThumbprint(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := jwk.New(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	if err = publicKey.Set(jwk.KeyIDKey, thumbprint); err != nil {
+		return nil, err
+	}
+	if err = publicKey.Set(jwk.AlgorithmKey, algorithm); err != nil {
+		return nil, err
+	}
+	if err = publicKey.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return nil, err
+	}
+	keySet := jwk.NewSet()
+	keySet.Add(publicKey)
+	return keySet, nil
+}
+
+// SignToken signs the given token using the given private key, with the algorithm suitable to its type.
+func SignToken(token jwt.Token, privateKey crypto.Signer) (string, error) {
+	algorithm, err := signatureAlgorithm(privateKey.Public())
+	if err != nil {
+		return "", err
+	}
 	headers, err := generateTokenHeaders(privateKey)
 	if err != nil {
 		return "", err
 	}
-	signedToken, err := jwt.Sign(token, EncryptionAlgorithmDefault, privateKey, jwt.WithHeaders(headers))
+	signedToken, err := jwt.Sign(token, algorithm, privateKey, jwt.WithHeaders(headers))
 	if err != nil {
 		return "", err
 	}
 	return string(signedToken), err
 }
 
+// checkKeyID checks if the given token was signed by the key identified by the thumbprint of the public key.
+func checkKeyID(token string, publicKey crypto.PublicKey) error {
+	message, err := jws.ParseString(token)
+	if err != nil {
+		return err
+	}
+	thumbprint, err := getThumbprint(publicKey)
+	if err != nil {
+		return err
+	}
+	for _, signature := range message.Signatures() {
+		if kid := signature.ProtectedHeaders().KeyID(); kid != thumbprint {
+			return fmt.Errorf("the token key ID %q does not match the public key", kid)
+		}
+	}
+	return nil
+}
+
 // ParseToken parses the token using the public key and returns the parsed token, otherwise an error.
-func ParseToken(token string, publicKey rsa.PublicKey) (jwt.Token, error) {
-	parsedToken, err := jwt.Parse([]byte(token), jwt.WithVerify(EncryptionAlgorithmDefault, publicKey))
+// The token is also validated, so it must have been signed with the algorithm suitable to the public key, by
+// the key identified by its thumbprint, and issued by the given issuer to at least one of the given audiences. Its
+// expiration and not before times are checked allowing the given leeway, to tolerate the clocks drift.
+func ParseToken(token string, publicKey crypto.PublicKey, issuer string, audiences []string, leeway time.Duration) (jwt.Token, error) {
+	algorithm, err := signatureAlgorithm(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkKeyID(token, publicKey); err != nil {
+		return nil, err
+	}
+	parsedToken, err := jwt.Parse([]byte(token), jwt.WithVerify(algorithm, publicKey))
 	if err != nil {
 		return nil, err
 	}
+	// the expiration is checked apart from the other claims, so the expired tokens can be told apart
+	if !parsedToken.Expiration().IsZero() && !time.Now().Before(parsedToken.Expiration().Add(leeway)) {
+		return nil, errTokenExpired
+	}
+	if err = jwt.Validate(parsedToken, jwt.WithIssuer(issuer), jwt.WithAcceptableSkew(leeway)); err != nil {
+		return nil, err
+	}
+	if !hasAudience(parsedToken, audiences) {
+		return nil, fmt.Errorf("the token audiences %q are not allowed", parsedToken.Audience())
+	}
 	return parsedToken, nil
 }
 
+// hasAudience checks if any of the token audiences is one of the given ones.
+func hasAudience(token jwt.Token, audiences []string) bool {
+	for _, tokenAudience := range token.Audience() {
+		for _, audience := range audiences {
+			if tokenAudience == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GenerateTokens generates Tokens for the given user.
-func GenerateTokens(ctx context.Context, privateKey rsa.PrivateKey, user User, opts ...TokenOption) (*Tokens, error) {
+func GenerateTokens(ctx context.Context, privateKey crypto.Signer, user User, opts ...TokenOption) (*Tokens, error) {
 	opts = append(opts, WithSubject(user.UUID.String()), WithRole(user.Role))
 	accessToken, err := NewJwtToken(GetDefaultAccessTokenOptions(opts...)...)
 	if err != nil {
@@ -199,7 +360,7 @@ func GenerateTokens(ctx context.Context, privateKey rsa.PrivateKey, user User, o
 }
 
 // MustGenerateTokens generates Tokens for the given user and if any error occurs, will panic.
-func MustGenerateTokens(ctx context.Context, privateKey rsa.PrivateKey, user User, opts ...TokenOption) *Tokens {
+func MustGenerateTokens(ctx context.Context, privateKey crypto.Signer, user User, opts ...TokenOption) *Tokens {
 	tokens, err := GenerateTokens(ctx, privateKey, user, opts...)
 	if err != nil {
 		panic(err)
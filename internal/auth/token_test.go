@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto"
+	"encoding/hex"
+	"hospital-booking/internal/configs"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+func TestSignAndParseToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		configPath    string
+		wantAlgorithm string
+	}{
+		{
+			name:          "should sign and parse the token with RS512 using a RSA key",
+			configPath:    "./../../test/testdata/config_valid.json",
+			wantAlgorithm: "RS512",
+		},
+		{
+			name:          "should sign and parse the token with ES256 using an EC key",
+			configPath:    "./../../test/testdata/config_ec_private_key.json",
+			wantAlgorithm: "ES256",
+		},
+		{
+			name:          "should sign and parse the token with EdDSA using an Ed25519 key",
+			configPath:    "./../../test/testdata/config_ed25519_private_key.json",
+			wantAlgorithm: "EdDSA",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := configs.MustLoad(tt.configPath)
+
+			token, err := NewJwtToken(GetDefaultAccessTokenOptions(WithSubject("subject"))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signedToken, err := SignToken(token, config.PrivateKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			message, err := jws.Parse([]byte(signedToken))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := message.Signatures()[0].ProtectedHeaders().Algorithm().String(); got != tt.wantAlgorithm {
+				t.Errorf("token algorithm is incorrect, got %s, want %s", got, tt.wantAlgorithm)
+			}
+			parsedToken, err := ParseToken(signedToken, config.PrivateKey().Public(), IssuerDefault, []string{AudienceDefault}, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parsedToken.Subject(); got != "subject" {
+				t.Errorf("token subject is incorrect, got %s, want %s", got, "subject")
+			}
+		})
+	}
+}
+
+func TestParseTokenWithAnotherKey(t *testing.T) {
+	rsaConfig := configs.MustLoad("./../../test/testdata/config_valid.json")
+	ecConfig := configs.MustLoad("./../../test/testdata/config_ec_private_key.json")
+
+	token, err := NewJwtToken(GetDefaultAccessTokenOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedToken, err := SignToken(token, ecConfig.PrivateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseToken(signedToken, rsaConfig.PrivateKey().Public(), IssuerDefault, []string{AudienceDefault}, 0); err == nil {
+		t.Error("the token signed with an EC key should not be verified with a RSA one")
+	}
+}
+
+func TestTokenKeyID(t *testing.T) {
+	tests := []struct {
+		name       string
+		configPath string
+	}{
+		{
+			name:       "should identify the RSA key by the thumbprint of its public key",
+			configPath: "./../../test/testdata/config_valid.json",
+		},
+		{
+			name:       "should identify the EC key by the thumbprint of its public key",
+			configPath: "./../../test/testdata/config_ec_private_key.json",
+		},
+		{
+			name:       "should identify the Ed25519 key by the thumbprint of its public key",
+			configPath: "./../../test/testdata/config_ed25519_private_key.json",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := configs.MustLoad(tt.configPath)
+
+			publicKey, err := jwk.New(config.PrivateKey().Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := NewJwtToken(GetDefaultAccessTokenOptions()...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signedToken, err := SignToken(token, config.PrivateKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			message, err := jws.Parse([]byte(signedToken))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := message.Signatures()[0].ProtectedHeaders().KeyID(), hex.EncodeToString(thumbprint); got != want {
+				t.Errorf("token key ID is incorrect, got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestParseTokenWithAnotherKeyID(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+
+	token, err := NewJwtToken(GetDefaultAccessTokenOptions()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := jws.NewHeaders()
+	if err = headers.Set(jws.KeyIDKey, "another_key"); err != nil {
+		t.Fatal(err)
+	}
+	signedToken, err := jwt.Sign(token, EncryptionAlgorithmDefault, config.PrivateKey(), jwt.WithHeaders(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseToken(string(signedToken), config.PrivateKey().Public(), IssuerDefault, []string{AudienceDefault}, 0); err == nil {
+		t.Error("the token identifying another key should not be parsed")
+	}
+}
+
+func TestParseTokenAudiences(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	allowedAudiences := []string{AudienceDefault, "hospital_booking_mobile", "hospital_booking_web"}
+	tests := []struct {
+		name      string
+		audiences []string
+		wantErr   bool
+	}{
+		{
+			name:      "should parse the token because its audience is allowed",
+			audiences: []string{"hospital_booking_mobile"},
+		},
+		{
+			name:      "should parse the token because one of its audiences is allowed",
+			audiences: []string{"another_app", "hospital_booking_web"},
+		},
+		{
+			name:      "should not parse the token because its audience is not allowed",
+			audiences: []string{"another_app"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			token, err := NewJwtToken(GetDefaultAccessTokenOptions(WithAudience(tt.audiences))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signedToken, err := SignToken(token, config.PrivateKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ParseToken(signedToken, config.PrivateKey().Public(), IssuerDefault, allowedAudiences, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
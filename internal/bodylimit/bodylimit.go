@@ -0,0 +1,20 @@
+// Package bodylimit contains the middleware used to limit the size of the request bodies.
+package bodylimit
+
+import (
+	"net/http"
+)
+
+// Middleware limits the request bodies to the given number of bytes, so a client can't stream an unbounded
+// body into the handlers. Reading past the limit fails, and the handlers decoding the body respond with
+// 413 - Request Entity Too Large.
+func Middleware(limit int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
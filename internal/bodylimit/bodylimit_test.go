@@ -0,0 +1,43 @@
+package bodylimit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{
+			name:    "should read a body within the limit",
+			body:    strings.Repeat("a", 10),
+			wantErr: false,
+		},
+		{
+			name:    "should not read a body over the limit",
+			body:    strings.Repeat("a", 11),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var readErr error
+			handler := Middleware(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, readErr = ioutil.ReadAll(r.Body)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if (readErr != nil) != tt.wantErr {
+				t.Errorf("read error = %v, wantErr %v", readErr, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package calendar
+
+import (
+	"hospital-booking/internal/configs"
+	"sync"
+	"time"
+)
+
+// calendarCacheTTL is how long a computed calendar is served from the cache. The cache is local to each instance,
+// so it is kept short to bound how long the writes made through other instances take to show up.
+const calendarCacheTTL = 30 * time.Second
+
+// CalendarCache caches the computed calendars of the doctors, by doctor and day.
+type CalendarCache interface {
+
+	// Get gets the cached calendar of the doctor on the given day, if it is still fresh, along with the version of
+	// the doctor's calendars to be given to Set once a missing calendar is computed.
+	Get(doctorID int64, date time.Time) ([]Entry, uint64, bool)
+
+	// Set caches the calendar of the doctor on the given day, unless the doctor's calendars were invalidated since
+	// the given version was read, so a calendar computed before a write is never cached after it.
+	Set(doctorID int64, date time.Time, version uint64, entries []Entry)
+
+	// Invalidate drops the cached calendar of the doctor on the given day.
+	Invalidate(doctorID int64, date time.Time)
+
+	// InvalidateDoctor drops all the cached calendars of the doctor.
+	InvalidateDoctor(doctorID int64)
+}
+
+// NewCalendarCache creates the CalendarCache kept in memory when enabled by the configuration, otherwise the
+// calendars are always computed. It can be shared with the other services that change the doctors.
+func NewCalendarCache(config configs.Config) CalendarCache {
+	if config.CalendarCacheEnabled() {
+		return newMemoryCalendarCache(calendarCacheTTL)
+	}
+	return noopCalendarCache{}
+}
+
+// noopCalendarCache is used when the cache is disabled, so the calendars are always computed.
+type noopCalendarCache struct{}
+
+func (n noopCalendarCache) Get(doctorID int64, date time.Time) ([]Entry, uint64, bool) {
+	return nil, 0, false
+}
+
+func (n noopCalendarCache) Set(doctorID int64, date time.Time, version uint64, entries []Entry) {}
+
+func (n noopCalendarCache) Invalidate(doctorID int64, date time.Time) {}
+
+func (n noopCalendarCache) InvalidateDoctor(doctorID int64) {}
+
+type calendarCacheKey struct {
+	doctorID int64
+	day      string
+}
+
+type calendarCacheItem struct {
+	entries   []Entry
+	expiresAt time.Time
+}
+
+type memoryCalendarCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	now      func() time.Time
+	items    map[calendarCacheKey]calendarCacheItem
+	versions map[int64]uint64
+}
+
+// newMemoryCalendarCache creates a CalendarCache that keeps the calendars in memory for the given TTL.
+func newMemoryCalendarCache(ttl time.Duration) CalendarCache {
+	return &memoryCalendarCache{
+		ttl:      ttl,
+		now:      time.Now,
+		items:    make(map[calendarCacheKey]calendarCacheItem),
+		versions: make(map[int64]uint64),
+	}
+}
+
+func newCalendarCacheKey(doctorID int64, date time.Time) calendarCacheKey {
+	return calendarCacheKey{doctorID: doctorID, day: date.Format(dateLayout)}
+}
+
+// copyEntries copies the given entries, so the cached ones can't be changed by the callers.
+func copyEntries(entries []Entry) []Entry {
+	copied := make([]Entry, len(entries))
+	copy(copied, entries)
+	return copied
+}
+
+func (m *memoryCalendarCache) Get(doctorID int64, date time.Time) ([]Entry, uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	version := m.versions[doctorID]
+	key := newCalendarCacheKey(doctorID, date)
+	item, found := m.items[key]
+	if !found {
+		return nil, version, false
+	}
+	if !m.now().Before(item.expiresAt) {
+		delete(m.items, key)
+		return nil, version, false
+	}
+	return copyEntries(item.entries), version, true
+}
+
+func (m *memoryCalendarCache) Set(doctorID int64, date time.Time, version uint64, entries []Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.versions[doctorID] != version {
+		return
+	}
+	now := m.now()
+	// drops the expired calendars, so the days no longer read don't pile up
+	for key, item := range m.items {
+		if !now.Before(item.expiresAt) {
+			delete(m.items, key)
+		}
+	}
+	m.items[newCalendarCacheKey(doctorID, date)] = calendarCacheItem{entries: copyEntries(entries), expiresAt: now.Add(m.ttl)}
+}
+
+func (m *memoryCalendarCache) Invalidate(doctorID int64, date time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[doctorID]++
+	delete(m.items, newCalendarCacheKey(doctorID, date))
+}
+
+func (m *memoryCalendarCache) InvalidateDoctor(doctorID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[doctorID]++
+	for key := range m.items {
+		if key.doctorID == doctorID {
+			delete(m.items, key)
+		}
+	}
+}
@@ -11,8 +11,52 @@ const (
 	ErrInvalidDayReference               = "invalid day reference - e.g. 10"
 	ErrOnlyDoctorCanCreateBlocker        = "only a doctor can create a blocker"
 	ErrOnlyPatientCanCreateAppointment   = "only a patient can create an appointment"
-	ErrSlotNotAvailable                  = "chosen slot is not available"
+	ErrSlotBlocked                       = "chosen slot is blocked by the doctor"
+	ErrSlotBooked                        = "chosen slot is already booked"
 	ErrOnlyDoctorCanCheckItsAppointments = "only a doctor can check its appointments"
+	ErrOnlyPatientCanCancelAppointment   = "only a patient can cancel an appointment"
+	ErrAppointmentNotFound               = "appointment not found"
+	ErrAppointmentAccessDenied           = "only the appointment's patient or doctor can check it"
+	ErrPatientNotFound                   = "patient not found"
+	ErrDoctorInactive                    = "doctor is no longer accepting appointments"
+	ErrConsentRequired                   = "patient must give consent before booking"
+	ErrOnlyDoctorCanUpdateStatus         = "only the appointment's doctor can update its status"
+	ErrInvalidStatusTransition           = "appointment can't move to the given status"
+	ErrAppointmentNotHappenedYet         = "appointment can't be marked as no-show before its time"
+	ErrOnlyDoctorCanCreateException      = "only a doctor can create an availability exception"
+	ErrOnlyDoctorCanCheckItsBlockers     = "only a doctor can check its blockers"
+	ErrOnlyPatientCanUpdateContact       = "only a patient can update its contact details"
+	ErrEmailAlreadyInUse                 = "email already in use by another patient"
+	ErrAppointmentVersionConflict        = "appointment was updated meanwhile, reload it and try again"
+)
+
+// Codes of the errors, which are stable so clients can rely on them.
+const (
+	CodeDoctorNotFound                    = "DOCTOR_NOT_FOUND"
+	CodeInvalidIdentifier                 = "INVALID_IDENTIFIER"
+	CodeInvalidDateReference              = "INVALID_DATE_REFERENCE"
+	CodeInvalidYearReference              = "INVALID_YEAR_REFERENCE"
+	CodeInvalidMonthReference             = "INVALID_MONTH_REFERENCE"
+	CodeInvalidDayReference               = "INVALID_DAY_REFERENCE"
+	CodeOnlyDoctorCanCreateBlocker        = "ONLY_DOCTOR_CAN_CREATE_BLOCKER"
+	CodeOnlyPatientCanCreateAppointment   = "ONLY_PATIENT_CAN_CREATE_APPOINTMENT"
+	CodeSlotBlocked                       = "SLOT_BLOCKED"
+	CodeSlotBooked                        = "SLOT_BOOKED"
+	CodeOnlyDoctorCanCheckItsAppointments = "ONLY_DOCTOR_CAN_CHECK_ITS_APPOINTMENTS"
+	CodeOnlyPatientCanCancelAppointment   = "ONLY_PATIENT_CAN_CANCEL_APPOINTMENT"
+	CodeAppointmentNotFound               = "APPOINTMENT_NOT_FOUND"
+	CodeAppointmentAccessDenied           = "APPOINTMENT_ACCESS_DENIED"
+	CodePatientNotFound                   = "PATIENT_NOT_FOUND"
+	CodeDoctorInactive                    = "DOCTOR_INACTIVE"
+	CodeConsentRequired                   = "CONSENT_REQUIRED"
+	CodeOnlyDoctorCanUpdateStatus         = "ONLY_DOCTOR_CAN_UPDATE_STATUS"
+	CodeInvalidStatusTransition           = "INVALID_STATUS_TRANSITION"
+	CodeAppointmentNotHappenedYet         = "APPOINTMENT_NOT_HAPPENED_YET"
+	CodeOnlyDoctorCanCreateException      = "ONLY_DOCTOR_CAN_CREATE_EXCEPTION"
+	CodeOnlyDoctorCanCheckItsBlockers     = "ONLY_DOCTOR_CAN_CHECK_ITS_BLOCKERS"
+	CodeOnlyPatientCanUpdateContact       = "ONLY_PATIENT_CAN_UPDATE_CONTACT"
+	CodeEmailAlreadyInUse                 = "EMAIL_ALREADY_IN_USE"
+	CodeAppointmentVersionConflict        = "APPOINTMENT_VERSION_CONFLICT"
 )
 
 func (e Error) Error() string {
@@ -1,59 +1,102 @@
 package calendar
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"hospital-booking/internal/apierrors"
 	"hospital-booking/internal/auth"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/database"
+	"hospital-booking/internal/jsonbody"
 	"hospital-booking/internal/logging"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/go-chi/chi/v5/middleware"
-
 	"github.com/google/uuid"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// IdempotencyKeyHeader is the header used by the clients to safely retry the appointment creation.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	ETagHeader        = "ETag"
+	IfNoneMatchHeader = "If-None-Match"
+)
+
 type httpHandler struct {
 	authorizer auth.Authorizer
 	service    Service
-	logger     *log.Logger
+	location   *time.Location
 }
 
-// Setup setups the routes handled by auth context.
-func Setup(router *chi.Mux, logger *log.Logger, authorizer auth.Authorizer, config configs.Config, dbConn database.Connection) {
-	handler := &httpHandler{logger: logger, authorizer: authorizer, service: NewService(config, dbConn)}
+// Setup setups the routes handled by calendar context, under the configured API base path. The given options are
+// used to create the calendar service.
+func Setup(router *chi.Mux, logger *log.Logger, authorizer auth.Authorizer, config configs.Config, dbConn database.Connection, opts ...ServiceOption) {
+	handler := &httpHandler{authorizer: authorizer, service: NewService(config, dbConn, opts...), location: config.Location()}
 
-	// protected routes, only for patients
-	router.Group(func(group chi.Router) {
-		group.Use(auth.JwtValidator(authorizer))
-		group.Use(auth.AllowedRole(authorizer, auth.PatientRole))
-		group.Get("/api/v1/calendar/{doctorUUID}/{year}/{month}/{day}", handler.GetDoctorCalendar)
-		group.Post("/api/v1/calendar/{doctorUUID}/{year}/{month}/{day}", handler.InsertAppointment)
-	})
+	router.Route(config.APIBasePath(), func(api chi.Router) {
+		// protected routes, only for patients
+		api.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(auth.JwtValidator(authorizer))
+			group.Use(auth.AllowedRole(authorizer, auth.PatientRole))
+			group.Get("/doctors", handler.ListDoctorsBySpecialty)
+			group.Get("/doctors/{doctorUUID}", handler.GetDoctor)
+			group.Get("/doctors/{doctorUUID}/availability", handler.GetDoctorAvailability)
+			group.Get("/calendar/{doctorUUID}/{year}/{month}/{day}", handler.GetDoctorCalendar)
+			group.Post("/calendar/{doctorUUID}/{year}/{month}/{day}", handler.InsertAppointment)
+			group.Delete("/calendar/appointments/{appointmentUUID}", handler.CancelAppointment)
+			group.Put("/patients/me", handler.UpdatePatientContact)
+		})
 
-	// protected routes, only for doctors
-	router.Group(func(group chi.Router) {
-		group.Use(auth.JwtValidator(authorizer))
-		group.Use(auth.AllowedRole(authorizer, auth.DoctorRole))
-		group.Get("/api/v1/calendar/{year}/{month}/{day}", handler.GetAppointments)
-		group.Post("/api/v1/calendar/blockers", handler.InsertBlockPeriod)
+		// protected routes, only for doctors
+		api.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(auth.JwtValidator(authorizer))
+			group.Use(auth.AllowedRole(authorizer, auth.DoctorRole))
+			group.Get("/calendar/{year}/{month}", handler.GetMonthAppointments)
+			group.Get("/calendar/{year}/{month}/{day}", handler.GetAppointments)
+			group.Get("/calendar/{year}/{month}/{day}.ics", handler.GetAppointmentsICalendar)
+			group.Get("/calendar/blockers", handler.ListBlockPeriods)
+			group.Post("/calendar/blockers", handler.InsertBlockPeriod)
+			group.Post("/calendar/blockers/recurring", handler.InsertRecurringBlockPeriod)
+			group.Post("/calendar/exceptions", handler.InsertAvailabilityException)
+			group.Get("/patients/{patientUUID}/appointments", handler.GetPatientAppointments)
+			group.Put("/calendar/appointments/{appointmentUUID}/status", handler.UpdateAppointmentStatus)
+		})
+
+		// protected routes, only for admins
+		api.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(auth.JwtValidator(authorizer))
+			group.Use(auth.AllowedRole(authorizer, auth.AdminRole))
+			group.Get("/doctors/all", handler.ListDoctors)
+			group.Get("/doctors/search", handler.FindDoctorByEmail)
+		})
+
+		// protected routes, for the appointment's patient or doctor
+		api.Group(func(group chi.Router) {
+			group.Use(logging.Middleware(logger))
+			group.Use(auth.JwtValidator(authorizer))
+			group.Get("/appointments/{appointmentUUID}", handler.GetAppointment)
+		})
 	})
 }
 
 func (h httpHandler) writeResponseError(w http.ResponseWriter, r *http.Request, err error) {
-	logging.PrintlnError(h.logger, fmt.Sprint(r.Context().Value(middleware.RequestIDKey), " ", err))
+	logging.FromContext(r.Context()).Error(err)
 	switch errType := err.(type) {
 	case *auth.UnauthorizedError:
 		w.WriteHeader(http.StatusUnauthorized)
 		return
-	case *apierrors.ValidationError:
+	case *apierrors.ValidationError, apierrors.ValidationErrors:
 		w.WriteHeader(http.StatusBadRequest)
 		_ = json.NewEncoder(w).Encode(err)
 		return
@@ -65,49 +108,148 @@ func (h httpHandler) writeResponseError(w http.ResponseWriter, r *http.Request,
 	w.WriteHeader(http.StatusInternalServerError)
 }
 
-// parseDate parses the given parameters into a valid time.
+// etagMatches checks if the given If-None-Match header value matches the given ETag, ignoring the weak prefix.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCacheableResponse writes the given value along with an ETag computed from its content, so clients polling
+// it can send the ETag back through If-None-Match and get a 304 status with no body while it is unchanged.
+func (h httpHandler) writeCacheableResponse(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	body = append(body, '\n')
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf("%q", hex.EncodeToString(sum[:16]))
+	w.Header().Set(ETagHeader, etag)
+	if etagMatches(r.Header.Get(IfNoneMatchHeader), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+// parseDate parses the given parameters into a valid time, in the clinic timezone.
 func (h httpHandler) parseDateParameters(r *http.Request) (time.Time, error) {
 	var zeroTime time.Time
 	year := chi.URLParam(r, "year")
 	month := chi.URLParam(r, "month")
 	day := chi.URLParam(r, "day")
 	if year == "" || month == "" || day == "" {
-		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDateReference), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDateReference), apierrors.WithCode(CodeInvalidDateReference), apierrors.WithHTTPStatusCode(http.StatusNotFound))
 	}
-	yearInt, err := strconv.Atoi(year)
-	if len(year) != 4 || err != nil {
-		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidYearReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
-	}
-	monthInt, err := strconv.Atoi(month)
-	if len(month) > 2 || err != nil {
-		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidMonthReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	yearInt, monthInt, err := parseYearMonth(year, month)
+	if err != nil {
+		return zeroTime, err
 	}
 	dayInt, err := strconv.Atoi(day)
 	if len(day) > 2 || err != nil {
-		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDayReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDayReference), apierrors.WithCode(CodeInvalidDayReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
 	}
 	concatDate := fmt.Sprintf("%4d-%02d-%02d", yearInt, monthInt, dayInt)
-	date, err := time.Parse("2006-01-02", concatDate)
+	date, err := time.ParseInLocation("2006-01-02", concatDate, h.location)
 	if err != nil {
-		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDateReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+		return zeroTime, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDateReference), apierrors.WithCode(CodeInvalidDateReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
 	}
 	return date, nil
 }
 
+// parseMonthParameters parses the year and month parameters of the request.
+func (h httpHandler) parseMonthParameters(r *http.Request) (int, time.Month, error) {
+	year := chi.URLParam(r, "year")
+	month := chi.URLParam(r, "month")
+	if year == "" || month == "" {
+		return 0, 0, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidDateReference), apierrors.WithCode(CodeInvalidDateReference), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	yearInt, monthInt, err := parseYearMonth(year, month)
+	if err != nil {
+		return 0, 0, err
+	}
+	return yearInt, time.Month(monthInt), nil
+}
+
+// parseYearMonth parses the given year, e.g. 2021, and month, from 1 to 12.
+func parseYearMonth(year string, month string) (int, int, error) {
+	yearInt, err := strconv.Atoi(year)
+	if len(year) != 4 || err != nil {
+		return 0, 0, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidYearReference), apierrors.WithCode(CodeInvalidYearReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	}
+	monthInt, err := strconv.Atoi(month)
+	if len(month) > 2 || err != nil || monthInt < 1 || monthInt > 12 {
+		return 0, 0, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidMonthReference), apierrors.WithCode(CodeInvalidMonthReference), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	}
+	return yearInt, monthInt, nil
+}
+
 // parseUUIDParameter parses a UUID parameter into a valid UUID.
 func (h httpHandler) parseUUIDParameter(parName string, r *http.Request) (uuid.UUID, error) {
 	zeroUUID := uuid.UUID{}
 	uuidPar := chi.URLParam(r, parName)
 	if uuidPar == "" {
-		return zeroUUID, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidIdentifier), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+		return zeroUUID, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidIdentifier), apierrors.WithCode(CodeInvalidIdentifier), apierrors.WithHTTPStatusCode(http.StatusNotFound))
 	}
 	parsedUUID, err := uuid.Parse(uuidPar)
 	if err != nil {
-		return zeroUUID, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidIdentifier), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+		return zeroUUID, apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidIdentifier), apierrors.WithCode(CodeInvalidIdentifier), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
 	}
 	return parsedUUID, nil
 }
 
+func (h httpHandler) ListDoctorsBySpecialty(w http.ResponseWriter, r *http.Request) {
+	doctors, err := h.service.ListDoctorsBySpecialty(r.Context(), r.URL.Query().Get("specialty"))
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(doctors)
+}
+
+func (h httpHandler) ListDoctors(w http.ResponseWriter, r *http.Request) {
+	pagination, err := ParsePagination(r.URL.Query().Get("page"), r.URL.Query().Get("size"))
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	page, err := h.service.ListDoctors(r.Context(), pagination)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func (h httpHandler) FindDoctorByEmail(w http.ResponseWriter, r *http.Request) {
+	doctor, err := h.service.FindDoctorByEmail(r.Context(), r.URL.Query().Get("email"))
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(doctor)
+}
+
+func (h httpHandler) GetDoctor(w http.ResponseWriter, r *http.Request) {
+	doctorUUID, err := h.parseUUIDParameter("doctorUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	doctor, err := h.service.GetDoctor(r.Context(), doctorUUID)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(doctor.Summary())
+}
+
 func (h httpHandler) GetDoctorCalendar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date, err := h.parseDateParameters(r)
@@ -118,6 +260,7 @@ func (h httpHandler) GetDoctorCalendar(w http.ResponseWriter, r *http.Request) {
 	doctorUUID, err := h.parseUUIDParameter("doctorUUID", r)
 	if err != nil {
 		h.writeResponseError(w, r, err)
+		return
 	}
 	user, err := h.authorizer.GetAuthenticatedUser(ctx)
 	if err != nil {
@@ -129,7 +272,41 @@ func (h httpHandler) GetDoctorCalendar(w http.ResponseWriter, r *http.Request) {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	_ = json.NewEncoder(w).Encode(entries)
+	if r.URL.Query().Get("include") != "doctor" {
+		h.writeCacheableResponse(w, r, entries)
+		return
+	}
+	doctor, err := h.service.GetDoctor(ctx, doctorUUID)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	h.writeCacheableResponse(w, r, DoctorCalendar{Doctor: doctor.Summary(), Entries: entries})
+}
+
+func (h httpHandler) GetDoctorAvailability(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	doctorUUID, err := h.parseUUIDParameter("doctorUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	dateRange, err := ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), h.location)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	days, err := h.service.GetDoctorAvailability(ctx, user, doctorUUID, dateRange)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	h.writeCacheableResponse(w, r, days)
 }
 
 func (h httpHandler) InsertAppointment(w http.ResponseWriter, r *http.Request) {
@@ -150,12 +327,13 @@ func (h httpHandler) InsertAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	appointmentRequest := &AppointmentRequest{}
-	if err = json.NewDecoder(r.Body).Decode(appointmentRequest); err != nil {
+	if err = jsonbody.Decode(r, appointmentRequest); err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
 	appointmentRequest.DoctorUUID = doctorUUID
 	appointmentRequest.Date = date
+	appointmentRequest.IdempotencyKey = r.Header.Get(IdempotencyKeyHeader)
 	err = h.service.InsertAppointment(ctx, user, *appointmentRequest)
 	if err != nil {
 		h.writeResponseError(w, r, err)
@@ -164,6 +342,114 @@ func (h httpHandler) InsertAppointment(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (h httpHandler) CancelAppointment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appointmentUUID, err := h.parseUUIDParameter("appointmentUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.CancelAppointment(ctx, user, appointmentUUID); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h httpHandler) UpdateAppointmentStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appointmentUUID, err := h.parseUUIDParameter("appointmentUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	request := &AppointmentStatusRequest{}
+	if err = jsonbody.Decode(r, request); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.UpdateAppointmentStatus(ctx, user, appointmentUUID, *request); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h httpHandler) UpdatePatientContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	request := &PatientContactRequest{}
+	if err = jsonbody.Decode(r, request); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	patient, err := h.service.UpdatePatientContact(ctx, user, *request)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(patient)
+}
+
+func (h httpHandler) GetAppointment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appointmentUUID, err := h.parseUUIDParameter("appointmentUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	appointment, err := h.service.GetAppointment(ctx, user, appointmentUUID)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(appointment)
+}
+
+func (h httpHandler) GetPatientAppointments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	patientUUID, err := h.parseUUIDParameter("patientUUID", r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	status, err := ParseAppointmentStatusFilter(r.URL.Query().Get("status"))
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	appointments, err := h.service.GetPatientAppointments(ctx, user, patientUUID, status)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(appointments)
+}
+
 func (h httpHandler) GetAppointments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date, err := h.parseDateParameters(r)
@@ -176,12 +462,78 @@ func (h httpHandler) GetAppointments(w http.ResponseWriter, r *http.Request) {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	entries, err := h.service.GetAppointments(ctx, user, date)
+	filter, err := ParseEntryFilter(r.URL.Query().Get("only"))
 	if err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	_ = json.NewEncoder(w).Encode(entries)
+	entries, err := h.service.GetAppointments(ctx, user, date, filter)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	h.writeCacheableResponse(w, r, entries)
+}
+
+func (h httpHandler) GetMonthAppointments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	year, month, err := h.parseMonthParameters(r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	days, err := h.service.GetMonthAppointments(ctx, user, year, month)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(days)
+}
+
+func (h httpHandler) GetAppointmentsICalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	date, err := h.parseDateParameters(r)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	entries, err := h.service.GetAppointments(ctx, user, date, BookedEntries)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", ICalendarContentType)
+	_ = WriteICalendar(w, date, entries, time.Now())
+}
+
+func (h httpHandler) ListBlockPeriods(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dateRange, err := ParseDateRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), h.location)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	blockers, err := h.service.ListBlockers(ctx, user, dateRange)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(blockers)
 }
 
 func (h httpHandler) InsertBlockPeriod(w http.ResponseWriter, r *http.Request) {
@@ -192,14 +544,55 @@ func (h httpHandler) InsertBlockPeriod(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	blockPeriod := &BlockPeriod{}
-	if err = json.NewDecoder(r.Body).Decode(blockPeriod); err != nil {
+	if err = jsonbody.Decode(r, blockPeriod); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	result, err := h.service.InsertBlocker(ctx, user, *blockPeriod)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func (h httpHandler) InsertRecurringBlockPeriod(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	request := &RecurringBlockPeriodRequest{}
+	if err = jsonbody.Decode(r, request); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	if err = h.service.InsertRecurringBlockers(ctx, user, *request); err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h httpHandler) InsertAvailabilityException(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := h.authorizer.GetAuthenticatedUser(ctx)
+	if err != nil {
+		h.writeResponseError(w, r, err)
+		return
+	}
+	exception := &AvailabilityException{}
+	if err = jsonbody.Decode(r, exception); err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
-	err = h.service.InsertBlocker(ctx, user, *blockPeriod)
+	created, err := h.service.InsertAvailabilityException(ctx, user, *exception)
 	if err != nil {
 		h.writeResponseError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
 }
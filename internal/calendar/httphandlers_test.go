@@ -7,19 +7,24 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"hospital-booking/internal/apierrors"
 	"hospital-booking/internal/auth"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/mock"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type emptyWriter struct{}
@@ -30,6 +35,16 @@ func (e emptyWriter) Write(p []byte) (n int, err error) {
 
 var logger = log.New(&emptyWriter{}, "", log.LstdFlags)
 
+type stubNotifier struct {
+	calls int
+	err   error
+}
+
+func (s *stubNotifier) AppointmentCreated(ctx context.Context, appointment Appointment) error {
+	s.calls++
+	return s.err
+}
+
 type mockAuthorizer struct {
 	mockValidateToken        func(ctx context.Context, token string) (*auth.User, error)
 	mockRefreshTokens        func(ctx context.Context, tokens auth.Tokens) (*auth.Tokens, error)
@@ -72,6 +87,48 @@ func withFindDoctorByUserIDError() mock.DBResultOption {
 	}
 }
 
+func withFindDoctorByEmailResult(email string, rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorByEmailQuery)).WithArgs(email).WillReturnRows(rows)
+	}
+}
+
+func withListDoctorsBySpecialtyResult(specialty string, rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listDoctorsBySpecialtyQuery)).WithArgs(specialty, maxListedDoctors).WillReturnRows(rows)
+	}
+}
+
+func withListDoctorsBySpecialtyError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listDoctorsBySpecialtyQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withListDoctorsPagedResult(limit, offset int, rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listDoctorsPagedQuery)).WithArgs(limit, offset).WillReturnRows(rows)
+	}
+}
+
+func withListDoctorsPagedError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listDoctorsPagedQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withCountDoctorsResult(count int64) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(countDoctorsQuery)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	}
+}
+
+func withCountDoctorsError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(countDoctorsQuery)).WillReturnError(sql.ErrConnDone)
+	}
+}
+
 func withFindPatientByIDResult(rows *sqlmock.Rows) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
 		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientByIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
@@ -84,6 +141,18 @@ func withFindPatientByIDError() mock.DBResultOption {
 	}
 }
 
+func withFindPatientsByIDsResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientsByIDsQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withFindPatientsByIDsError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientsByIDsQuery)).WithArgs(sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
 func withFindPatientByUserIDResult(rows *sqlmock.Rows) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
 		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientByUserIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
@@ -96,39 +165,73 @@ func withFindPatientByUserIDError() mock.DBResultOption {
 	}
 }
 
+func withUpdatePatientResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updatePatientQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
+	}
+}
+
 func withInsertBlockerResult(result driver.Result) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertBlockerQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertBlockerQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
 	}
 }
 
 func withInsertBlockerError() mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertBlockerQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertBlockerQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withInsertBlockersBatchResult(count int, result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		args := make([]driver.Value, count*6)
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(buildInsertBlockersBatchQuery(count))).WithArgs(args...).WillReturnResult(result)
+	}
+}
+
+func withInsertBlockersBatchError(count int) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(buildInsertBlockersBatchQuery(count))).WillReturnError(sql.ErrConnDone)
 	}
 }
 
 func withListBlockersResult(rows *sqlmock.Rows) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
 	}
 }
 
 func withListBlockersError() mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withListExceptionsResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listExceptionsQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withInsertExceptionResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertExceptionQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
 	}
 }
 
 func withInsertAppointmentResult(result driver.Result) mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
 	}
 }
 
 func withInsertAppointmentError() mock.DBResultOption {
 	return func(dbConn mock.Connection) {
-		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
 	}
 }
 
@@ -144,6 +247,114 @@ func withListAppointmentsError() mock.DBResultOption {
 	}
 }
 
+func withListAppointmentsInRangeResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listAppointmentsInRangeQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withListAppointmentsInRangeError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listAppointmentsInRangeQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withListAppointmentsByPatientAndDateResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listPatientAppointmentsQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withListAppointmentsByPatientAndDateError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listPatientAppointmentsQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withFindPatientByUUIDResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findPatientByUUIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withListPatientHistoryResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listPatientHistoryQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withListPatientHistoryError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listPatientHistoryQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withFindAppointmentByUUIDResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findAppointmentByUUIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withFindAppointmentByUUIDError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findAppointmentByUUIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withFindDoctorByIDResult(rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorByIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+	}
+}
+
+func withFindDoctorByIDError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorByIDQuery)).WithArgs(sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withCancelAppointmentResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(cancelAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
+	}
+}
+
+func withCancelAppointmentError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(cancelAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+	}
+}
+
+func withUpdateAppointmentStatusResult(result driver.Result) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updateAppointmentStatusQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(result)
+	}
+}
+
+func withFindIdempotencyKeyResult(key string, rows *sqlmock.Rows) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findIdempotencyKeyQuery)).WithArgs(sqlmock.AnyArg(), key).WillReturnRows(rows)
+	}
+}
+
+func withInsertAppointmentWithIdempotencyKeyResult(key string) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectBegin()
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertIdempotencyKeyQuery)).WithArgs(key, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		dbConn.SQLMock.ExpectCommit()
+	}
+}
+
+func withInsertAppointmentWithIdempotencyKeyConflict(key string) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectBegin()
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertIdempotencyKeyQuery)).WithArgs(key, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(&pq.Error{Code: "23505"})
+		dbConn.SQLMock.ExpectRollback()
+	}
+}
+
 func mockPatientUser() *auth.User {
 	return &auth.User{
 		ID:    1,
@@ -162,6 +373,15 @@ func mockDoctorUser() *auth.User {
 	}
 }
 
+func mockAdminUser() *auth.User {
+	return &auth.User{
+		ID:    1,
+		UUID:  uuid.New(),
+		Email: "admin@hospital.com",
+		Role:  auth.AdminRole,
+	}
+}
+
 func TestGetDoctorCalendar(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
 	type args struct {
@@ -195,7 +415,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
 				},
@@ -221,7 +441,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
 					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
 				},
@@ -289,7 +509,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"})),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
 				},
 				doctorUUID: &uuid.UUID{},
 				year:       "2021",
@@ -337,7 +557,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 				},
 				doctorUUID: &uuid.UUID{},
 				year:       "2021",
@@ -361,7 +581,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsError(),
 				},
 				doctorUUID: &uuid.UUID{},
@@ -386,7 +606,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, false, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 				},
 				doctorUUID: &uuid.UUID{},
@@ -411,7 +631,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 					withListBlockersError(),
 				},
@@ -437,7 +657,7 @@ func TestGetDoctorCalendar(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, false, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
 				},
@@ -479,110 +699,584 @@ func TestGetDoctorCalendar(t *testing.T) {
 	}
 }
 
-func TestGetAppointments(t *testing.T) {
+func TestGetDoctorCalendarIncludeDoctor(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
-	type args struct {
-		config        configs.Config
-		mockAuth      mockAuthorizer
-		dbConn        mock.Connection
-		dbMockOptions []mock.DBResultOption
-		tokens        *auth.Tokens
-		doctorUUID    *uuid.UUID
-		year          string
-		month         string
-		day           string
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "Cardiology", true)
 	}
 	tests := []struct {
-		name string
-		args args
-		want int
+		name          string
+		query         string
+		dbMockOptions []mock.DBResultOption
+		wantDoctor    bool
 	}{
 		{
-			name: "should get the calendar with appointments and blockers",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				mockAuth: mockAuthorizer{
-					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
-						return mockDoctorUser(), nil
-					},
-					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
-						return *mockDoctorUser(), nil
-					},
-				},
-				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
-				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
-					withFindPatientByIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "")),
-				},
-				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+			name:  "should embed the doctor when requested",
+			query: "?include=doctor",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withFindDoctorByUUIDResult(doctorRows()),
 			},
-			want: http.StatusOK,
+			wantDoctor: true,
 		},
 		{
-			name: "should get the calendar with no appointments and no blockers",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				mockAuth: mockAuthorizer{
-					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
-						return mockDoctorUser(), nil
-					},
-					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
-						return *mockDoctorUser(), nil
-					},
+			name:  "should not embed the doctor when not requested",
+			query: "",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+			},
+			wantDoctor: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
 				},
-				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
-				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
 				},
-				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s/2021/08/10%s", uuid.UUID{}, tt.query), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			if !tt.wantDoctor {
+				var entries []Entry
+				if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+					t.Errorf("response body should be a bare array of entries, got error %v", err)
+				}
+				return
+			}
+			var calendar DoctorCalendar
+			if err := json.NewDecoder(recorder.Body).Decode(&calendar); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			want := DoctorSummary{UUID: uuid.UUID{}, Name: "John Doe", Specialty: "Cardiology"}
+			if calendar.Doctor != want {
+				t.Errorf("embedded doctor is incorrect, got %+v, want %+v", calendar.Doctor, want)
+			}
+		})
+	}
+}
+
+func TestGetDoctorCalendarDayOff(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_working_days.json")
+	// 2021-08-14 is a Saturday, which is off for the configured working days
+	saturday := time.Date(2021, 8, 14, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		want          []int32
+	}{
+		{
+			name: "should have no slots on a day off",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withListExceptionsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
 			},
-			want: http.StatusOK,
+			want: []int32{},
 		},
 		{
-			name: "should not get the calendar because the date parameters are wrong",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				mockAuth: mockAuthorizer{
-					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
-						return mockDoctorUser(), nil
-					},
-					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
-						return *mockDoctorUser(), nil
-					},
-				},
-				tokens:     auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
-				doctorUUID: &uuid.UUID{},
-				year:       "AAAA",
-				month:      "08",
-				day:        "10",
+			name: "should have the slots added by an exception on a day off, except the blocked ones",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, saturday.Add(11*time.Hour))),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, saturday.Add(10*time.Hour), saturday.Add(11*time.Hour), "")),
+				withListExceptionsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, saturday.Add(9*time.Hour), saturday.Add(13*time.Hour), "extra Saturday")),
 			},
-			want: http.StatusBadRequest,
+			want: []int32{9, 12},
 		},
-		{
-			name: "should not get the calendar because no doctor associated with the user was found",
-			args: args{
-				config: config,
-				dbConn: mock.MustCreateConnectionMock(),
-				mockAuth: mockAuthorizer{
-					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
-						return mockDoctorUser(), nil
-					},
-					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
-						return *mockDoctorUser(), nil
-					},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s/2021/08/14", uuid.UUID{}), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			got := make([]int32, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, entry.Hour)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("available hours are incorrect, got %v, want %v", got, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInsertAvailabilityException(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_working_days.json")
+	tests := []struct {
+		name          string
+		exception     AvailabilityException
+		dbMockOptions []mock.DBResultOption
+		want          int
+	}{
+		{
+			name: "should insert an availability exception on a day off",
+			exception: AvailabilityException{
+				StartDate: time.Date(2021, 8, 14, 9, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2021, 8, 14, 13, 0, 0, 0, time.UTC),
+			},
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withInsertExceptionResult(sqlmock.NewResult(1, 1)),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should not insert an availability exception on a working day",
+			exception: AvailabilityException{
+				StartDate: time.Date(2021, 8, 10, 9, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2021, 8, 10, 13, 0, 0, 0, time.UTC),
+			},
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert an availability exception spanning several days",
+			exception: AvailabilityException{
+				StartDate: time.Date(2021, 8, 14, 9, 0, 0, 0, time.UTC),
+				EndDate:   time.Date(2021, 8, 15, 13, 0, 0, 0, time.UTC),
+			},
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+			},
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			body, _ := json.Marshal(tt.exception)
+			req, _ := http.NewRequest("POST", "/api/v1/calendar/exceptions", bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetDoctorCalendarTimezone(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_timezone.json")
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockPatientUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockPatientUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	// the dates are stored without timezone, so they are read as UTC and must be taken as the clinic wall clock
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.UTC))),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.UTC), time.Date(2021, 8, 10, 16, 0, 0, 0, time.UTC), "")),
+	)
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s/2021/08/10", uuid.UUID{}), nil)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var entries []Entry
+	if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+		t.Fatalf("response body is incorrect, got error %v", err)
+	}
+	got := make([]int32, 0, len(entries))
+	for _, entry := range entries {
+		got = append(got, entry.Hour)
+	}
+	want := []int32{9, 11, 12, 13, 14, 16, 17}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("available hours are incorrect, got %v, want %v", got, want)
+	}
+}
+
+func TestGetDoctorCalendarBlockerBoundaries(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	day := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	allHours := []int32{9, 10, 11, 12, 13, 14, 15, 16, 17}
+	tests := []struct {
+		name     string
+		blockers *sqlmock.Rows
+		want     []int32
+	}{
+		{
+			name:     "should keep the slots available when a blocker ends at the midnight of the day",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.Add(-4*time.Hour), day, ""),
+			want:     allHours,
+		},
+		{
+			name:     "should keep the slots available when a blocker starts at the midnight of the next day",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.AddDate(0, 0, 1), day.AddDate(0, 0, 1).Add(4*time.Hour), ""),
+			want:     allHours,
+		},
+		{
+			name:     "should block the slots of a blocker spanning the whole day",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day, day.AddDate(0, 0, 1), ""),
+			want:     []int32{},
+		},
+		{
+			name:     "should block the slots of a blocker until the hour it ends",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.Add(-4*time.Hour), day.Add(11*time.Hour), ""),
+			want:     []int32{11, 12, 13, 14, 15, 16, 17},
+		},
+		{
+			name:     "should keep the slot at the end of a blocker available",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.Add(15*time.Hour), day.Add(16*time.Hour), ""),
+			want:     []int32{9, 10, 11, 12, 13, 14, 16, 17},
+		},
+		{
+			name:     "should free the slots once the blocker is gone",
+			blockers: sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}),
+			want:     allHours,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				// the blockers overlapping the day are the ones starting before its end and ending after its start
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(1, day, day.AddDate(0, 0, 1)).WillReturnRows(tt.blockers)
+				},
+			)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s/2021/08/10", uuid.UUID{}), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			got := make([]int32, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, entry.Hour)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("available hours are incorrect, got %v, want %v", got, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCalendarETag(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	tests := []struct {
+		name          string
+		user          *auth.User
+		path          string
+		dbMockOptions func() []mock.DBResultOption
+	}{
+		{
+			name: "should cache the doctor's calendar",
+			user: mockPatientUser(),
+			path: fmt.Sprintf("/api/v1/calendar/%s/2021/08/10", uuid.UUID{}),
+			dbMockOptions: func() []mock.DBResultOption {
+				return []mock.DBResultOption{
+					withFindDoctorByUUIDResult(doctorRows()),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				}
+			},
+		},
+		{
+			name: "should cache the doctor's appointments",
+			user: mockDoctorUser(),
+			path: "/api/v1/calendar/2021/08/10",
+			dbMockOptions: func() []mock.DBResultOption {
+				return []mock.DBResultOption{
+					withFindDoctorByUserIDResult(doctorRows()),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			request := func(ifNoneMatch string) *httptest.ResponseRecorder {
+				mock.MockDBResults(dbConn, tt.dbMockOptions()...)
+				req, _ := http.NewRequest("GET", tt.path, nil)
+				req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+				if ifNoneMatch != "" {
+					req.Header.Add(IfNoneMatchHeader, ifNoneMatch)
+				}
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, req)
+				return recorder
+			}
+
+			first := request("")
+			etag := first.Header().Get(ETagHeader)
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("first response should be 200 with an ETag, got %d and %q", first.Code, etag)
+			}
+
+			notModified := request(etag)
+			if notModified.Code != http.StatusNotModified {
+				t.Errorf("response status is incorrect, got %d, want %d", notModified.Code, http.StatusNotModified)
+			}
+			if notModified.Body.Len() != 0 {
+				t.Errorf("response body should be empty, got %s", notModified.Body.String())
+			}
+
+			modified := request(`"stale"`)
+			if modified.Code != http.StatusOK || modified.Body.String() != first.Body.String() {
+				t.Errorf("response should be 200 with the same body, got %d and %s", modified.Code, modified.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetAppointments(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	type args struct {
+		config        configs.Config
+		mockAuth      mockAuthorizer
+		dbConn        mock.Connection
+		dbMockOptions []mock.DBResultOption
+		tokens        *auth.Tokens
+		doctorUUID    *uuid.UUID
+		year          string
+		month         string
+		day           string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "should get the calendar with appointments and blockers",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockDoctorUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockDoctorUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+					withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", true)),
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       "2021",
+				month:      "08",
+				day:        "10",
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "should get the calendar with no appointments and no blockers",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockDoctorUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockDoctorUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       "2021",
+				month:      "08",
+				day:        "10",
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "should not get the calendar because the date parameters are wrong",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockDoctorUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockDoctorUser(), nil
+					},
+				},
+				tokens:     auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
+				doctorUUID: &uuid.UUID{},
+				year:       "AAAA",
+				month:      "08",
+				day:        "10",
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not get the calendar because no doctor associated with the user was found",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockDoctorUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockDoctorUser(), nil
+					},
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
 				dbMockOptions: []mock.DBResultOption{
@@ -764,7 +1458,7 @@ func TestGetAppointments(t *testing.T) {
 					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
-					withFindPatientByIDError(),
+					withFindPatientsByIDsError(),
 				},
 				doctorUUID: &uuid.UUID{},
 				year:       "2021",
@@ -791,7 +1485,7 @@ func TestGetAppointments(t *testing.T) {
 					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
 					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
 					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
-					withFindPatientByIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, false, 1, "John Doe", "doctor@hospital.com", "")),
+					withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, false, 1, "John Doe", "doctor@hospital.com", "", true)),
 				},
 				doctorUUID: &uuid.UUID{},
 				year:       "2021",
@@ -831,6 +1525,211 @@ func TestGetAppointments(t *testing.T) {
 	}
 }
 
+func TestGetAppointmentsLoadsPatientsOnce(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockDoctorUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockDoctorUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	// any other patient query would not be expected, failing the request
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).
+			AddRow(1, uuid.New(), 1, 1, time.Date(2021, 8, 10, 9, 0, 0, 0, time.Local)).
+			AddRow(2, uuid.New(), 1, 2, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local)).
+			AddRow(3, uuid.New(), 1, 1, time.Date(2021, 8, 10, 11, 0, 0, 0, time.Local))),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).
+			AddRow(1, uuid.New(), 10, "First Patient", "first@hospital.com", "", true).
+			AddRow(2, uuid.New(), 20, "Second Patient", "second@hospital.com", "", true)),
+	)
+
+	req, _ := http.NewRequest("GET", "/api/v1/calendar/2021/08/10?only=booked", nil)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var entries []Entry
+	if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+		t.Fatalf("response body is incorrect, got error %v", err)
+	}
+	got := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Patient == nil {
+			t.Fatalf("booked entry at %d should have its patient", entry.Hour)
+		}
+		got = append(got, entry.Patient.Name)
+	}
+	want := []string{"First Patient", "Second Patient", "First Patient"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries patients are incorrect, got %v, want %v", got, want)
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the patients should be loaded in a single query: %v", err)
+	}
+}
+
+func TestGetAppointmentsFilter(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tests := []struct {
+		name      string
+		only      string
+		want      int
+		wantHours []int32
+	}{
+		{
+			name:      "should get all the entries by default",
+			only:      "",
+			want:      http.StatusOK,
+			wantHours: []int32{9, 10, 11, 12, 13, 14, 15, 16, 17},
+		},
+		{
+			name:      "should get all the entries",
+			only:      "all",
+			want:      http.StatusOK,
+			wantHours: []int32{9, 10, 11, 12, 13, 14, 15, 16, 17},
+		},
+		{
+			name:      "should get only the available entries",
+			only:      "available",
+			want:      http.StatusOK,
+			wantHours: []int32{9, 11, 12, 13, 14, 16, 17},
+		},
+		{
+			name:      "should get only the booked entries",
+			only:      "booked",
+			want:      http.StatusOK,
+			wantHours: []int32{10},
+		},
+		{
+			name: "should not get the entries because the filter is invalid",
+			only: "blocked",
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+				withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "patient@hospital.com", "", true)),
+			)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/2021/08/10?only=%s", tt.only), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			hours := make([]int32, 0, len(entries))
+			for _, entry := range entries {
+				hours = append(hours, entry.Hour)
+			}
+			if fmt.Sprint(hours) != fmt.Sprint(tt.wantHours) {
+				t.Errorf("entries are incorrect, got hours %v, want %v", hours, tt.wantHours)
+			}
+		})
+	}
+}
+
+func TestGetAppointmentsICalendar(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockDoctorUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockDoctorUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).
+			AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local)).
+			AddRow(2, uuid.UUID{}, 1, 2, time.Date(2021, 8, 10, 14, 0, 0, 0, time.Local))),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).
+			AddRow(1, uuid.New(), 1, "Jane Roe", "jane@hospital.com", "", true).
+			AddRow(2, uuid.New(), 2, "Richard Roe", "richard@hospital.com", "", true)),
+	)
+
+	req, _ := http.NewRequest("GET", "/api/v1/calendar/2021/08/10.ics", nil)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != ICalendarContentType {
+		t.Errorf("content type is incorrect, got %s, want %s", got, ICalendarContentType)
+	}
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") {
+		t.Errorf("response should be an iCalendar feed, got %s", body)
+	}
+	if got := strings.Count(body, "BEGIN:VEVENT\r\n"); got != 2 {
+		t.Errorf("events are incorrect, got %d, want %d", got, 2)
+	}
+	start := time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local).UTC().Format("20060102T150405Z")
+	for _, want := range []string{"SUMMARY:Appointment with Jane Roe\r\n", "SUMMARY:Appointment with Richard Roe\r\n", "DTSTART:" + start + "\r\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("response should contain %q, got %s", want, body)
+		}
+	}
+}
+
 func TestInsertBlockPeriod(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
 	type args struct {
@@ -863,6 +1762,7 @@ func TestInsertBlockPeriod(t *testing.T) {
 				dbMockOptions: []mock.DBResultOption{
 					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
 					withInsertBlockerResult(sqlmock.NewResult(1, 1)),
+					withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
 				},
 				blockPeriod: &BlockPeriod{
 					StartDate:   time.Now(),
@@ -1021,6 +1921,33 @@ func TestInsertBlockPeriod(t *testing.T) {
 			},
 			want: http.StatusInternalServerError,
 		},
+		{
+			name: "should not insert a block period due to a database error while listing the affected appointments",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockDoctorUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockDoctorUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+					withInsertBlockerResult(sqlmock.NewResult(1, 1)),
+					withListAppointmentsInRangeError(),
+				},
+				blockPeriod: &BlockPeriod{
+					StartDate:   time.Now(),
+					EndDate:     time.Now().Add(24 * time.Hour),
+					Description: nil,
+				},
+			},
+			want: http.StatusInternalServerError,
+		},
 		{
 			name: "should not insert a block period because no rows are affected after insertion",
 			args: args{
@@ -1079,8 +2006,347 @@ func TestInsertBlockPeriod(t *testing.T) {
 	}
 }
 
+func TestInsertBlockPeriodValidationErrors(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockDoctorUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockDoctorUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+	)
+
+	body, _ := json.Marshal(BlockPeriod{})
+	req, _ := http.NewRequest("POST", "/api/v1/calendar/blockers", bytes.NewBuffer(body))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	want := "[{\"field\":\"start_date\",\"tag\":\"required\"},{\"field\":\"end_date\",\"tag\":\"required\"}]\n"
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("response is incorrect, got %s, want %s", got, want)
+	}
+}
+
+func TestInsertBlockPeriodAffectedAppointments(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockDoctorUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockDoctorUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	overlapping := uuid.New()
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+		withInsertBlockerResult(sqlmock.NewResult(1, 1)),
+		withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).
+			AddRow(1, overlapping, 1, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local)).
+			AddRow(2, uuid.New(), 1, 2, time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local))),
+	)
+
+	body, _ := json.Marshal(BlockPeriod{
+		StartDate: time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local),
+		EndDate:   time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local),
+	})
+	req, _ := http.NewRequest("POST", "/api/v1/calendar/blockers", bytes.NewReader(body))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusCreated)
+	}
+	var result BlockerResult
+	if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+		t.Fatalf("response body is incorrect, got error %v", err)
+	}
+	if result.AffectedAppointments != 1 || len(result.AffectedAppointmentUUIDs) != 1 || result.AffectedAppointmentUUIDs[0] != overlapping {
+		t.Errorf("affected appointments are incorrect, got %+v, want only %s", result, overlapping)
+	}
+}
+
+func TestInclusiveBlockPeriod(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	day := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		inclusive    bool
+		wantHours    []int32
+		wantAffected int
+	}{
+		{
+			name:         "should keep the end hour of an exclusive blocker available",
+			inclusive:    false,
+			wantHours:    []int32{9, 10, 11, 12, 13, 14, 16, 17},
+			wantAffected: 1,
+		},
+		{
+			name:         "should block the end hour of an inclusive blocker",
+			inclusive:    true,
+			wantHours:    []int32{9, 10, 11, 12, 13, 14, 17},
+			wantAffected: 2,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		logger := log.New(emptyWriter{}, "", log.LstdFlags)
+
+		t.Run(tt.name+" on the calendar", func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+			dbConn := mock.MustCreateConnectionMock()
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description", "inclusive"}).AddRow(1, uuid.UUID{}, 1, day.Add(15*time.Hour), day.Add(16*time.Hour), "", tt.inclusive)),
+			)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s/2021/08/10", uuid.UUID{}), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			got := make([]int32, 0, len(entries))
+			for _, entry := range entries {
+				got = append(got, entry.Hour)
+			}
+			if !reflect.DeepEqual(got, tt.wantHours) {
+				t.Errorf("available hours are incorrect, got %v, want %v", got, tt.wantHours)
+			}
+		})
+
+		t.Run(tt.name+" on the affected appointments", func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+			dbConn := mock.MustCreateConnectionMock()
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")),
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertBlockerQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), tt.inclusive).WillReturnResult(sqlmock.NewResult(1, 1))
+				},
+				withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).
+					AddRow(1, uuid.New(), 1, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local)).
+					AddRow(2, uuid.New(), 1, 2, time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local))),
+			)
+
+			body, _ := json.Marshal(BlockPeriod{
+				StartDate: time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local),
+				EndDate:   time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local),
+				Inclusive: tt.inclusive,
+			})
+			req, _ := http.NewRequest("POST", "/api/v1/calendar/blockers", bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusCreated {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusCreated)
+			}
+			var result BlockerResult
+			if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if result.AffectedAppointments != tt.wantAffected {
+				t.Errorf("affected appointments are incorrect, got %d, want %d", result.AffectedAppointments, tt.wantAffected)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("the inclusive flag should be persisted: %v", err)
+			}
+		})
+	}
+}
+
+func TestInsertRecurringBlockPeriod(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).AddRow(1, uuid.UUID{}, "John Doe", "doctor@hospital.com")
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		request       RecurringBlockPeriodRequest
+		want          int
+	}{
+		{
+			name: "should insert a blocker for each week of a 4-week span",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withInsertBlockersBatchResult(4, sqlmock.NewResult(0, 4)),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   strings.ToLower(tomorrow.Weekday().String()),
+				StartHour: 13,
+				EndHour:   18,
+				EndDate:   tomorrow.AddDate(0, 0, 21),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should not insert the blockers because the weekday is not valid",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   "someday",
+				StartHour: 13,
+				EndHour:   18,
+				EndDate:   tomorrow.AddDate(0, 0, 21),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert the blockers because the end date is not after today",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   "wednesday",
+				StartHour: 13,
+				EndHour:   18,
+				EndDate:   time.Now(),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert the blockers because the end hour is not after the start hour",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   "wednesday",
+				StartHour: 13,
+				EndHour:   13,
+				EndDate:   tomorrow.AddDate(0, 0, 21),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert the blockers because no doctor associated to the user was found",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "name", "email"})),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   "wednesday",
+				StartHour: 13,
+				EndHour:   18,
+				EndDate:   tomorrow.AddDate(0, 0, 21),
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "should not insert the blockers due to a database error",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withInsertBlockersBatchError(4),
+			},
+			request: RecurringBlockPeriodRequest{
+				Weekday:   strings.ToLower(tomorrow.Weekday().String()),
+				StartHour: 13,
+				EndHour:   18,
+				EndDate:   tomorrow.AddDate(0, 0, 21),
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			body, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest("POST", "/api/v1/calendar/blockers/recurring", bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("the expected blockers were not inserted: %v", err)
+			}
+		})
+	}
+}
+
 func TestInsertAppointment(t *testing.T) {
 	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
 	type args struct {
 		config             configs.Config
 		mockAuth           mockAuthorizer
@@ -1113,25 +2379,26 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
 					withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusCreated,
 		},
 		{
-			name: "should not insert an appointment because no patient associated with the user was found",
+			name: "should insert an appointment starting at the end of a blocker",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
@@ -1145,20 +2412,26 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"})),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
 				},
 				appointmentRequest: &AppointmentRequest{
-					Hour: 9,
+					Hour: 16,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
-			want: http.StatusForbidden,
+			want: http.StatusCreated,
 		},
 		{
-			name: "should not insert an appointment due to a database error while searching for the patient",
+			name: "should not insert an appointment at the same hour the patient has an appointment with another doctor",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
@@ -1172,20 +2445,25 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDError(),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(2, uuid.New(), 2, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 9, 0, 0, 0, time.UTC))),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
-			want: http.StatusInternalServerError,
+			want: http.StatusBadRequest,
 		},
 		{
-			name: "should not insert an appointment due to a database error while parsing the found patient",
+			name: "should insert an appointment at a different hour the patient has an appointment in the same day",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
@@ -1199,20 +2477,58 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, false, 1, "Patient", "patient@hospital.com", "")),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(2, uuid.New(), 2, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.UTC))),
+					withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should not insert an appointment due to an error listing the patient's appointments",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+					withListAppointmentsByPatientAndDateError(),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusInternalServerError,
 		},
 		{
-			name: "should not insert an appointment because no doctor was found with the given UUID",
+			name: "should not insert an appointment in the past",
 			args: args{
 				config: config,
 				dbConn: mock.MustCreateConnectionMock(),
@@ -1226,8 +2542,7 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"})),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
@@ -1237,8 +2552,231 @@ func TestInsertAppointment(t *testing.T) {
 				month:      "08",
 				day:        "10",
 			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert an appointment yesterday",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: endWorkHour,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.AddDate(0, 0, -2).Format("2006"),
+				month:      tomorrow.AddDate(0, 0, -2).Format("01"),
+				day:        tomorrow.AddDate(0, 0, -2).Format("02"),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert an appointment because the chosen slot is inside a blocker",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 15,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not insert an appointment because no patient associated with the user was found",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"})),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "should not insert an appointment because the patient has not given consent",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", false)),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "should not insert an appointment due to a database error while searching for the patient",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDError(),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "should not insert an appointment due to a database error while parsing the found patient",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, false, 1, "Patient", "patient@hospital.com", "", true)),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "should not insert an appointment because no doctor was found with the given UUID",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
 			want: http.StatusNotFound,
 		},
+		{
+			name: "should not insert an appointment because the doctor is inactive",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", false)),
+				},
+				appointmentRequest: &AppointmentRequest{
+					Hour: 9,
+				},
+				doctorUUID: &uuid.UUID{},
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
+			},
+			want: http.StatusConflict,
+		},
 		{
 			name: "should not insert an appointment due to a database error while searching for the doctor",
 			args: args{
@@ -1255,15 +2793,15 @@ func TestInsertAppointment(t *testing.T) {
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
 					withFindDoctorByUUIDError(),
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusInternalServerError,
 		},
@@ -1282,16 +2820,16 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, false, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, false, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusInternalServerError,
 		},
@@ -1309,13 +2847,16 @@ func TestInsertAppointment(t *testing.T) {
 					},
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 19,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusBadRequest,
 		},
@@ -1334,19 +2875,20 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 10,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusBadRequest,
 		},
@@ -1365,20 +2907,21 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
 					withInsertAppointmentError(),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusInternalServerError,
 		},
@@ -1397,20 +2940,21 @@ func TestInsertAppointment(t *testing.T) {
 				},
 				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
 				dbMockOptions: []mock.DBResultOption{
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "")),
-					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "")),
-					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
-					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 10, 15, 0, 0, 0, time.Local), time.Date(2021, 8, 10, 16, 0, 0, 0, time.Local), "")),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local), time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 16, 0, 0, 0, time.Local), "")),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
 					withInsertAppointmentResult(sqlmock.NewResult(0, 0)),
 				},
 				appointmentRequest: &AppointmentRequest{
 					Hour: 9,
 				},
 				doctorUUID: &uuid.UUID{},
-				year:       "2021",
-				month:      "08",
-				day:        "10",
+				year:       tomorrow.Format("2006"),
+				month:      tomorrow.Format("01"),
+				day:        tomorrow.Format("02"),
 			},
 			want: http.StatusInternalServerError,
 		},
@@ -1445,3 +2989,2883 @@ func TestInsertAppointment(t *testing.T) {
 		})
 	}
 }
+
+func TestListDoctorsBySpecialty(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	type args struct {
+		config        configs.Config
+		mockAuth      mockAuthorizer
+		dbConn        mock.Connection
+		dbMockOptions []mock.DBResultOption
+		tokens        *auth.Tokens
+		specialty     string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         int
+		wantResponse string
+	}{
+		{
+			name: "should list the doctors matching the specialty ignoring case",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withListDoctorsBySpecialtyResult("cardiology", sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", true)),
+				},
+				specialty: "%20Cardiology%20",
+			},
+			want:         http.StatusOK,
+			wantResponse: "[{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"specialty\":\"cardiology\"}]\n",
+		},
+		{
+			name: "should only search among the active doctors",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					func(dbConn mock.Connection) {
+						dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta("FROM tb_doctor WHERE active AND")).WithArgs("", maxListedDoctors).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", true))
+					},
+				},
+			},
+			want:         http.StatusOK,
+			wantResponse: "[{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"specialty\":\"cardiology\"}]\n",
+		},
+		{
+			name: "should list no doctors if none matches the specialty",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withListDoctorsBySpecialtyResult("neurology", sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+				},
+				specialty: "Neurology",
+			},
+			want:         http.StatusOK,
+			wantResponse: "[]\n",
+		},
+		{
+			name: "should list all the doctors if no specialty is given",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withListDoctorsBySpecialtyResult("", sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", true).AddRow(2, uuid.UUID{}, 2, "Mary Doe", "mary@hospital.com", "", "neurology", true)),
+				},
+				specialty: "",
+			},
+			want:         http.StatusOK,
+			wantResponse: "[{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"specialty\":\"cardiology\"},{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"Mary Doe\",\"specialty\":\"neurology\"}]\n",
+		},
+		{
+			name: "should not list the doctors because the specialty is unknown",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens:    auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				specialty: "CARDIO",
+			},
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"specialty\",\"tag\":\"oneof cardiology dermatology gynecology neurology oncology ophthalmology orthopedics pediatrics psychiatry\"}\n",
+		},
+		{
+			name: "should not list the doctors due to a database error while searching for the doctors",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withListDoctorsBySpecialtyError(),
+				},
+				specialty: "cardiology",
+			},
+			want:         http.StatusInternalServerError,
+			wantResponse: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router := chi.NewRouter()
+			Setup(router, logger, tt.args.mockAuth, tt.args.config, tt.args.dbConn)
+
+			mock.MockDBResults(tt.args.dbConn, tt.args.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/doctors?specialty=%s", tt.args.specialty), nil)
+
+			token := ""
+			if tt.args.tokens != nil {
+				token = fmt.Sprintf("Bearer %s", tt.args.tokens.AccessToken)
+			}
+
+			req.Header.Add("Authorization", token)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			response := recorder.Result()
+
+			if response.StatusCode != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+
+			buf := new(bytes.Buffer)
+			_, err := buf.ReadFrom(response.Body)
+			if err != nil {
+				t.Errorf("an error occurred while reading response body: %v", err)
+			}
+
+			responseBody := buf.String()
+
+			if tt.wantResponse != responseBody {
+				t.Errorf("response body is incorrect, got %s, want %s", responseBody, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestListDoctors(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", true)
+	}
+	tests := []struct {
+		name          string
+		user          *auth.User
+		query         string
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantResponse  string
+	}{
+		{
+			name:  "should list the first page of doctors by default",
+			user:  mockAdminUser(),
+			query: "",
+			dbMockOptions: []mock.DBResultOption{
+				withListDoctorsPagedResult(20, 0, doctorRows()),
+				withCountDoctorsResult(1),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"items\":[{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"email\":\"doctor@hospital.com\",\"mobile_phone\":\"\",\"specialty\":\"cardiology\",\"active\":true}],\"total\":1,\"page\":1,\"size\":20}\n",
+		},
+		{
+			name:  "should list the given page of doctors",
+			user:  mockAdminUser(),
+			query: "?page=3&size=10",
+			dbMockOptions: []mock.DBResultOption{
+				withListDoctorsPagedResult(10, 20, sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+				withCountDoctorsResult(12),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"items\":[],\"total\":12,\"page\":3,\"size\":10}\n",
+		},
+		{
+			name:  "should cap the page size",
+			user:  mockAdminUser(),
+			query: "?page=1&size=500",
+			dbMockOptions: []mock.DBResultOption{
+				withListDoctorsPagedResult(maxPageSize, 0, doctorRows()),
+				withCountDoctorsResult(1),
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:         "should not list the doctors because the page is lower than 1",
+			user:         mockAdminUser(),
+			query:        "?page=0",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"page\",\"tag\":\"min 1\"}\n",
+		},
+		{
+			name:         "should not list the doctors because the size is not a number",
+			user:         mockAdminUser(),
+			query:        "?size=ten",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"size\",\"tag\":\"min 1\"}\n",
+		},
+		{
+			name:  "should not list the doctors because the user is not an admin",
+			user:  mockPatientUser(),
+			query: "",
+			want:  http.StatusForbidden,
+		},
+		{
+			name:  "should not list the doctors due to a database error while listing them",
+			user:  mockAdminUser(),
+			query: "",
+			dbMockOptions: []mock.DBResultOption{
+				withListDoctorsPagedError(),
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name:  "should not list the doctors due to a database error while counting them",
+			user:  mockAdminUser(),
+			query: "",
+			dbMockOptions: []mock.DBResultOption{
+				withListDoctorsPagedResult(20, 0, doctorRows()),
+				withCountDoctorsError(),
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", "/api/v1/doctors/all"+tt.query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.wantResponse != "" && recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFindDoctorByEmail(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tests := []struct {
+		name          string
+		user          *auth.User
+		query         string
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantResponse  string
+	}{
+		{
+			name:  "should find the doctor with the given email",
+			user:  mockAdminUser(),
+			query: "?email=doctor@hospital.com",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByEmailResult("doctor@hospital.com", sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", false)),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"email\":\"doctor@hospital.com\",\"mobile_phone\":\"\",\"specialty\":\"cardiology\",\"active\":false}\n",
+		},
+		{
+			name:  "should not find the doctor because none has the given email",
+			user:  mockAdminUser(),
+			query: "?email=nobody@hospital.com",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByEmailResult("nobody@hospital.com", sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			want:         http.StatusNotFound,
+			wantResponse: "{\"message\":\"doctor not found\",\"code\":\"DOCTOR_NOT_FOUND\"}\n",
+		},
+		{
+			name:         "should not find the doctor because the email is missing",
+			user:         mockAdminUser(),
+			query:        "",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"email\",\"tag\":\"required\"}\n",
+		},
+		{
+			name:         "should not find the doctor because the email is invalid",
+			user:         mockAdminUser(),
+			query:        "?email=doctor",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"email\",\"tag\":\"email\"}\n",
+		},
+		{
+			name:  "should not find the doctor due to a database error",
+			user:  mockAdminUser(),
+			query: "?email=doctor@hospital.com",
+			dbMockOptions: []mock.DBResultOption{
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorByEmailQuery)).WithArgs(sqlmock.AnyArg()).WillReturnError(sql.ErrConnDone)
+				},
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name:  "should not find the doctor because the user is not an admin",
+			user:  mockPatientUser(),
+			query: "?email=doctor@hospital.com",
+			want:  http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", "/api/v1/doctors/search"+tt.query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.wantResponse != "" && recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestGetDoctor(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	type args struct {
+		config        configs.Config
+		mockAuth      mockAuthorizer
+		dbConn        mock.Connection
+		dbMockOptions []mock.DBResultOption
+		tokens        *auth.Tokens
+		doctorUUID    string
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         int
+		wantResponse string
+	}{
+		{
+			name: "should get the doctor",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", true)),
+				},
+				doctorUUID: uuid.UUID{}.String(),
+			},
+			want:         http.StatusOK,
+			wantResponse: "{\"uuid\":\"00000000-0000-0000-0000-000000000000\",\"name\":\"John Doe\",\"specialty\":\"cardiology\"}\n",
+		},
+		{
+			name: "should not get the doctor because it was not found",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+				},
+				doctorUUID: uuid.UUID{}.String(),
+			},
+			want:         http.StatusNotFound,
+			wantResponse: "{\"message\":\"doctor not found\",\"code\":\"DOCTOR_NOT_FOUND\"}\n",
+		},
+		{
+			name: "should not get the doctor because it is inactive",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "cardiology", false)),
+				},
+				doctorUUID: uuid.UUID{}.String(),
+			},
+			want:         http.StatusNotFound,
+			wantResponse: "{\"message\":\"doctor not found\",\"code\":\"DOCTOR_NOT_FOUND\"}\n",
+		},
+		{
+			name: "should not get the doctor because wrong UUID",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens:     auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				doctorUUID: "not-an-uuid",
+			},
+			want:         http.StatusBadRequest,
+			wantResponse: fmt.Sprintf("{\"message\":\"%s\",\"code\":\"%s\"}\n", ErrInvalidIdentifier, CodeInvalidIdentifier),
+		},
+		{
+			name: "should not get the doctor due to a database error while searching for the doctor",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindDoctorByUUIDError(),
+				},
+				doctorUUID: uuid.UUID{}.String(),
+			},
+			want:         http.StatusInternalServerError,
+			wantResponse: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, tt.args.mockAuth, tt.args.config, tt.args.dbConn)
+
+			mock.MockDBResults(tt.args.dbConn, tt.args.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/doctors/%s", tt.args.doctorUUID), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tt.args.tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.wantResponse != "" && recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestGetDoctorAvailability(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	day := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		query         string
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantResponse  string
+	}{
+		{
+			name:  "should tell the fully blocked days apart from the days with free slots",
+			query: "from=2021-08-10&to=2021-08-12",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				// the whole range is loaded at once
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listAppointmentsInRangeQuery)).WithArgs(1, day, day.AddDate(0, 0, 3)).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, day.AddDate(0, 0, 2).Add(9*time.Hour)))
+				},
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(1, day, day.AddDate(0, 0, 3)).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), ""))
+				},
+			},
+			want:         http.StatusOK,
+			wantResponse: "[{\"date\":\"2021-08-10\",\"available\":true},{\"date\":\"2021-08-11\",\"available\":false},{\"date\":\"2021-08-12\",\"available\":true}]\n",
+		},
+		{
+			name:  "should not be available on a day whose free slots are all booked or blocked",
+			query: "from=2021-08-10&to=2021-08-10",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, day.Add(17*time.Hour))),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, day.Add(9*time.Hour), day.Add(17*time.Hour), "")),
+			},
+			want:         http.StatusOK,
+			wantResponse: "[{\"date\":\"2021-08-10\",\"available\":false}]\n",
+		},
+		{
+			name:         "should not get the availability because the dates are missing",
+			query:        "",
+			want:         http.StatusBadRequest,
+			wantResponse: "[{\"field\":\"from\",\"tag\":\"date e.g. 2021-08-10\"},{\"field\":\"to\",\"tag\":\"date e.g. 2021-08-10\"}]\n",
+		},
+		{
+			name:         "should not get the availability because the range ends before it starts",
+			query:        "from=2021-08-10&to=2021-08-09",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"to\",\"tag\":\"invalid period\"}\n",
+		},
+		{
+			name:         "should not get the availability because the range is longer than 60 days",
+			query:        "from=2021-08-10&to=2021-10-09",
+			want:         http.StatusBadRequest,
+			wantResponse: "{\"field\":\"to\",\"tag\":\"max 60 days\"}\n",
+		},
+		{
+			name:  "should get the availability of a range of 60 days",
+			query: "from=2021-08-10&to=2021-10-08",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:  "should not get the availability because the doctor was not found",
+			query: "from=2021-08-10&to=2021-08-12",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			want:         http.StatusNotFound,
+			wantResponse: "{\"message\":\"doctor not found\",\"code\":\"DOCTOR_NOT_FOUND\"}\n",
+		},
+		{
+			name:  "should not get the availability due to a database error while listing the blockers",
+			query: "from=2021-08-10&to=2021-08-12",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersError(),
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/doctors/%s/availability?%s", uuid.UUID{}, tt.query), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.wantResponse != "" && recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInsertAppointmentAdvanceWindow(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tests := []struct {
+		name string
+		date time.Time
+		want int
+	}{
+		{
+			name: "should insert an appointment on the last day of the window",
+			date: time.Now().AddDate(0, 0, config.MaxAdvanceDays()),
+			want: http.StatusCreated,
+		},
+		{
+			name: "should not insert an appointment after the last day of the window",
+			date: time.Now().AddDate(0, 0, config.MaxAdvanceDays()+1),
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)))
+			if tt.want == http.StatusCreated {
+				mock.MockDBResults(dbConn,
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
+				)
+			}
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: 9})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tt.date.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want == http.StatusBadRequest && !strings.Contains(recorder.Body.String(), "too far in advance") {
+				t.Errorf("the response should describe the window, got %s", recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAppointmentNotes(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	notes := "headache since yesterday"
+	tests := []struct {
+		name  string
+		notes string
+		want  int
+	}{
+		{
+			name:  "should insert an appointment with notes",
+			notes: notes,
+			want:  http.StatusCreated,
+		},
+		{
+			name:  "should not insert an appointment because the notes are too long",
+			notes: strings.Repeat("a", maxNotesLength+1),
+			want:  http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)))
+			if tt.want == http.StatusCreated {
+				mock.MockDBResults(dbConn,
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+					withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+					func(dbConn mock.Connection) {
+						dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), tt.notes, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+					},
+				)
+			}
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: 9, Notes: &tt.notes})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("the notes should be persisted: %v", err)
+			}
+		})
+	}
+
+	t.Run("should return the notes in the doctor's appointments", func(t *testing.T) {
+		t.Parallel()
+		mockAuth := mockAuthorizer{
+			mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+				return mockDoctorUser(), nil
+			},
+			mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+				return *mockDoctorUser(), nil
+			},
+		}
+		dbConn := mock.MustCreateConnectionMock()
+		tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+		router := chi.NewRouter()
+		logger := log.New(emptyWriter{}, "", log.LstdFlags)
+		Setup(router, logger, mockAuth, config, dbConn)
+
+		mock.MockDBResults(dbConn,
+			withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+			withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "notes"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 9, 0, 0, 0, time.Local), notes)),
+			withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+			withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		)
+
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/calendar/%s?only=booked", tomorrow.Format("2006/01/02")), nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+		}
+		var entries []Entry
+		if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+			t.Fatalf("response body is incorrect, got error %v", err)
+		}
+		if len(entries) != 1 || entries[0].Notes == nil || *entries[0].Notes != notes {
+			t.Errorf("appointment notes are incorrect, got %+v, want %q", entries, notes)
+		}
+	})
+}
+
+func TestUnknownRequestFields(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	tests := []struct {
+		name string
+		user *auth.User
+		url  string
+		body string
+	}{
+		{
+			name: "should not insert an appointment with an unknown field",
+			user: mockPatientUser(),
+			url:  fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")),
+			body: `{"hour":9,"bogus":1}`,
+		},
+		{
+			name: "should not insert an appointment with the date in the body",
+			user: mockPatientUser(),
+			url:  fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")),
+			body: `{"hour":9,"Date":"2021-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "should not insert a blocker with an unknown field",
+			user: mockDoctorUser(),
+			url:  "/api/v1/calendar/blockers",
+			body: `{"start_date":"2021-01-01T08:00:00Z","end_date":"2021-01-01T10:00:00Z","bogus":1}`,
+		},
+		{
+			name: "should not insert recurring blockers with an unknown field",
+			user: mockDoctorUser(),
+			url:  "/api/v1/calendar/blockers/recurring",
+			body: `{"weekday":"monday","start_hour":8,"end_hour":10,"bogus":1}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			req, _ := http.NewRequest("POST", tt.url, strings.NewReader(tt.body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			if !strings.Contains(recorder.Body.String(), "unknown field") {
+				t.Errorf("the response should describe the unknown field, got %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestAppointmentDuration(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	blockerStart := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		hour     int32
+		duration int32
+		mockDB   bool
+		want     int
+	}{
+		{
+			name:     "should not insert a 2-slot appointment spanning a blocked hour",
+			hour:     9,
+			duration: 2,
+			mockDB:   true,
+			want:     http.StatusBadRequest,
+		},
+		{
+			name:     "should insert a 2-slot appointment in a free window",
+			hour:     13,
+			duration: 2,
+			mockDB:   true,
+			want:     http.StatusCreated,
+		},
+		{
+			name:     "should not insert an appointment ending after the working hours",
+			hour:     endWorkHour,
+			duration: 2,
+			want:     http.StatusBadRequest,
+		},
+		{
+			name:     "should not insert an appointment with a negative duration",
+			hour:     9,
+			duration: -1,
+			want:     http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			if tt.mockDB {
+				options := []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+					withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "duration"})),
+					withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, blockerStart, blockerStart.Add(time.Hour), "")),
+				}
+				if tt.want == http.StatusBadRequest {
+					options = append(options, withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, blockerStart, blockerStart.Add(time.Hour), "")))
+				}
+				if tt.want == http.StatusCreated {
+					options = append(options,
+						withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "duration"})),
+						func(dbConn mock.Connection) {
+							dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(insertAppointmentQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), tt.duration).WillReturnResult(sqlmock.NewResult(1, 1))
+						},
+					)
+				}
+				mock.MockDBResults(dbConn, options...)
+			} else {
+				mock.MockDBResults(dbConn, withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)))
+			}
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: tt.hour, Duration: tt.duration})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSlotNotAvailableReason(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	booked := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local)
+	blockerStart := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 15, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		hour     int32
+		wantCode string
+	}{
+		{
+			name:     "should tell the slot is blocked by the doctor",
+			hour:     15,
+			wantCode: CodeSlotBlocked,
+		},
+		{
+			name:     "should tell the slot is already booked",
+			hour:     10,
+			wantCode: CodeSlotBooked,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn,
+				withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 2, booked)),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, blockerStart, blockerStart.Add(time.Hour), "")),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}).AddRow(1, uuid.UUID{}, 1, blockerStart, blockerStart.Add(time.Hour), "")),
+			)
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: tt.hour})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			var apiErr struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(recorder.Body).Decode(&apiErr); err != nil {
+				t.Fatal(err)
+			}
+			if apiErr.Code != tt.wantCode {
+				t.Errorf("error code is incorrect, got %s, want %s", apiErr.Code, tt.wantCode)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInsertAppointmentNotifier(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	tests := []struct {
+		name         string
+		notifier     *stubNotifier
+		insertOption mock.DBResultOption
+		want         int
+		wantNotified int
+	}{
+		{
+			name:         "should notify the created appointment",
+			notifier:     &stubNotifier{},
+			insertOption: withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
+			want:         http.StatusCreated,
+			wantNotified: 1,
+		},
+		{
+			name:         "should create the appointment even if the notification fails",
+			notifier:     &stubNotifier{err: fmt.Errorf("webhook unavailable")},
+			insertOption: withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
+			want:         http.StatusCreated,
+			wantNotified: 1,
+		},
+		{
+			name:         "should not notify when the appointment is not inserted",
+			notifier:     &stubNotifier{},
+			insertOption: withInsertAppointmentError(),
+			want:         http.StatusInternalServerError,
+			wantNotified: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn, WithNotifier(tt.notifier))
+
+			mock.MockDBResults(dbConn,
+				withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				tt.insertOption,
+			)
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: 9})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.notifier.calls != tt.wantNotified {
+				t.Errorf("notifier calls are incorrect, got %d, want %d", tt.notifier.calls, tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestCancelAppointment(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	type args struct {
+		config          configs.Config
+		mockAuth        mockAuthorizer
+		dbConn          mock.Connection
+		dbMockOptions   []mock.DBResultOption
+		tokens          *auth.Tokens
+		appointmentUUID string
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "should cancel the appointment",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "REQUESTED")),
+					withCancelAppointmentResult(sqlmock.NewResult(0, 1)),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should not cancel the appointment because it was not found",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should not cancel the appointment because it belongs to another patient",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 2, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusNotFound,
+		},
+		{
+			name: "should not cancel the appointment because the user is not a patient",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"})),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "should not cancel the appointment because wrong UUID",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens:          auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				appointmentUUID: "not-an-uuid",
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not cancel the appointment due to a database error while searching for the appointment",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDError(),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "should not cancel the appointment due to a database error while cancelling it",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "REQUESTED")),
+					withCancelAppointmentError(),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "should not cancel the appointment because it is completed",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "COMPLETED")),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name: "should not cancel the appointment because it is a no-show",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "NO_SHOW")),
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name: "should not cancel the appointment because it was updated meanwhile",
+			args: args{
+				config: config,
+				dbConn: mock.MustCreateConnectionMock(),
+				mockAuth: mockAuthorizer{
+					mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+						return mockPatientUser(), nil
+					},
+					mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+						return *mockPatientUser(), nil
+					},
+				},
+				tokens: auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser()),
+				dbMockOptions: []mock.DBResultOption{
+					withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+					withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "version"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "CONFIRMED", 2)),
+					func(dbConn mock.Connection) {
+						dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(cancelAppointmentQuery)).WithArgs(int64(1), int32(2)).WillReturnResult(sqlmock.NewResult(0, 0))
+					},
+				},
+				appointmentUUID: uuid.UUID{}.String(),
+			},
+			want: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, tt.args.mockAuth, tt.args.config, tt.args.dbConn)
+
+			mock.MockDBResults(tt.args.dbConn, tt.args.dbMockOptions...)
+
+			req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/calendar/appointments/%s", tt.args.appointmentUUID), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tt.args.tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateAppointmentStatus(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	appointmentRows := func(doctorID int64, status AppointmentStatus) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "version"}).AddRow(1, uuid.UUID{}, doctorID, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), string(status), 1)
+	}
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "Cardiology", true)
+	}
+	tests := []struct {
+		name            string
+		dbMockOptions   []mock.DBResultOption
+		appointmentUUID string
+		body            string
+		want            int
+		wantCode        string
+	}{
+		{
+			name: "should confirm the requested appointment",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentRequested)),
+				withUpdateAppointmentStatusResult(sqlmock.NewResult(0, 1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusNoContent,
+		},
+		{
+			name: "should complete the confirmed appointment",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentConfirmed)),
+				withUpdateAppointmentStatusResult(sqlmock.NewResult(0, 1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "COMPLETED", "version": 1}`,
+			want:            http.StatusNoContent,
+		},
+		{
+			name: "should cancel the confirmed appointment",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentConfirmed)),
+				withCancelAppointmentResult(sqlmock.NewResult(0, 1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CANCELLED", "version": 1}`,
+			want:            http.StatusNoContent,
+		},
+		{
+			name: "should not complete the appointment because it was not confirmed",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentRequested)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "COMPLETED", "version": 1}`,
+			want:            http.StatusConflict,
+		},
+		{
+			name: "should not confirm the appointment because it was completed",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentCompleted)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusConflict,
+		},
+		{
+			name: "should confirm the appointment at the expected version, bumping it",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "version"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "REQUESTED", 3)),
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updateAppointmentStatusQuery)).WithArgs("CONFIRMED", int64(1), int32(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+				},
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 3}`,
+			want:            http.StatusNoContent,
+		},
+		{
+			name: "should not confirm the appointment because the expected version is stale",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "version"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "REQUESTED", 2)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusConflict,
+			wantCode:        CodeAppointmentVersionConflict,
+		},
+		{
+			name:            "should not update the status without the expected version",
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED"}`,
+			want:            http.StatusBadRequest,
+		},
+		{
+			name: "should not cancel the appointment because it was updated meanwhile",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentConfirmed)),
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(cancelAppointmentQuery)).WithArgs(int64(1), int32(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				},
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CANCELLED", "version": 1}`,
+			want:            http.StatusConflict,
+			wantCode:        CodeAppointmentVersionConflict,
+		},
+		{
+			name: "should not confirm the appointment because its status changed meanwhile",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentRequested)),
+				withUpdateAppointmentStatusResult(sqlmock.NewResult(0, 0)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusConflict,
+			wantCode:        CodeAppointmentVersionConflict,
+		},
+		{
+			name: "should mark the past confirmed appointment as no-show",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentConfirmed)),
+				withUpdateAppointmentStatusResult(sqlmock.NewResult(0, 1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "NO_SHOW", "version": 1}`,
+			want:            http.StatusNoContent,
+		},
+		{
+			name: "should not mark the future appointment as no-show",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "version"}).AddRow(1, uuid.UUID{}, 1, 1, time.Now().Add(24*time.Hour), "CONFIRMED", 1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "NO_SHOW", "version": 1}`,
+			want:            http.StatusBadRequest,
+		},
+		{
+			name: "should not mark the requested appointment as no-show",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(1, AppointmentRequested)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "NO_SHOW", "version": 1}`,
+			want:            http.StatusConflict,
+		},
+		{
+			name:            "should not update the status because it is unknown",
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "MISSED", "version": 1}`,
+			want:            http.StatusBadRequest,
+		},
+		{
+			name:            "should not update the status because wrong UUID",
+			appointmentUUID: "not-an-uuid",
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusBadRequest,
+		},
+		{
+			name: "should not update the status because the appointment belongs to another doctor",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(2, AppointmentRequested)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusNotFound,
+		},
+		{
+			name: "should not update the status because the user is not a doctor",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusForbidden,
+		},
+		{
+			name: "should not update the status due to a database error while searching for the appointment",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDError(),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			body:            `{"status": "CONFIRMED", "version": 1}`,
+			want:            http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			dbConn := mock.MustCreateConnectionMock()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+			req, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/calendar/appointments/%s/status", tt.appointmentUUID), strings.NewReader(tt.body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("database expectations were not met: %v", err)
+			}
+			if tt.wantCode != "" && !strings.Contains(recorder.Body.String(), tt.wantCode) {
+				t.Errorf("response body is incorrect, got %s, want the code %s", recorder.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGetAppointment(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	appointmentRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))
+	}
+	doctorRows := func(userID int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, userID, "John Doe", "doctor@hospital.com", "", "Cardiology", true)
+	}
+	patientRows := func(userID int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, userID, "Patient", "patient@hospital.com", "", true)
+	}
+	tests := []struct {
+		name            string
+		user            *auth.User
+		dbMockOptions   []mock.DBResultOption
+		appointmentUUID string
+		want            int
+	}{
+		{
+			name: "should return the appointment to its patient",
+			user: mockPatientUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(appointmentRows()),
+				withFindDoctorByIDResult(doctorRows(2)),
+				withFindPatientByIDResult(patientRows(1)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusOK,
+		},
+		{
+			name: "should return the appointment to its doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(appointmentRows()),
+				withFindDoctorByIDResult(doctorRows(1)),
+				withFindPatientByIDResult(patientRows(2)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusOK,
+		},
+		{
+			name: "should not return the appointment to another patient",
+			user: mockPatientUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(appointmentRows()),
+				withFindDoctorByIDResult(doctorRows(3)),
+				withFindPatientByIDResult(patientRows(2)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusForbidden,
+		},
+		{
+			name: "should not return the appointment to another doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(appointmentRows()),
+				withFindDoctorByIDResult(doctorRows(2)),
+				withFindPatientByIDResult(patientRows(3)),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusForbidden,
+		},
+		{
+			name: "should not return the appointment because it was not found",
+			user: mockPatientUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusNotFound,
+		},
+		{
+			name:            "should not return the appointment due to invalid appointment UUID",
+			user:            mockPatientUser(),
+			appointmentUUID: "invalid",
+			want:            http.StatusBadRequest,
+		},
+		{
+			name: "should not return the appointment due to a database error while finding it",
+			user: mockPatientUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDError(),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusInternalServerError,
+		},
+		{
+			name: "should not return the appointment due to a database error while finding its doctor",
+			user: mockPatientUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindAppointmentByUUIDResult(appointmentRows()),
+				withFindDoctorByIDError(),
+			},
+			appointmentUUID: uuid.UUID{}.String(),
+			want:            http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/appointments/%s", tt.appointmentUUID), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var appointment Appointment
+			if err := json.NewDecoder(recorder.Body).Decode(&appointment); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if appointment.Doctor == nil || appointment.Patient == nil {
+				t.Errorf("appointment doctor and patient should be populated, got %+v", appointment)
+			}
+		})
+	}
+}
+
+func TestGetPatientAppointments(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "Cardiology", true)
+	}
+	patientRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 2, "Patient", "patient@hospital.com", "", true)
+	}
+	tests := []struct {
+		name             string
+		user             *auth.User
+		dbMockOptions    []mock.DBResultOption
+		patientUUID      string
+		query            string
+		want             int
+		wantAppointments int
+	}{
+		{
+			name: "should return the patient's appointments with the doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindPatientByUUIDResult(patientRows()),
+				withListPatientHistoryResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "notes"}).
+					AddRow(2, uuid.New(), 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), "follow-up").
+					AddRow(1, uuid.New(), 1, 1, time.Date(2021, 7, 10, 9, 0, 0, 0, time.Local), nil)),
+			},
+			patientUUID:      uuid.UUID{}.String(),
+			want:             http.StatusOK,
+			wantAppointments: 2,
+		},
+		{
+			name: "should return only the patient's no-shows with the doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindPatientByUUIDResult(patientRows()),
+				withListPatientHistoryResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "notes", "status"}).
+					AddRow(3, uuid.New(), 1, 1, time.Date(2021, 9, 10, 10, 0, 0, 0, time.Local), nil, "COMPLETED").
+					AddRow(2, uuid.New(), 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local), nil, "NO_SHOW").
+					AddRow(1, uuid.New(), 1, 1, time.Date(2021, 7, 10, 9, 0, 0, 0, time.Local), nil, "CONFIRMED")),
+			},
+			patientUUID:      uuid.UUID{}.String(),
+			query:            "?status=no_show",
+			want:             http.StatusOK,
+			wantAppointments: 1,
+		},
+		{
+			name:        "should not return the appointments due to an unknown status filter",
+			user:        mockDoctorUser(),
+			patientUUID: uuid.UUID{}.String(),
+			query:       "?status=missed",
+			want:        http.StatusBadRequest,
+		},
+		{
+			name: "should not return the appointments because the patient never had one with the doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindPatientByUUIDResult(patientRows()),
+				withListPatientHistoryResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "notes"})),
+			},
+			patientUUID: uuid.UUID{}.String(),
+			want:        http.StatusNotFound,
+		},
+		{
+			name: "should not return the appointments because the patient was not found",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindPatientByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"})),
+			},
+			patientUUID: uuid.UUID{}.String(),
+			want:        http.StatusNotFound,
+		},
+		{
+			name: "should not return the appointments because the user is not a doctor",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			patientUUID: uuid.UUID{}.String(),
+			want:        http.StatusForbidden,
+		},
+		{
+			name:        "should not return the appointments to a patient",
+			user:        mockPatientUser(),
+			patientUUID: uuid.UUID{}.String(),
+			want:        http.StatusForbidden,
+		},
+		{
+			name:        "should not return the appointments due to invalid patient UUID",
+			user:        mockDoctorUser(),
+			patientUUID: "invalid",
+			want:        http.StatusBadRequest,
+		},
+		{
+			name: "should not return the appointments due to a database error while listing them",
+			user: mockDoctorUser(),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindPatientByUUIDResult(patientRows()),
+				withListPatientHistoryError(),
+			},
+			patientUUID: uuid.UUID{}.String(),
+			want:        http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/v1/patients/%s/appointments%s", tt.patientUUID, tt.query), nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var appointments []Appointment
+			if err := json.NewDecoder(recorder.Body).Decode(&appointments); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if len(appointments) != tt.wantAppointments {
+				t.Errorf("appointments count is incorrect, got %d, want %d", len(appointments), tt.wantAppointments)
+			}
+		})
+	}
+}
+
+func TestCancelledSlotIsBookable(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockPatientUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockPatientUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	// the appointment is soft-deleted, so it is not listed anymore while booking the same slot
+	mock.MockDBResults(dbConn,
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindAppointmentByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local), "REQUESTED")),
+		withCancelAppointmentResult(sqlmock.NewResult(0, 1)),
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withInsertAppointmentResult(sqlmock.NewResult(2, 1)),
+	)
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/api/v1/calendar/appointments/%s", uuid.UUID{}), nil)
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("cancel response status is incorrect, got %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+
+	body, _ := json.Marshal(AppointmentRequest{Hour: 10})
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("insert response status is incorrect, got %d, want %d", recorder.Code, http.StatusCreated)
+	}
+
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the appointment should be soft-deleted and kept: %v", err)
+	}
+}
+
+func TestBookingInvalidatesCachedCalendar(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_calendar_cache.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockPatientUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockPatientUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	// the second read is served from the cache, the booking checks the stored appointments and the last read
+	// computes the calendar again
+	mock.MockDBResults(dbConn,
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withInsertAppointmentResult(sqlmock.NewResult(2, 1)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(2, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 10, 0, 0, 0, time.Local))),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+	)
+
+	calendarURL := fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02"))
+	getCalendar := func() []Entry {
+		req, _ := http.NewRequest("GET", calendarURL, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("calendar response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+		}
+		var entries []Entry
+		if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+			t.Fatalf("calendar response body is invalid: %v", err)
+		}
+		return entries
+	}
+	hasSlot := func(entries []Entry, hour int32) bool {
+		for _, entry := range entries {
+			if entry.Hour == hour {
+				return true
+			}
+		}
+		return false
+	}
+
+	if entries := getCalendar(); !hasSlot(entries, 10) {
+		t.Fatalf("the slot should be available before the booking")
+	}
+	if entries := getCalendar(); !hasSlot(entries, 10) {
+		t.Fatalf("the cached slot should be available before the booking")
+	}
+
+	body, _ := json.Marshal(AppointmentRequest{Hour: 10})
+	req, _ := http.NewRequest("POST", calendarURL, bytes.NewReader(body))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("insert response status is incorrect, got %d, want %d", recorder.Code, http.StatusCreated)
+	}
+
+	if entries := getCalendar(); hasSlot(entries, 10) {
+		t.Errorf("the booked slot should not be served as available")
+	}
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the calendar should be computed again after the booking: %v", err)
+	}
+}
+
+func TestInsertAppointmentIdempotency(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockPatientUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockPatientUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+	router := chi.NewRouter()
+	logger := log.New(emptyWriter{}, "", log.LstdFlags)
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	key := "7c1f6bd2-0c4f-4bd4-9d5e-0e1b3f0e9a11"
+
+	// the second request finds the key stored by the first one, so the appointment is inserted only once
+	mock.MockDBResults(dbConn,
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindIdempotencyKeyResult(key, sqlmock.NewRows([]string{"id", "idempotency_key", "patient_id", "appointment_uuid", "created_at"})),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withFindDoctorByUUIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withInsertAppointmentWithIdempotencyKeyResult(key),
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindIdempotencyKeyResult(key, sqlmock.NewRows([]string{"id", "idempotency_key", "patient_id", "appointment_uuid", "created_at"}).AddRow(1, key, 1, uuid.New(), time.Now())),
+	)
+
+	for i := 0; i < 2; i++ {
+		body, _ := json.Marshal(AppointmentRequest{Hour: 9})
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+		req.Header.Add(IdempotencyKeyHeader, key)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusCreated {
+			t.Errorf("request %d: response status is incorrect, got %d, want %d", i, recorder.Code, http.StatusCreated)
+		}
+	}
+
+	if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("the appointment should be inserted only once: %v", err)
+	}
+}
+
+func TestInsertAppointmentIdempotentRetries(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	key := "7c1f6bd2-0c4f-4bd4-9d5e-0e1b3f0e9a11"
+	patientRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)
+	}
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	noKeyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "idempotency_key", "patient_id", "appointment_uuid", "created_at"})
+	}
+	keyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "idempotency_key", "patient_id", "appointment_uuid", "created_at"}).AddRow(1, key, 1, uuid.New(), time.Now())
+	}
+	tests := []struct {
+		name          string
+		date          time.Time
+		dbMockOptions []mock.DBResultOption
+		want          int
+	}{
+		{
+			name: "should succeed as the original request when retried after the lead time passed",
+			date: tomorrow.AddDate(0, 0, -2),
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				withFindIdempotencyKeyResult(key, keyRows()),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should succeed when a concurrent request with the same key stored it first",
+			date: tomorrow,
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				withFindIdempotencyKeyResult(key, noKeyRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withInsertAppointmentWithIdempotencyKeyConflict(key),
+				withFindIdempotencyKeyResult(key, keyRows()),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should succeed when a concurrent request with the same key took the slot first",
+			date: tomorrow,
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				withFindIdempotencyKeyResult(key, noKeyRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 9, 0, 0, 0, time.Local))),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withFindIdempotencyKeyResult(key, keyRows()),
+			},
+			want: http.StatusCreated,
+		},
+		{
+			name: "should not succeed when the unique violation is not caused by the same key",
+			date: tomorrow,
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				withFindIdempotencyKeyResult(key, noKeyRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+				withInsertAppointmentWithIdempotencyKeyConflict(key),
+				withFindIdempotencyKeyResult(key, noKeyRows()),
+			},
+			want: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			body, _ := json.Marshal(AppointmentRequest{Hour: 9})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tt.date.Format("2006/01/02")), bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+			req.Header.Add(IdempotencyKeyHeader, key)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestPatientContactsAreOnlyShownToTheDoctors(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	appointmentRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 10, 0, 0, 0, time.Local))
+	}
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	tests := []struct {
+		name          string
+		user          *auth.User
+		url           string
+		dbMockOptions []mock.DBResultOption
+		wantEmail     string
+	}{
+		{
+			name: "should show the patient contacts to the doctor",
+			user: mockDoctorUser(),
+			url:  "/api/v1/calendar/2021/08/10",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withListAppointmentsResult(appointmentRows()),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "351123123123", true)),
+			},
+			wantEmail: "patient@hospital.com",
+		},
+		{
+			name: "should not show the patient contacts to the other patients",
+			user: mockPatientUser(),
+			url:  fmt.Sprintf("/api/v1/calendar/%s/2021/08/10", uuid.UUID{}),
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(appointmentRows()),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+			},
+			wantEmail: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return tt.user, nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *tt.user, nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *tt.user)
+
+			router := chi.NewRouter()
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusOK)
+			}
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("response body is invalid: %v", err)
+			}
+			email := ""
+			for _, entry := range entries {
+				if entry.Patient != nil && entry.Patient.Email != "" {
+					email = entry.Patient.Email
+				}
+			}
+			if email != tt.wantEmail {
+				t.Errorf("patient email is incorrect, got %q, want %q", email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+// countAppointmentsCreated gets the number of appointments booked with the doctors of the given specialty, as
+// exported to Prometheus.
+func countAppointmentsCreated(t *testing.T, specialty string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "appointments_created_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "specialty" && label.GetValue() == specialty {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestBookingIncrementsAppointmentsCreated(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	mockAuth := mockAuthorizer{
+		mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+			return mockPatientUser(), nil
+		},
+		mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+			return *mockPatientUser(), nil
+		},
+	}
+	dbConn := mock.MustCreateConnectionMock()
+	tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+	router := chi.NewRouter()
+	Setup(router, logger, mockAuth, config, dbConn)
+
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "oncology", true)
+	}
+	mock.MockDBResults(dbConn,
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		withFindDoctorByUUIDResult(doctorRows()),
+		withFindDoctorByUUIDResult(doctorRows()),
+		withListAppointmentsResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+		withListAppointmentsByPatientAndDateResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"})),
+		withInsertAppointmentResult(sqlmock.NewResult(1, 1)),
+	)
+
+	before := countAppointmentsCreated(t, "oncology")
+
+	body, _ := json.Marshal(AppointmentRequest{Hour: 10})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/api/v1/calendar/%s/%s", uuid.UUID{}, tomorrow.Format("2006/01/02")), bytes.NewReader(body))
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("response status is incorrect, got %d, want %d", recorder.Code, http.StatusCreated)
+	}
+	if got := countAppointmentsCreated(t, "oncology") - before; got != 1 {
+		t.Errorf("appointments created counter increment is incorrect, got %v, want %v", got, 1)
+	}
+}
+
+func TestListBlockPeriods(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	blockerColumns := []string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"}
+	tests := []struct {
+		name          string
+		query         string
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantBlockers  []time.Time
+	}{
+		{
+			name:  "should list the blockers partially overlapping the range boundaries",
+			query: "?from=2021-08-10&to=2021-08-31",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				func(dbConn mock.Connection) {
+					rows := sqlmock.NewRows(blockerColumns).
+						AddRow(1, uuid.UUID{}, 1, time.Date(2021, 8, 9, 15, 0, 0, 0, time.UTC), time.Date(2021, 8, 10, 10, 0, 0, 0, time.UTC), "").
+						AddRow(2, uuid.UUID{}, 1, time.Date(2021, 8, 20, 9, 0, 0, 0, time.UTC), time.Date(2021, 8, 20, 12, 0, 0, 0, time.UTC), "").
+						AddRow(3, uuid.UUID{}, 1, time.Date(2021, 8, 31, 16, 0, 0, 0, time.UTC), time.Date(2021, 9, 2, 10, 0, 0, 0, time.UTC), "")
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listBlockersQuery)).WithArgs(int64(1), time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC), time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)).WillReturnRows(rows)
+				},
+			},
+			want: http.StatusOK,
+			wantBlockers: []time.Time{
+				time.Date(2021, 8, 9, 15, 0, 0, 0, time.UTC),
+				time.Date(2021, 8, 20, 9, 0, 0, 0, time.UTC),
+				time.Date(2021, 8, 31, 16, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "should list no blockers when none overlaps the range",
+			query: "?from=2021-08-10&to=2021-08-10",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withListBlockersResult(sqlmock.NewRows(blockerColumns)),
+			},
+			want:         http.StatusOK,
+			wantBlockers: []time.Time{},
+		},
+		{
+			name:  "should not list the blockers when the range ends before it starts",
+			query: "?from=2021-08-31&to=2021-08-10",
+			want:  http.StatusBadRequest,
+		},
+		{
+			name:  "should not list the blockers when the range is too long",
+			query: "?from=2021-08-10&to=2021-12-31",
+			want:  http.StatusBadRequest,
+		},
+		{
+			name:  "should not list the blockers without a range",
+			query: "",
+			want:  http.StatusBadRequest,
+		},
+		{
+			name:  "should not list the blockers of an user who is not a doctor",
+			query: "?from=2021-08-10&to=2021-08-31",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", "/api/v1/calendar/blockers"+tt.query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantBlockers == nil {
+				return
+			}
+			var blockers []BlockPeriod
+			if err := json.NewDecoder(recorder.Body).Decode(&blockers); err != nil {
+				t.Fatal(err)
+			}
+			if len(blockers) != len(tt.wantBlockers) {
+				t.Fatalf("got %d blockers, want %d", len(blockers), len(tt.wantBlockers))
+			}
+			for i, blocker := range blockers {
+				if !blocker.StartDate.Equal(tt.wantBlockers[i]) {
+					t.Errorf("blocker %d starts at %s, want %s", i, blocker.StartDate, tt.wantBlockers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUpdatePatientContact(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	patientRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)
+	}
+	tests := []struct {
+		name          string
+		request       PatientContactRequest
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantField     string
+	}{
+		{
+			name:    "should update the contact details, leaving the login email untouched",
+			request: PatientContactRequest{Email: " New.Patient@Hospital.com ", MobilePhone: "5551234567"},
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				// only tb_patient is updated, any query to tb_user would fail the expectations
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updatePatientQuery)).WithArgs("new.patient@hospital.com", "5551234567", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				},
+			},
+			want: http.StatusOK,
+		},
+		{
+			name:      "should not update the contact details with an invalid email",
+			request:   PatientContactRequest{Email: "not an email", MobilePhone: "5551234567"},
+			want:      http.StatusBadRequest,
+			wantField: "email",
+		},
+		{
+			name:      "should not update the contact details without an email",
+			request:   PatientContactRequest{MobilePhone: "5551234567"},
+			want:      http.StatusBadRequest,
+			wantField: "email",
+		},
+		{
+			name:      "should not update the contact details with a too long mobile phone",
+			request:   PatientContactRequest{Email: "patient@hospital.com", MobilePhone: "5551234567890"},
+			want:      http.StatusBadRequest,
+			wantField: "mobile_phone",
+		},
+		{
+			name:    "should not update the contact details with an email used by another patient",
+			request: PatientContactRequest{Email: "other@hospital.com"},
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(patientRows()),
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectExec(regexp.QuoteMeta(updatePatientQuery)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(&pq.Error{Code: "23505"})
+				},
+			},
+			want: http.StatusConflict,
+		},
+		{
+			name:    "should not update the contact details of an user who is not a patient",
+			request: PatientContactRequest{Email: "patient@hospital.com"},
+			dbMockOptions: []mock.DBResultOption{
+				withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"})),
+			},
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockPatientUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockPatientUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockPatientUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			body, _ := json.Marshal(tt.request)
+			req, _ := http.NewRequest("PUT", "/api/v1/patients/me", bytes.NewReader(body))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantField != "" {
+				validationErr := apierrors.ValidationError{}
+				if err := json.NewDecoder(recorder.Body).Decode(&validationErr); err != nil {
+					t.Fatal(err)
+				}
+				if validationErr.Field != tt.wantField {
+					t.Errorf("invalid field is incorrect, got %s, want %s", validationErr.Field, tt.wantField)
+				}
+			}
+			if tt.want == http.StatusOK {
+				patient := Patient{}
+				if err := json.NewDecoder(recorder.Body).Decode(&patient); err != nil {
+					t.Fatal(err)
+				}
+				if want := strings.ToLower(strings.TrimSpace(tt.request.Email)); patient.Email != want {
+					t.Errorf("email is incorrect, got %s, want %s", patient.Email, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMonthAppointments(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	appointmentColumns := []string{"id", "uuid", "doctor_id", "patient_id", "date", "notes", "status", "duration", "version"}
+	tests := []struct {
+		name          string
+		path          string
+		dbMockOptions []mock.DBResultOption
+		want          int
+		wantDays      map[string]int
+	}{
+		{
+			name: "should list the appointments spread across several days, grouped by day",
+			path: "/api/v1/calendar/2021/08",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				func(dbConn mock.Connection) {
+					rows := sqlmock.NewRows(appointmentColumns).
+						AddRow(1, uuid.New(), 1, 1, time.Date(2021, 8, 2, 9, 0, 0, 0, time.UTC), nil, "COMPLETED", 1, 2).
+						AddRow(2, uuid.New(), 1, 2, time.Date(2021, 8, 2, 14, 0, 0, 0, time.UTC), nil, "COMPLETED", 1, 2).
+						AddRow(3, uuid.New(), 1, 1, time.Date(2021, 8, 17, 10, 0, 0, 0, time.UTC), nil, "CONFIRMED", 2, 2).
+						AddRow(4, uuid.New(), 1, 2, time.Date(2021, 8, 31, 17, 0, 0, 0, time.UTC), nil, "REQUESTED", 1, 1)
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listMonthAppointmentsQuery)).WithArgs(int64(1), time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)).WillReturnRows(rows)
+				},
+				withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).
+					AddRow(1, uuid.New(), 2, "Patient", "patient@hospital.com", "", true).
+					AddRow(2, uuid.New(), 3, "Another Patient", "another@hospital.com", "", true)),
+			},
+			want:     http.StatusOK,
+			wantDays: map[string]int{"2021-08-02": 2, "2021-08-17": 1, "2021-08-31": 1},
+		},
+		{
+			name: "should list no days for a month without appointments",
+			path: "/api/v1/calendar/2021/02",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				func(dbConn mock.Connection) {
+					dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(listMonthAppointmentsQuery)).WithArgs(int64(1), time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)).WillReturnRows(sqlmock.NewRows(appointmentColumns))
+				},
+			},
+			want:     http.StatusOK,
+			wantDays: map[string]int{},
+		},
+		{
+			name: "should not list the appointments of an invalid month",
+			path: "/api/v1/calendar/2021/13",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not list the appointments of month zero",
+			path: "/api/v1/calendar/2021/0",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not list the appointments of an invalid year",
+			path: "/api/v1/calendar/21/08",
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "should not list the appointments of an user who is not a doctor",
+			path: "/api/v1/calendar/2021/08",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"})),
+			},
+			want: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			dbConn := mock.MustCreateConnectionMock()
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Fatalf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.wantDays == nil {
+				return
+			}
+			var days []DayAppointments
+			if err := json.NewDecoder(recorder.Body).Decode(&days); err != nil {
+				t.Fatal(err)
+			}
+			if len(days) != len(tt.wantDays) {
+				t.Fatalf("got %d days, want %d", len(days), len(tt.wantDays))
+			}
+			for i, day := range days {
+				if i > 0 && day.Date <= days[i-1].Date {
+					t.Errorf("days are not ordered, got %s after %s", day.Date, days[i-1].Date)
+				}
+				if len(day.Appointments) != tt.wantDays[day.Date] {
+					t.Errorf("appointments of %s are incorrect, got %d, want %d", day.Date, len(day.Appointments), tt.wantDays[day.Date])
+				}
+				for _, appointment := range day.Appointments {
+					if appointment.Patient == nil {
+						t.Errorf("appointment %s of %s has no patient", appointment.UUID, day.Date)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAppointmentStatusNoShowInClinicTimezone(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_timezone.json")
+	// the dates are stored as the clinic wall clock, read back as UTC
+	clinicNow := time.Now().In(config.Location())
+	storedNow := time.Date(clinicNow.Year(), clinicNow.Month(), clinicNow.Day(), clinicNow.Hour(), clinicNow.Minute(), 0, 0, time.UTC)
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "Cardiology", true)
+	}
+	appointmentRows := func(date time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date", "status", "duration", "version"}).AddRow(1, uuid.UUID{}, 1, 1, date, "CONFIRMED", 1, 1)
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		want          int
+	}{
+		{
+			name: "should mark the appointment which ended in the clinic timezone as no-show",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(storedNow.Add(-90 * time.Minute))),
+				withUpdateAppointmentStatusResult(sqlmock.NewResult(0, 1)),
+			},
+			want: http.StatusNoContent,
+		},
+		{
+			name: "should not mark the appointment still going on in the clinic timezone as no-show",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withFindAppointmentByUUIDResult(appointmentRows(storedNow.Add(-30 * time.Minute))),
+			},
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			logger := log.New(emptyWriter{}, "", log.LstdFlags)
+			dbConn := mock.MustCreateConnectionMock()
+			mockAuth := mockAuthorizer{
+				mockValidateToken: func(ctx context.Context, token string) (*auth.User, error) {
+					return mockDoctorUser(), nil
+				},
+				mockGetAuthenticatedUser: func(ctx context.Context) (auth.User, error) {
+					return *mockDoctorUser(), nil
+				},
+			}
+			Setup(router, logger, mockAuth, config, dbConn)
+
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			tokens := auth.MustGenerateTokens(context.TODO(), config.PrivateKey(), *mockDoctorUser())
+			req, _ := http.NewRequest("PUT", fmt.Sprintf("/api/v1/calendar/appointments/%s/status", uuid.UUID{}), strings.NewReader(`{"status": "NO_SHOW", "version": 1}`))
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokens.AccessToken))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+			if err := dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("database expectations were not met: %v", err)
+			}
+		})
+	}
+}
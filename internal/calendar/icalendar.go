@@ -0,0 +1,73 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICalendarContentType is the media type of the calendars served in the iCalendar format.
+const ICalendarContentType = "text/calendar; charset=utf-8"
+
+const (
+	icalendarTimeLayout = "20060102T150405Z"
+	// icalendarLineLength is the maximum length of a content line, in octets, after which the line is folded.
+	icalendarLineLength = 75
+)
+
+var icalendarEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// foldLine splits the given content line into lines of up to 75 octets, continued by a leading space, without
+// breaking UTF-8 sequences.
+func foldLine(line string) string {
+	var builder strings.Builder
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > icalendarLineLength {
+			builder.WriteString("\r\n ")
+			length = 1
+		}
+		builder.WriteRune(r)
+		length += size
+	}
+	builder.WriteString("\r\n")
+	return builder.String()
+}
+
+// WriteICalendar writes the booked entries of the given day as an iCalendar feed, with an event for each
+// appointment, so the doctors can subscribe to their schedule from their calendar applications.
+func WriteICalendar(w io.Writer, date time.Time, entries []Entry, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//hospital-booking//calendar//EN",
+		"CALSCALE:GREGORIAN",
+	}
+	for _, entry := range entries {
+		if entry.Patient == nil {
+			continue
+		}
+		start := time.Date(date.Year(), date.Month(), date.Day(), int(entry.Hour), 0, 0, 0, date.Location()).UTC()
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%s-%s@hospital-booking", start.Format(icalendarTimeLayout), entry.Patient.UUID),
+			"DTSTAMP:"+now.UTC().Format(icalendarTimeLayout),
+			"DTSTART:"+start.Format(icalendarTimeLayout),
+			"DTEND:"+start.Add(slotDuration).Format(icalendarTimeLayout),
+			"SUMMARY:"+icalendarEscaper.Replace("Appointment with "+entry.Patient.Name),
+		)
+		if entry.Notes != nil {
+			lines = append(lines, "DESCRIPTION:"+icalendarEscaper.Replace(*entry.Notes))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldLine(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
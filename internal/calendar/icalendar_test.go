@@ -0,0 +1,88 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestWriteICalendar(t *testing.T) {
+	date := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2021, 8, 9, 12, 0, 0, 0, time.UTC)
+	notes := "Headache; fever, since monday\nand cough"
+	longName := strings.Repeat("a", 80)
+	tests := []struct {
+		name       string
+		entries    []Entry
+		wantEvents int
+		wantLines  []string
+	}{
+		{
+			name:       "should write an empty calendar when there are no appointments",
+			entries:    []Entry{{Hour: 9, Available: true}},
+			wantEvents: 0,
+			wantLines:  []string{"BEGIN:VCALENDAR", "VERSION:2.0", "END:VCALENDAR"},
+		},
+		{
+			name: "should write an event per appointment, skipping the available slots",
+			entries: []Entry{
+				{Hour: 9, Available: true},
+				{Hour: 10, Patient: &Patient{UUID: uuid.UUID{}, Name: "Jane Roe"}},
+				{Hour: 11, Patient: &Patient{UUID: uuid.UUID{}, Name: "Richard Roe"}},
+			},
+			wantEvents: 2,
+			wantLines: []string{
+				"UID:20210810T100000Z-00000000-0000-0000-0000-000000000000@hospital-booking",
+				"DTSTAMP:20210809T120000Z",
+				"DTSTART:20210810T100000Z",
+				"DTEND:20210810T110000Z",
+				"SUMMARY:Appointment with Jane Roe",
+				"SUMMARY:Appointment with Richard Roe",
+			},
+		},
+		{
+			name:       "should escape the notes",
+			entries:    []Entry{{Hour: 10, Patient: &Patient{Name: "Jane Roe"}, Notes: &notes}},
+			wantEvents: 1,
+			wantLines:  []string{`DESCRIPTION:Headache\; fever\, since monday\nand cough`},
+		},
+		{
+			name:       "should fold the lines longer than 75 octets",
+			entries:    []Entry{{Hour: 10, Patient: &Patient{Name: longName}}},
+			wantEvents: 1,
+			wantLines:  []string{"SUMMARY:Appointment with " + longName[:50], " " + longName[50:]},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			buffer := &bytes.Buffer{}
+			if err := WriteICalendar(buffer, date, tt.entries, now); err != nil {
+				t.Fatalf("WriteICalendar() error = %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n")
+			if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+				t.Errorf("calendar is incorrect, got %v", lines)
+			}
+			if got := strings.Count(buffer.String(), "BEGIN:VEVENT\r\n"); got != tt.wantEvents {
+				t.Errorf("events are incorrect, got %d, want %d", got, tt.wantEvents)
+			}
+			for _, want := range tt.wantLines {
+				found := false
+				for _, line := range lines {
+					if len(line) > icalendarLineLength {
+						t.Errorf("line is longer than %d octets: %s", icalendarLineLength, line)
+					}
+					found = found || line == want
+				}
+				if !found {
+					t.Errorf("calendar should contain the line %q, got %v", want, lines)
+				}
+			}
+		})
+	}
+}
@@ -2,20 +2,52 @@ package calendar
 
 import (
 	"hospital-booking/internal/apierrors"
+	"net/mail"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+// Patient is a patient of the clinic. Patients can only book once they consent to the processing of their data.
 type Patient struct {
-	ID          int64     `json:"-" dbfield:"id"`
-	UserID      int64     `json:"-" dbfield:"user_id"`
-	UUID        uuid.UUID `json:"uuid" dbfield:"uuid"`
-	Name        string    `json:"name" dbfield:"name"`
-	Email       string    `json:"email" dbfield:"email"`
-	MobilePhone string    `json:"mobile_phone" dbfield:"mobile_phone"`
+	ID           int64     `json:"-" dbfield:"id"`
+	UserID       int64     `json:"-" dbfield:"user_id"`
+	UUID         uuid.UUID `json:"uuid" dbfield:"uuid"`
+	Name         string    `json:"name" dbfield:"name"`
+	Email        string    `json:"email" dbfield:"email"`
+	MobilePhone  string    `json:"mobile_phone" dbfield:"mobile_phone"`
+	ConsentGiven bool      `json:"-" dbfield:"consent_given"`
+}
+
+// Sanitize zeroes out the patient contact details, so they are never disclosed to other patients.
+func (p *Patient) Sanitize() {
+	p.Email = ""
+	p.MobilePhone = ""
+}
+
+const maxMobilePhoneLength = 12
+
+// PatientContactRequest holds the contact details a patient keeps up to date.
+type PatientContactRequest struct {
+	Email       string `json:"email"`
+	MobilePhone string `json:"mobile_phone"`
 }
 
+// Validate validates if the contact details given are valid.
+func (p PatientContactRequest) Validate() error {
+	if _, err := ParseEmail(p.Email); err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(p.MobilePhone)) > maxMobilePhoneLength {
+		return apierrors.NewValidationError("mobile_phone", "max")
+	}
+	return nil
+}
+
+// Doctor is a doctor of the clinic. Doctors who left the clinic are kept inactive, hidden from the patients.
 type Doctor struct {
 	ID          int64     `json:"-" dbfield:"id"`
 	UserID      int64     `json:"-" dbfield:"user_id"`
@@ -24,6 +56,171 @@ type Doctor struct {
 	Email       string    `json:"email" dbfield:"email"`
 	MobilePhone string    `json:"mobile_phone" dbfield:"mobile_phone"`
 	Specialty   string    `json:"specialty" dbfield:"specialty"`
+	Active      bool      `json:"active" dbfield:"active"`
+}
+
+// Summary returns the compact representation of the doctor, exposed to the patients.
+func (d Doctor) Summary() DoctorSummary {
+	return DoctorSummary{
+		UUID:      d.UUID,
+		Name:      d.Name,
+		Specialty: d.Specialty,
+	}
+}
+
+// ParseEmail parses the given email address, ignoring the surrounding spaces and its case.
+func ParseEmail(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return "", apierrors.NewValidationError("email", "required")
+	}
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return "", apierrors.NewValidationError("email", "email")
+	}
+	return value, nil
+}
+
+// Specialty is one of the medical specialties of the clinic, always lowercase.
+type Specialty string
+
+// KnownSpecialties are the specialties the doctors can have.
+var KnownSpecialties = []Specialty{
+	"cardiology",
+	"dermatology",
+	"gynecology",
+	"neurology",
+	"oncology",
+	"ophthalmology",
+	"orthopedics",
+	"pediatrics",
+	"psychiatry",
+}
+
+// ParseSpecialty normalizes the given value, trimming and lowering its case, into one of the KnownSpecialties.
+// Unknown specialties are rejected.
+func ParseSpecialty(value string) (Specialty, error) {
+	specialty := Specialty(strings.ToLower(strings.TrimSpace(value)))
+	for _, known := range KnownSpecialties {
+		if specialty == known {
+			return specialty, nil
+		}
+	}
+	names := make([]string, 0, len(KnownSpecialties))
+	for _, known := range KnownSpecialties {
+		names = append(names, string(known))
+	}
+	return "", apierrors.NewValidationError("specialty", "oneof "+strings.Join(names, " "))
+}
+
+type DoctorSummary struct {
+	UUID      uuid.UUID `json:"uuid"`
+	Name      string    `json:"name"`
+	Specialty string    `json:"specialty"`
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Pagination determines which page of a listing is returned, counting the pages from 1.
+type Pagination struct {
+	Page int
+	Size int
+}
+
+// Offset returns how many items come before the page.
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.Size
+}
+
+// ParsePagination parses the given page and size, defaulting to the first page of 20 items. Sizes larger than
+// 100 are capped at 100.
+func ParsePagination(page string, size string) (Pagination, error) {
+	pagination := Pagination{Page: 1, Size: defaultPageSize}
+	if page != "" {
+		parsedPage, err := strconv.Atoi(page)
+		if err != nil || parsedPage < 1 {
+			return Pagination{}, apierrors.NewValidationError("page", "min 1")
+		}
+		pagination.Page = parsedPage
+	}
+	if size != "" {
+		parsedSize, err := strconv.Atoi(size)
+		if err != nil || parsedSize < 1 {
+			return Pagination{}, apierrors.NewValidationError("size", "min 1")
+		}
+		pagination.Size = parsedSize
+	}
+	if pagination.Size > maxPageSize {
+		pagination.Size = maxPageSize
+	}
+	return pagination, nil
+}
+
+// DoctorPage is a page of the doctors listing, along with the total of doctors to build the pagination.
+type DoctorPage struct {
+	Items []*Doctor `json:"items"`
+	Total int64     `json:"total"`
+	Page  int       `json:"page"`
+	Size  int       `json:"size"`
+}
+
+const (
+	// dateLayout is the layout of the dates sent by the clients, in the clinic timezone.
+	dateLayout = "2006-01-02"
+
+	// maxRangeDays limits the days of a date range, so the availability of a doctor is checked for about two months.
+	maxRangeDays = 60
+)
+
+// DateRange is a range of days, including both its first and last days.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Days returns how many days are in the range.
+func (r DateRange) Days() int {
+	return int(r.To.Sub(r.From).Hours()/24+0.5) + 1
+}
+
+// ParseDateRange parses the given dates, e.g. 2021-08-10, into a DateRange in the given location. Ranges of
+// more than 60 days are rejected.
+func ParseDateRange(from string, to string, location *time.Location) (DateRange, error) {
+	var errs apierrors.ValidationErrors
+	fromDate, err := time.ParseInLocation(dateLayout, from, location)
+	if err != nil {
+		errs.Add("from", "date e.g. 2021-08-10")
+	}
+	toDate, err := time.ParseInLocation(dateLayout, to, location)
+	if err != nil {
+		errs.Add("to", "date e.g. 2021-08-10")
+	}
+	if len(errs) > 0 {
+		return DateRange{}, errs
+	}
+	dateRange := DateRange{From: fromDate, To: toDate}
+	if toDate.Before(fromDate) {
+		return DateRange{}, apierrors.NewValidationError("to", "invalid period")
+	}
+	if dateRange.Days() > maxRangeDays {
+		return DateRange{}, apierrors.NewValidationError("to", "max 60 days")
+	}
+	return dateRange, nil
+}
+
+// DayAvailability tells whether there is any available slot on a day.
+type DayAvailability struct {
+	Date      string `json:"date"`
+	Available bool   `json:"available"`
+}
+
+// DayAppointments holds the appointments of a day, e.g. when listing a whole month.
+type DayAppointments struct {
+	Date         string         `json:"date"`
+	Appointments []*Appointment `json:"appointments"`
 }
 
 type BlockPeriod struct {
@@ -34,36 +231,233 @@ type BlockPeriod struct {
 	StartDate   time.Time `json:"start_date,omitempty" dbfield:"start_date"`
 	EndDate     time.Time `json:"end_date,omitempty" dbfield:"end_date"`
 	Description *string   `json:"description" dbfield:"description"`
+	Inclusive   bool      `json:"inclusive" dbfield:"inclusive"`
+}
+
+// blockedUntil returns when the period taken by the blocker ends. Blockers are half-open by default, so the slot
+// starting at their end date stays available, while inclusive ones also take that slot.
+func (b BlockPeriod) blockedUntil() time.Time {
+	if b.Inclusive {
+		return b.EndDate.Add(time.Hour)
+	}
+	return b.EndDate
 }
 
-// Validate validates if the block period is valid.
+// Validate validates if the block period is valid, returning the errors of all the fields at once.
 func (b BlockPeriod) Validate() error {
+	var errs apierrors.ValidationErrors
 	if b.StartDate.IsZero() {
-		return apierrors.NewValidationError("start_date", "required")
+		errs.Add("start_date", "required")
 	}
 	if b.EndDate.IsZero() {
+		errs.Add("end_date", "required")
+	}
+	if len(errs) == 0 && b.EndDate.Before(b.StartDate) {
+		errs.Add("end_date", "invalid period")
+	}
+	return errs.ErrorOrNil()
+}
+
+// AvailabilityException adds working hours to the doctor's calendar on a day which is normally off, e.g. an extra
+// Saturday. The blockers still apply to the hours it adds.
+type AvailabilityException struct {
+	ID          int64     `json:"-" dbfield:"id"`
+	UUID        uuid.UUID `json:"uuid,omitempty" dbfield:"uuid"`
+	DoctorID    int64     `json:"-" dbfield:"doctor_id"`
+	Doctor      *Doctor   `json:"-"`
+	StartDate   time.Time `json:"start_date,omitempty" dbfield:"start_date"`
+	EndDate     time.Time `json:"end_date,omitempty" dbfield:"end_date"`
+	Description *string   `json:"description" dbfield:"description"`
+}
+
+// Validate validates if the availability exception is valid, returning the errors of all the fields at once. The
+// exception must start and end on the same day.
+func (a AvailabilityException) Validate() error {
+	var errs apierrors.ValidationErrors
+	if a.StartDate.IsZero() {
+		errs.Add("start_date", "required")
+	}
+	if a.EndDate.IsZero() {
+		errs.Add("end_date", "required")
+	}
+	if len(errs) == 0 && !a.EndDate.After(a.StartDate) {
+		errs.Add("end_date", "invalid period")
+	}
+	if len(errs) == 0 {
+		nextDay := time.Date(a.StartDate.Year(), a.StartDate.Month(), a.StartDate.Day()+1, 0, 0, 0, 0, a.StartDate.Location())
+		if a.EndDate.After(nextDay) {
+			errs.Add("end_date", "same day as start_date")
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// BlockerResult is the result of a block period creation, along with the appointments that fall inside it, so
+// the doctor can reschedule them.
+type BlockerResult struct {
+	UUID                     uuid.UUID   `json:"uuid"`
+	AffectedAppointments     int         `json:"affected_appointments"`
+	AffectedAppointmentUUIDs []uuid.UUID `json:"affected_appointment_uuids"`
+}
+
+// RecurringBlockPeriodRequest represents a block period repeated every week, on the same weekday and hours,
+// until the given end date.
+type RecurringBlockPeriodRequest struct {
+	Weekday     string    `json:"weekday"`
+	StartHour   int32     `json:"start_hour"`
+	EndHour     int32     `json:"end_hour"`
+	EndDate     time.Time `json:"end_date"`
+	Description *string   `json:"description"`
+	Inclusive   bool      `json:"inclusive"`
+}
+
+// ParseWeekday parses the given weekday name, ignoring case.
+func ParseWeekday(value string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), strings.TrimSpace(value)) {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// Validate validates if the recurring block period is valid.
+func (r RecurringBlockPeriodRequest) Validate() error {
+	if _, ok := ParseWeekday(r.Weekday); !ok {
+		return apierrors.NewValidationError("weekday", "oneof sunday monday tuesday wednesday thursday friday saturday")
+	}
+	if r.StartHour < 0 || r.StartHour > 23 {
+		return apierrors.NewValidationError("start_hour", "invalid hour")
+	}
+	if r.EndHour <= r.StartHour || r.EndHour > 24 {
+		return apierrors.NewValidationError("end_hour", "invalid period")
+	}
+	if r.EndDate.IsZero() {
 		return apierrors.NewValidationError("end_date", "required")
 	}
-	if b.EndDate.Before(b.StartDate) {
-		return apierrors.NewValidationError("end_date", "invalid period")
+	now := time.Now().In(r.EndDate.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endDay := time.Date(r.EndDate.Year(), r.EndDate.Month(), r.EndDate.Day(), 0, 0, 0, 0, r.EndDate.Location())
+	if !endDay.After(today) {
+		return apierrors.NewValidationError("end_date", "must be after today")
 	}
 	return nil
 }
 
 type Appointment struct {
-	ID        int64     `json:"-" dbfield:"id"`
-	UUID      uuid.UUID `json:"uuid" dbfield:"uuid"`
-	Doctor    *Doctor   `json:"doctor"`
-	DoctorID  int64     `json:"-" dbfield:"doctor_id"`
-	Patient   *Patient  `json:"patient"`
-	PatientID int64     `json:"-" dbfield:"patient_id"`
-	Date      time.Time `json:"date" dbfield:"date"`
+	ID        int64             `json:"-" dbfield:"id"`
+	UUID      uuid.UUID         `json:"uuid" dbfield:"uuid"`
+	Doctor    *Doctor           `json:"doctor"`
+	DoctorID  int64             `json:"-" dbfield:"doctor_id"`
+	Patient   *Patient          `json:"patient"`
+	PatientID int64             `json:"-" dbfield:"patient_id"`
+	Date      time.Time         `json:"date" dbfield:"date"`
+	Notes     *string           `json:"notes,omitempty" dbfield:"notes"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty" dbfield:"deleted_at"`
+	Status    AppointmentStatus `json:"status,omitempty" dbfield:"status"`
+	Duration  int32             `json:"duration,omitempty" dbfield:"duration"`
+	Version   int32             `json:"version,omitempty" dbfield:"version"`
+}
+
+// Slots gets the number of slots taken by the appointment, which is at least one.
+func (a Appointment) Slots() int32 {
+	if a.Duration < 1 {
+		return 1
+	}
+	return a.Duration
+}
+
+// AppointmentStatus is the stage of an appointment. Appointments are requested by the patients, then confirmed and
+// completed by the doctors, unless they are cancelled first or the patient doesn't show up.
+type AppointmentStatus string
+
+const (
+	AppointmentRequested AppointmentStatus = "REQUESTED"
+	AppointmentConfirmed AppointmentStatus = "CONFIRMED"
+	AppointmentCompleted AppointmentStatus = "COMPLETED"
+	AppointmentCancelled AppointmentStatus = "CANCELLED"
+	AppointmentNoShow    AppointmentStatus = "NO_SHOW"
+)
+
+// appointmentTransitions are the statuses each status can move to. Completed, cancelled and no-show appointments are
+// final.
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	AppointmentRequested: {AppointmentConfirmed, AppointmentCancelled},
+	AppointmentConfirmed: {AppointmentCompleted, AppointmentCancelled, AppointmentNoShow},
+}
+
+// CanMoveTo tells whether an appointment can move from the status to the given one.
+func (s AppointmentStatus) CanMoveTo(status AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AppointmentStatusRequest holds the status an appointment must move to.
+type AppointmentStatusRequest struct {
+	Status string `json:"status"`
+
+	// Version is the version of the appointment the client expects to update, so concurrent updates are detected.
+	Version int32 `json:"version"`
+}
+
+// Validate validates if the status given is one of the known ones, and if the expected version is given.
+func (a AppointmentStatusRequest) Validate() error {
+	if a.Version < 1 {
+		return apierrors.NewValidationError("version", "required")
+	}
+	switch AppointmentStatus(a.Status) {
+	case "":
+		return apierrors.NewValidationError("status", "required")
+	case AppointmentRequested, AppointmentConfirmed, AppointmentCompleted, AppointmentCancelled, AppointmentNoShow:
+		return nil
+	}
+	return apierrors.NewValidationError("status", "oneof REQUESTED CONFIRMED COMPLETED CANCELLED NO_SHOW")
+}
+
+// ParseAppointmentStatusFilter parses the given value, ignoring case, into the AppointmentStatus the appointments
+// must have. An empty status is returned when no value is given, meaning the appointments are not filtered.
+func ParseAppointmentStatusFilter(value string) (AppointmentStatus, error) {
+	switch status := AppointmentStatus(strings.ToUpper(value)); status {
+	case "", AppointmentRequested, AppointmentConfirmed, AppointmentCompleted, AppointmentNoShow:
+		return status, nil
+	}
+	return "", apierrors.NewValidationError("status", "oneof requested confirmed completed no_show")
 }
 
 type AppointmentRequest struct {
-	Hour       int32 `json:"hour"`
-	DoctorUUID uuid.UUID
-	Date       time.Time
+	Hour  int32   `json:"hour"`
+	Notes *string `json:"notes"`
+	// Duration is the number of slots to book, starting at the given hour. When omitted, a single slot is booked.
+	Duration int32 `json:"duration"`
+	// the fields below come from the URL and headers, so they are rejected in the body
+	DoctorUUID     uuid.UUID `json:"-"`
+	Date           time.Time `json:"-"`
+	IdempotencyKey string    `json:"-"`
+}
+
+type IdempotencyKey struct {
+	ID              int64     `dbfield:"id"`
+	Key             string    `dbfield:"idempotency_key"`
+	PatientID       int64     `dbfield:"patient_id"`
+	AppointmentUUID uuid.UUID `dbfield:"appointment_uuid"`
+	CreatedAt       time.Time `dbfield:"created_at"`
+}
+
+// StartsAt combines the requested date and hour into the appointment time, in the location of the date.
+func (a AppointmentRequest) StartsAt() time.Time {
+	return time.Date(a.Date.Year(), a.Date.Month(), a.Date.Day(), int(a.Hour), 0, 0, 0, a.Date.Location())
+}
+
+// Slots gets the number of slots to book, which is a single one when the duration is omitted.
+func (a AppointmentRequest) Slots() int32 {
+	if a.Duration == 0 {
+		return 1
+	}
+	return a.Duration
 }
 
 // Validate checks if the given request is valid.
@@ -71,9 +465,21 @@ func (a AppointmentRequest) Validate() error {
 	if !(a.Hour >= startWorkHour && a.Hour <= endWorkHour) {
 		return apierrors.NewValidationError("hour", "out of working hours")
 	}
+	if a.Duration < 0 {
+		return apierrors.NewValidationError("duration", "min")
+	}
+	if a.Hour+a.Slots()-1 > endWorkHour {
+		return apierrors.NewValidationError("duration", "out of working hours")
+	}
 	if a.Date.IsZero() {
 		return apierrors.NewValidationError("date", "required")
 	}
+	if a.Notes != nil && utf8.RuneCountInString(*a.Notes) > maxNotesLength {
+		return apierrors.NewValidationError("notes", "max")
+	}
+	if len(a.IdempotencyKey) > maxIdempotencyKeyLength {
+		return apierrors.NewValidationError(IdempotencyKeyHeader, "max")
+	}
 	return nil
 }
 
@@ -81,4 +487,54 @@ type Entry struct {
 	Hour      int32    `json:"hour"`
 	Available bool     `json:"available"`
 	Patient   *Patient `json:"patient,omitempty"`
+	Notes     *string  `json:"notes,omitempty"`
+}
+
+// Sanitize returns a copy of the entry without the contact details of its patient, if any. The patient is copied
+// as well, since the entries may share it with the cached calendars.
+func (e Entry) Sanitize() Entry {
+	if e.Patient != nil {
+		patient := *e.Patient
+		patient.Sanitize()
+		e.Patient = &patient
+	}
+	return e
+}
+
+// EntryFilter filters the calendar entries.
+type EntryFilter string
+
+const (
+	AllEntries       EntryFilter = "all"
+	AvailableEntries EntryFilter = "available"
+	BookedEntries    EntryFilter = "booked"
+)
+
+// ParseEntryFilter parses the given value into an EntryFilter, defaulting to AllEntries when it is empty.
+func ParseEntryFilter(value string) (EntryFilter, error) {
+	switch filter := EntryFilter(value); filter {
+	case "":
+		return AllEntries, nil
+	case AllEntries, AvailableEntries, BookedEntries:
+		return filter, nil
+	default:
+		return "", apierrors.NewValidationError("only", "oneof available booked all")
+	}
+}
+
+// Match checks if the given entry satisfies the filter.
+func (f EntryFilter) Match(entry Entry) bool {
+	switch f {
+	case AvailableEntries:
+		return entry.Available
+	case BookedEntries:
+		return entry.Patient != nil
+	default:
+		return true
+	}
+}
+
+type DoctorCalendar struct {
+	Doctor  DoctorSummary `json:"doctor"`
+	Entries []Entry       `json:"entries"`
 }
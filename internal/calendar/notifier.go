@@ -0,0 +1,56 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Notifier notifies external services about the calendar events.
+type Notifier interface {
+
+	// AppointmentCreated notifies that the given appointment was created.
+	AppointmentCreated(ctx context.Context, appointment Appointment) error
+}
+
+// noopNotifier is used when no external service should be notified.
+type noopNotifier struct{}
+
+func (n noopNotifier) AppointmentCreated(ctx context.Context, appointment Appointment) error {
+	return nil
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a Notifier that posts the events as JSON to the given URL, using the given client.
+func NewWebhookNotifier(url string, client *http.Client) Notifier {
+	return &webhookNotifier{url: url, client: client}
+}
+
+func (w webhookNotifier) AppointmentCreated(ctx context.Context, appointment Appointment) error {
+	body, err := json.Marshal(appointment)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
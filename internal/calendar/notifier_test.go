@@ -0,0 +1,54 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestWebhookNotifierAppointmentCreated(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			name:       "should post the appointment to the webhook",
+			statusCode: http.StatusOK,
+			wantErr:    false,
+		},
+		{
+			name:       "should return an error when the webhook fails",
+			statusCode: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			appointment := Appointment{UUID: uuid.New()}
+			var received Appointment
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("request method is incorrect, got %s, want %s", r.Method, http.MethodPost)
+				}
+				_ = json.NewDecoder(r.Body).Decode(&received)
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			err := NewWebhookNotifier(server.URL, server.Client()).AppointmentCreated(context.TODO(), appointment)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AppointmentCreated() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if received.UUID != appointment.UUID {
+				t.Errorf("posted appointment is incorrect, got %s, want %s", received.UUID, appointment.UUID)
+			}
+		})
+	}
+}
@@ -4,52 +4,170 @@ import (
 	"context"
 	"fmt"
 	"hospital-booking/internal/database"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// maxListedDoctors is the maximum number of doctors returned by a search.
+const maxListedDoctors = 100
+
 const (
-	findDoctorByUUIDQuery    = "SELECT id, uuid, user_id, name, email, mobile_phone, specialty FROM tb_doctor WHERE uuid = $1"
-	findDoctorByUserIDQuery  = "SELECT id, uuid, user_id, name, email, mobile_phone, specialty FROM tb_doctor WHERE user_id = $1"
-	findPatientByIDQuery     = "SELECT id, uuid, user_id, name, email, mobile_phone FROM tb_patient WHERE id = $1"
-	findPatientByUUIDQuery   = "SELECT id, uuid, user_id, name, email, mobile_phone FROM tb_patient WHERE uuid = $1"
-	findPatientByUserIDQuery = "SELECT id, uuid, user_id, name, email, mobile_phone FROM tb_patient WHERE user_id = $1"
-	insertBlockerQuery       = "INSERT INTO tb_block_period (uuid, doctor_id, start_date, end_date, description) VALUES ($1, $2, $3, $4, $5)"
-	listBlockersQuery        = "SELECT id, uuid, doctor_id, start_date, end_date, description FROM tb_block_period WHERE doctor_id = $1 AND $2 BETWEEN date_trunc('day', start_date) AND date_trunc('day', end_date)"
-	insertAppointmentQuery   = "INSERT INTO tb_appointment (uuid, doctor_id, patient_id, date) VALUES ($1, $2, $3, $4)"
-	listAppointmentsQuery    = "SELECT id, uuid, doctor_id, patient_id, date FROM tb_appointment WHERE doctor_id = $1 AND $2 = date_trunc('day', date)"
+	findDoctorByIDQuery          = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE id = $1"
+	findDoctorByUUIDQuery        = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE uuid = $1"
+	findDoctorByUserIDQuery      = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE user_id = $1"
+	findDoctorByEmailQuery       = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE email = $1"
+	listDoctorsBySpecialtyQuery  = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE active AND ($1 = '' OR specialty ILIKE $1) ORDER BY name LIMIT $2"
+	listDoctorsPagedQuery        = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, COALESCE(specialty, '') AS specialty, active FROM tb_doctor WHERE deleted_at IS NULL ORDER BY name, id LIMIT $1 OFFSET $2"
+	countDoctorsQuery            = "SELECT count(*) FROM tb_doctor WHERE deleted_at IS NULL"
+	findPatientByIDQuery         = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, consent_given FROM tb_patient WHERE id = $1"
+	findPatientByUUIDQuery       = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, consent_given FROM tb_patient WHERE uuid = $1"
+	findPatientsByIDsQuery       = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, consent_given FROM tb_patient WHERE id = ANY($1)"
+	findPatientByUserIDQuery     = "SELECT id, uuid, COALESCE(user_id, 0) AS user_id, name, email, COALESCE(mobile_phone, '') AS mobile_phone, consent_given FROM tb_patient WHERE user_id = $1"
+	updatePatientQuery           = "UPDATE tb_patient SET email = $1, mobile_phone = $2 WHERE id = $3"
+	insertBlockerQuery           = "INSERT INTO tb_block_period (uuid, doctor_id, start_date, end_date, description, inclusive) VALUES ($1, $2, $3, $4, $5, $6)"
+	listBlockersQuery            = "SELECT id, uuid, doctor_id, start_date, end_date, description, inclusive FROM tb_block_period WHERE doctor_id = $1 AND start_date < $3 AND end_date > $2 ORDER BY start_date"
+	insertExceptionQuery         = "INSERT INTO tb_availability_exception (uuid, doctor_id, start_date, end_date, description) VALUES ($1, $2, $3, $4, $5)"
+	listExceptionsQuery          = "SELECT id, uuid, doctor_id, start_date, end_date, description FROM tb_availability_exception WHERE doctor_id = $1 AND start_date < $3 AND end_date > $2"
+	insertAppointmentQuery       = "INSERT INTO tb_appointment (uuid, doctor_id, patient_id, date, notes, duration) VALUES ($1, $2, $3, $4, $5, $6)"
+	listAppointmentsQuery        = "SELECT id, uuid, doctor_id, patient_id, date, notes, status, duration, version FROM tb_appointment WHERE doctor_id = $1 AND $2 = date_trunc('day', date) AND deleted_at IS NULL"
+	listPatientAppointmentsQuery = "SELECT id, uuid, doctor_id, patient_id, date, duration FROM tb_appointment WHERE patient_id = $1 AND $2 = date_trunc('day', date) AND deleted_at IS NULL"
+	listAppointmentsInRangeQuery = "SELECT id, uuid, doctor_id, patient_id, date, duration FROM tb_appointment WHERE doctor_id = $1 AND date >= $2 AND date < $3 AND deleted_at IS NULL ORDER BY date"
+	listMonthAppointmentsQuery   = "SELECT id, uuid, doctor_id, patient_id, date, notes, status, duration, version FROM tb_appointment WHERE doctor_id = $1 AND date >= $2 AND date < $3 AND deleted_at IS NULL ORDER BY date"
+	listPatientHistoryQuery      = "SELECT id, uuid, doctor_id, patient_id, date, notes, status, duration, version FROM tb_appointment WHERE doctor_id = $1 AND patient_id = $2 AND deleted_at IS NULL ORDER BY date DESC"
+	findAppointmentByUUIDQuery   = "SELECT id, uuid, doctor_id, patient_id, date, notes, status, duration, version FROM tb_appointment WHERE uuid = $1 AND deleted_at IS NULL"
+	cancelAppointmentQuery       = "UPDATE tb_appointment SET deleted_at = now(), status = 'CANCELLED', version = version + 1 WHERE id = $1 AND version = $2 AND deleted_at IS NULL"
+	updateAppointmentStatusQuery = "UPDATE tb_appointment SET status = $1, version = version + 1 WHERE id = $2 AND version = $3 AND deleted_at IS NULL"
+	findIdempotencyKeyQuery      = "SELECT id, idempotency_key, patient_id, appointment_uuid, created_at FROM tb_idempotency WHERE patient_id = $1 AND idempotency_key = $2"
+	insertIdempotencyKeyQuery    = "INSERT INTO tb_idempotency (idempotency_key, patient_id, appointment_uuid) VALUES ($1, $2, $3)"
 )
 
+// insertBlockersBatchQuery is completed with a group of values for each blocker by buildInsertBlockersBatchQuery.
+const insertBlockersBatchQuery = "INSERT INTO tb_block_period (uuid, doctor_id, start_date, end_date, description, inclusive) VALUES "
+
+// buildInsertBlockersBatchQuery builds a multi-row insert for the given number of blockers.
+func buildInsertBlockersBatchQuery(count int) string {
+	values := make([]string, count)
+	for i := range values {
+		offset := i * 6
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", offset+1, offset+2, offset+3, offset+4, offset+5, offset+6)
+	}
+	return insertBlockersBatchQuery + strings.Join(values, ", ")
+}
+
+// startOfDay returns the midnight of the given date's wall clock. Truncating the time would use its UTC
+// instant instead, moving dates in timezones ahead of UTC to the previous day.
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
 // Repository provides access to booking data.
 type Repository interface {
 
+	// FindDoctorByID finds a doctor by its ID.
+	FindDoctorByID(ctx context.Context, ID int64) (*Doctor, error)
+
 	// FindDoctorByUUID finds a doctor by its UUID.
 	FindDoctorByUUID(ctx context.Context, uuid uuid.UUID) (*Doctor, error)
 
 	// FindDoctorByUserID finds a doctor by its user ID.
 	FindDoctorByUserID(ctx context.Context, userID int64) (*Doctor, error)
 
+	// FindDoctorByEmail finds a doctor by its email.
+	FindDoctorByEmail(ctx context.Context, email string) (*Doctor, error)
+
+	// ListDoctorsBySpecialty lists up to maxListedDoctors active doctors with the given specialty, ignoring case.
+	// If the specialty is empty, lists all the active doctors.
+	ListDoctorsBySpecialty(ctx context.Context, specialty string) ([]*Doctor, error)
+
+	// ListDoctorsPaged lists up to limit doctors ordered by name, skipping the first offset ones.
+	ListDoctorsPaged(ctx context.Context, limit, offset int) ([]*Doctor, error)
+
+	// CountDoctors counts all the doctors.
+	CountDoctors(ctx context.Context) (int64, error)
+
 	// FindPatientByID finds a doctor by its ID.
 	FindPatientByID(ctx context.Context, ID int64) (*Patient, error)
 
+	// FindPatientsByIDs finds the patients with the given IDs at once, indexed by their IDs.
+	FindPatientsByIDs(ctx context.Context, IDs []int64) (map[int64]*Patient, error)
+
 	// FindPatientByUUID finds a doctor by its UUID.
 	FindPatientByUUID(ctx context.Context, uuid uuid.UUID) (*Patient, error)
 
 	// FindPatientByUserID finds a patient by its user ID.
 	FindPatientByUserID(ctx context.Context, userID int64) (*Patient, error)
 
+	// UpdatePatient updates the contact details of the given patient.
+	UpdatePatient(ctx context.Context, patient Patient) error
+
 	// InsertBlocker inserts a new block period.
 	InsertBlocker(ctx context.Context, blockPeriod BlockPeriod) error
 
-	// ListBlockers lists the doctor's blockers accordingly the given date.
+	// InsertBlockersBatch inserts the given block periods at once, so either all of them or none are inserted.
+	InsertBlockersBatch(ctx context.Context, blockPeriods []BlockPeriod) error
+
+	// ListBlockers lists the doctor's blockers overlapping the given date.
 	ListBlockers(ctx context.Context, doctorID int64, date time.Time) ([]*BlockPeriod, error)
 
+	// ListBlockersInRange lists the doctor's blockers overlapping the half-open period [start, end), ordered by their
+	// start date.
+	ListBlockersInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*BlockPeriod, error)
+
+	// InsertAvailabilityException inserts a new availability exception.
+	InsertAvailabilityException(ctx context.Context, exception AvailabilityException) error
+
+	// ListAvailabilityExceptionsInRange lists the doctor's availability exceptions overlapping the half-open period
+	// [start, end).
+	ListAvailabilityExceptionsInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*AvailabilityException, error)
+
 	// InsertAppointment inserts a new appointment.
 	InsertAppointment(ctx context.Context, appointment Appointment) error
 
-	// ListAppointments lists the doctor's appointments.
+	// InsertAppointmentWithIdempotencyKey inserts a new appointment along with the idempotency key of the
+	// request that created it, in the same transaction.
+	InsertAppointmentWithIdempotencyKey(ctx context.Context, appointment Appointment, key string) error
+
+	// FindByIdempotencyKey finds the idempotency key sent by the given patient.
+	FindByIdempotencyKey(ctx context.Context, patientID int64, key string) (*IdempotencyKey, error)
+
+	// ListAppointments lists the doctor's appointments, ignoring the cancelled ones.
 	ListAppointments(ctx context.Context, doctorID int64, date time.Time) ([]*Appointment, error)
+
+	// ListAppointmentsInRange lists the doctor's appointments starting within the half-open period [start, end),
+	// ignoring the cancelled ones.
+	ListAppointmentsInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*Appointment, error)
+
+	// ListAppointmentsByMonth lists the doctor's appointments of the given month, ordered by date, ignoring the
+	// cancelled ones.
+	ListAppointmentsByMonth(ctx context.Context, doctorID int64, year int, month time.Month) ([]*Appointment, error)
+
+	// StreamAppointments calls fn for each of the doctor's appointments starting within the half-open period
+	// [start, end), ignoring the cancelled ones, without loading all of them in memory. Iterating stops at the
+	// first error returned by fn, which is returned as is.
+	StreamAppointments(ctx context.Context, doctorID int64, start, end time.Time, fn func(*Appointment) error) error
+
+	// ListAppointmentsByPatientAndDate lists the patient's appointments with any doctor, ignoring the cancelled ones.
+	ListAppointmentsByPatientAndDate(ctx context.Context, patientID int64, date time.Time) ([]*Appointment, error)
+
+	// ListAppointmentsByDoctorAndPatient lists the appointments of the patient with the doctor, from the latest
+	// to the earliest, ignoring the cancelled ones.
+	ListAppointmentsByDoctorAndPatient(ctx context.Context, doctorID int64, patientID int64) ([]*Appointment, error)
+
+	// FindAppointmentByUUID finds an appointment by its UUID, ignoring the cancelled ones.
+	FindAppointmentByUUID(ctx context.Context, uuid uuid.UUID) (*Appointment, error)
+
+	// CancelAppointment cancels the given appointment, bumping its version, as long as it is still at the given
+	// version. The appointment is kept for audit purposes. It returns false if the appointment is no longer at that
+	// version, e.g. when it was updated meanwhile.
+	CancelAppointment(ctx context.Context, appointmentID int64, version int32) (bool, error)
+
+	// UpdateAppointmentStatus moves the given appointment to the given status, bumping its version, as long as it is
+	// still at the given version. It returns false if the appointment is no longer at that version, e.g. when it was
+	// updated meanwhile.
+	UpdateAppointmentStatus(ctx context.Context, appointmentID int64, version int32, status AppointmentStatus) (bool, error)
 }
 
 type defaultRepository struct {
@@ -66,7 +184,7 @@ func (d defaultRepository) FindDoctorByUserID(ctx context.Context, userID int64)
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = userID
-	rows, err := d.dbConn.DB().QueryContext(ctx, findDoctorByUserIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_doctor_by_user_id", findDoctorByUserIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +206,7 @@ func (d defaultRepository) FindPatientByUserID(ctx context.Context, userID int64
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = userID
-	rows, err := d.dbConn.DB().QueryContext(ctx, findPatientByUserIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_patient_by_user_id", findPatientByUserIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -105,12 +223,55 @@ func (d defaultRepository) FindPatientByUserID(ctx context.Context, userID int64
 	return nil, nil
 }
 
+func (d defaultRepository) UpdatePatient(ctx context.Context, patient Patient) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = patient.Email
+	params[1] = patient.MobilePhone
+	params[2] = patient.ID
+	result, err := database.Exec(ctx, d.dbConn.DB(), "update_patient", updatePatientQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no patient was updated")
+	}
+	return nil
+}
+
+func (d defaultRepository) FindDoctorByID(ctx context.Context, ID int64) (*Doctor, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = ID
+	rows, err := database.Query(ctx, d.dbConn, "find_doctor_by_id", findDoctorByIDQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	doctor := new(Doctor)
+	for rows.Next() {
+		if err = database.TransformRow(rows, doctor); err != nil {
+			return nil, err
+		}
+		if doctor.ID > 0 {
+			return doctor, nil
+		}
+	}
+	return nil, nil
+}
+
 func (d defaultRepository) FindDoctorByUUID(ctx context.Context, uuid uuid.UUID) (*Doctor, error) {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = uuid
-	rows, err := d.dbConn.DB().QueryContext(ctx, findDoctorByUUIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_doctor_by_uuid", findDoctorByUUIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -127,12 +288,98 @@ func (d defaultRepository) FindDoctorByUUID(ctx context.Context, uuid uuid.UUID)
 	return nil, nil
 }
 
+func (d defaultRepository) FindDoctorByEmail(ctx context.Context, email string) (*Doctor, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = email
+	rows, err := database.Query(ctx, d.dbConn, "find_doctor_by_email", findDoctorByEmailQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	doctor := new(Doctor)
+	for rows.Next() {
+		if err = database.TransformRow(rows, doctor); err != nil {
+			return nil, err
+		}
+		if doctor.ID > 0 {
+			return doctor, nil
+		}
+	}
+	return nil, nil
+}
+
+// escapeLikePattern escapes the wildcards of the given value, so it can be matched literally by LIKE.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (d defaultRepository) ListDoctorsBySpecialty(ctx context.Context, specialty string) ([]*Doctor, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = escapeLikePattern(specialty)
+	params[1] = maxListedDoctors
+	rows, err := database.Query(ctx, d.dbConn, "list_doctors_by_specialty", listDoctorsBySpecialtyQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	doctors := make([]*Doctor, 0)
+	for rows.Next() {
+		doctor := new(Doctor)
+		if err = database.TransformRow(rows, doctor); err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, doctor)
+	}
+	return doctors, nil
+}
+
+func (d defaultRepository) ListDoctorsPaged(ctx context.Context, limit, offset int) ([]*Doctor, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = limit
+	params[1] = offset
+	rows, err := database.Query(ctx, d.dbConn, "list_doctors_paged", listDoctorsPagedQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	doctors := make([]*Doctor, 0)
+	for rows.Next() {
+		doctor := new(Doctor)
+		if err = database.TransformRow(rows, doctor); err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, doctor)
+	}
+	return doctors, nil
+}
+
+func (d defaultRepository) CountDoctors(ctx context.Context) (int64, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	var count int64
+	err := database.Timed("count_doctors", func() error {
+		return database.Retry(ctx, d.dbConn.Retries(), func() error {
+			return d.dbConn.DB().QueryRowContext(ctx, countDoctorsQuery).Scan(&count)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (d defaultRepository) FindPatientByID(ctx context.Context, ID int64) (*Patient, error) {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = ID
-	rows, err := d.dbConn.DB().QueryContext(ctx, findPatientByIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_patient_by_id", findPatientByIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -149,12 +396,33 @@ func (d defaultRepository) FindPatientByID(ctx context.Context, ID int64) (*Pati
 	return nil, nil
 }
 
+func (d defaultRepository) FindPatientsByIDs(ctx context.Context, IDs []int64) (map[int64]*Patient, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = pq.Array(IDs)
+	rows, err := database.Query(ctx, d.dbConn, "find_patients_by_ids", findPatientsByIDsQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	patients := make(map[int64]*Patient, len(IDs))
+	for rows.Next() {
+		patient := new(Patient)
+		if err = database.TransformRow(rows, patient); err != nil {
+			return nil, err
+		}
+		patients[patient.ID] = patient
+	}
+	return patients, nil
+}
+
 func (d defaultRepository) FindPatientByUUID(ctx context.Context, uuid uuid.UUID) (*Patient, error) {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
 	params := make([]interface{}, 1)
 	params[0] = uuid
-	rows, err := d.dbConn.DB().QueryContext(ctx, findPatientByUUIDQuery, params...)
+	rows, err := database.Query(ctx, d.dbConn, "find_patient_by_uuid", findPatientByUUIDQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -174,13 +442,14 @@ func (d defaultRepository) FindPatientByUUID(ctx context.Context, uuid uuid.UUID
 func (d defaultRepository) InsertBlocker(ctx context.Context, blockPeriod BlockPeriod) error {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
-	params := make([]interface{}, 5)
+	params := make([]interface{}, 6)
 	params[0] = blockPeriod.UUID
 	params[1] = blockPeriod.Doctor.ID
 	params[2] = blockPeriod.StartDate
 	params[3] = blockPeriod.EndDate
 	params[4] = blockPeriod.Description
-	result, err := d.dbConn.DB().ExecContext(ctx, insertBlockerQuery, params...)
+	params[5] = blockPeriod.Inclusive
+	result, err := database.Exec(ctx, d.dbConn.DB(), "insert_blocker", insertBlockerQuery, params...)
 	if err != nil {
 		return err
 	}
@@ -197,12 +466,65 @@ func (d defaultRepository) InsertBlocker(ctx context.Context, blockPeriod BlockP
 func (d defaultRepository) InsertAppointment(ctx context.Context, appointment Appointment) error {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
-	params := make([]interface{}, 4)
+	return d.insertAppointment(ctx, d.dbConn.DB(), appointment)
+}
+
+func (d defaultRepository) InsertBlockersBatch(ctx context.Context, blockPeriods []BlockPeriod) error {
+	if len(blockPeriods) == 0 {
+		return nil
+	}
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 0, len(blockPeriods)*6)
+	for _, blockPeriod := range blockPeriods {
+		params = append(params, blockPeriod.UUID, blockPeriod.Doctor.ID, blockPeriod.StartDate, blockPeriod.EndDate, blockPeriod.Description, blockPeriod.Inclusive)
+	}
+	result, err := database.Exec(ctx, d.dbConn.DB(), "insert_blockers_batch", buildInsertBlockersBatchQuery(len(blockPeriods)), params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != int64(len(blockPeriods)) {
+		return fmt.Errorf("blockers not inserted, expected %d, got %d", len(blockPeriods), affected)
+	}
+	return nil
+}
+
+func (d defaultRepository) InsertAppointmentWithIdempotencyKey(ctx context.Context, appointment Appointment, key string) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	tx, err := d.dbConn.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if err = d.insertAppointment(ctx, tx, appointment); err != nil {
+		return err
+	}
+	params := make([]interface{}, 3)
+	params[0] = key
+	params[1] = appointment.Patient.ID
+	params[2] = appointment.UUID
+	if _, err = database.Exec(ctx, tx, "insert_idempotency_key", insertIdempotencyKeyQuery, params...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (d defaultRepository) insertAppointment(ctx context.Context, exec database.Execer, appointment Appointment) error {
+	params := make([]interface{}, 6)
 	params[0] = appointment.UUID
 	params[1] = appointment.Doctor.ID
 	params[2] = appointment.Patient.ID
 	params[3] = appointment.Date
-	result, err := d.dbConn.DB().ExecContext(ctx, insertAppointmentQuery, params...)
+	params[4] = appointment.Notes
+	params[5] = appointment.Slots()
+	result, err := database.Exec(ctx, exec, "insert_appointment", insertAppointmentQuery, params...)
 	if err != nil {
 		return err
 	}
@@ -217,12 +539,18 @@ func (d defaultRepository) InsertAppointment(ctx context.Context, appointment Ap
 }
 
 func (d defaultRepository) ListBlockers(ctx context.Context, doctorID int64, date time.Time) ([]*BlockPeriod, error) {
+	// only the blockers overlapping the day are listed, so a blocker ending at its midnight is left out
+	return d.ListBlockersInRange(ctx, doctorID, startOfDay(date), startOfDay(date).AddDate(0, 0, 1))
+}
+
+func (d defaultRepository) ListBlockersInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*BlockPeriod, error) {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
-	params := make([]interface{}, 2)
+	params := make([]interface{}, 3)
 	params[0] = doctorID
-	params[1] = date.Truncate(24 * time.Hour)
-	rows, err := d.dbConn.DB().QueryContext(ctx, listBlockersQuery, params...)
+	params[1] = start
+	params[2] = end
+	rows, err := database.Query(ctx, d.dbConn, "list_blockers", listBlockersQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -238,13 +566,59 @@ func (d defaultRepository) ListBlockers(ctx context.Context, doctorID int64, dat
 	return blockers, nil
 }
 
+func (d defaultRepository) InsertAvailabilityException(ctx context.Context, exception AvailabilityException) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 5)
+	params[0] = exception.UUID
+	params[1] = exception.Doctor.ID
+	params[2] = exception.StartDate
+	params[3] = exception.EndDate
+	params[4] = exception.Description
+	result, err := database.Exec(ctx, d.dbConn.DB(), "insert_availability_exception", insertExceptionQuery, params...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("availability exception not inserted")
+	}
+	return nil
+}
+
+func (d defaultRepository) ListAvailabilityExceptionsInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*AvailabilityException, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = doctorID
+	params[1] = start
+	params[2] = end
+	rows, err := database.Query(ctx, d.dbConn, "list_availability_exceptions", listExceptionsQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	exceptions := make([]*AvailabilityException, 0)
+	for rows.Next() {
+		exception := new(AvailabilityException)
+		if err = database.TransformRow(rows, exception); err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, exception)
+	}
+	return exceptions, nil
+}
+
 func (d defaultRepository) ListAppointments(ctx context.Context, doctorID int64, date time.Time) ([]*Appointment, error) {
 	ctx, cancel := d.dbConn.CreateContext(ctx)
 	defer cancel()
 	params := make([]interface{}, 2)
 	params[0] = doctorID
-	params[1] = date.Truncate(24 * time.Hour)
-	rows, err := d.dbConn.DB().QueryContext(ctx, listAppointmentsQuery, params...)
+	params[1] = startOfDay(date)
+	rows, err := database.Query(ctx, d.dbConn, "list_appointments", listAppointmentsQuery, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -259,3 +633,198 @@ func (d defaultRepository) ListAppointments(ctx context.Context, doctorID int64,
 	}
 	return appointments, nil
 }
+
+func (d defaultRepository) ListAppointmentsInRange(ctx context.Context, doctorID int64, start, end time.Time) ([]*Appointment, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = doctorID
+	params[1] = start
+	params[2] = end
+	rows, err := database.Query(ctx, d.dbConn, "list_appointments_in_range", listAppointmentsInRangeQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	appointments := make([]*Appointment, 0)
+	for rows.Next() {
+		appointment := new(Appointment)
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, nil
+}
+
+func (d defaultRepository) ListAppointmentsByMonth(ctx context.Context, doctorID int64, year int, month time.Month) ([]*Appointment, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	params := make([]interface{}, 3)
+	params[0] = doctorID
+	params[1] = start
+	params[2] = start.AddDate(0, 1, 0)
+	rows, err := database.Query(ctx, d.dbConn, "list_month_appointments", listMonthAppointmentsQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	appointments := make([]*Appointment, 0)
+	for rows.Next() {
+		appointment := new(Appointment)
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, nil
+}
+
+func (d defaultRepository) StreamAppointments(ctx context.Context, doctorID int64, start, end time.Time, fn func(*Appointment) error) error {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = doctorID
+	params[1] = start
+	params[2] = end
+	rows, err := database.Query(ctx, d.dbConn, "list_appointments_in_range", listAppointmentsInRangeQuery, params...)
+	if err != nil {
+		return err
+	}
+	defer database.CloseRows(rows)
+	for rows.Next() {
+		appointment := new(Appointment)
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return err
+		}
+		if err = fn(appointment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (d defaultRepository) ListAppointmentsByPatientAndDate(ctx context.Context, patientID int64, date time.Time) ([]*Appointment, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = patientID
+	params[1] = startOfDay(date)
+	rows, err := database.Query(ctx, d.dbConn, "list_patient_appointments", listPatientAppointmentsQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	appointments := make([]*Appointment, 0)
+	for rows.Next() {
+		appointment := new(Appointment)
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, nil
+}
+
+func (d defaultRepository) ListAppointmentsByDoctorAndPatient(ctx context.Context, doctorID int64, patientID int64) ([]*Appointment, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = doctorID
+	params[1] = patientID
+	rows, err := database.Query(ctx, d.dbConn, "list_patient_history", listPatientHistoryQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	appointments := make([]*Appointment, 0)
+	for rows.Next() {
+		appointment := new(Appointment)
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, appointment)
+	}
+	return appointments, nil
+}
+
+func (d defaultRepository) FindAppointmentByUUID(ctx context.Context, uuid uuid.UUID) (*Appointment, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 1)
+	params[0] = uuid
+	rows, err := database.Query(ctx, d.dbConn, "find_appointment_by_uuid", findAppointmentByUUIDQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	appointment := new(Appointment)
+	for rows.Next() {
+		if err = database.TransformRow(rows, appointment); err != nil {
+			return nil, err
+		}
+		if appointment.ID > 0 {
+			return appointment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (d defaultRepository) CancelAppointment(ctx context.Context, appointmentID int64, version int32) (bool, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = appointmentID
+	params[1] = version
+	result, err := database.Exec(ctx, d.dbConn.DB(), "cancel_appointment", cancelAppointmentQuery, params...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (d defaultRepository) UpdateAppointmentStatus(ctx context.Context, appointmentID int64, version int32, status AppointmentStatus) (bool, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 3)
+	params[0] = string(status)
+	params[1] = appointmentID
+	params[2] = version
+	result, err := database.Exec(ctx, d.dbConn.DB(), "update_appointment_status", updateAppointmentStatusQuery, params...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (d defaultRepository) FindByIdempotencyKey(ctx context.Context, patientID int64, key string) (*IdempotencyKey, error) {
+	ctx, cancel := d.dbConn.CreateContext(ctx)
+	defer cancel()
+	params := make([]interface{}, 2)
+	params[0] = patientID
+	params[1] = key
+	rows, err := database.Query(ctx, d.dbConn, "find_idempotency_key", findIdempotencyKeyQuery, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer database.CloseRows(rows)
+	idempotencyKey := new(IdempotencyKey)
+	for rows.Next() {
+		if err = database.TransformRow(rows, idempotencyKey); err != nil {
+			return nil, err
+		}
+		if idempotencyKey.ID > 0 {
+			return idempotencyKey, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,84 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"hospital-booking/internal/mock"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestStreamAppointments(t *testing.T) {
+	errStop := errors.New("stop")
+	day := time.Date(2021, 8, 10, 0, 0, 0, 0, time.UTC)
+	appointmentRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).
+			AddRow(1, uuid.New(), 1, 1, day.Add(9*time.Hour)).
+			AddRow(2, uuid.New(), 1, 2, day.Add(10*time.Hour)).
+			AddRow(3, uuid.New(), 1, 3, day.Add(11*time.Hour))
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		stopAt        int
+		wantCalls     int
+		wantErr       bool
+	}{
+		{
+			name:          "should call the callback for each appointment",
+			dbMockOptions: []mock.DBResultOption{withListAppointmentsInRangeResult(appointmentRows())},
+			wantCalls:     3,
+		},
+		{
+			name:          "should not call the callback when there are no appointments",
+			dbMockOptions: []mock.DBResultOption{withListAppointmentsInRangeResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}))},
+			wantCalls:     0,
+		},
+		{
+			name:          "should stop at the first error returned by the callback",
+			dbMockOptions: []mock.DBResultOption{withListAppointmentsInRangeResult(appointmentRows())},
+			stopAt:        2,
+			wantCalls:     2,
+			wantErr:       true,
+		},
+		{
+			name:          "should not call the callback due to a database error",
+			dbMockOptions: []mock.DBResultOption{withListAppointmentsInRangeError()},
+			wantCalls:     0,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+			repository := newRepository(dbConn)
+
+			calls := 0
+			err := repository.StreamAppointments(context.TODO(), 1, day, day.AddDate(0, 0, 1), func(appointment *Appointment) error {
+				calls++
+				if int64(calls) != appointment.ID {
+					t.Errorf("appointment is incorrect, got %d, want %d", appointment.ID, calls)
+				}
+				if calls == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StreamAppointments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.stopAt > 0 && !errors.Is(err, errStop) {
+				t.Errorf("StreamAppointments() error = %v, want %v", err, errStop)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("callback calls are incorrect, got %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
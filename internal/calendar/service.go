@@ -8,7 +8,10 @@ import (
 	"hospital-booking/internal/auth"
 	"hospital-booking/internal/configs"
 	"hospital-booking/internal/database"
+	"hospital-booking/internal/logging"
+	"hospital-booking/internal/metrics"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,16 +20,43 @@ import (
 const (
 	startWorkHour int32 = 9
 	endWorkHour   int32 = 17
+
+	maxIdempotencyKeyLength = 255
+	maxNotesLength          = 500
+	slotDuration            = time.Hour
+
+	// maxRecurringBlockers limits the recurring block periods to about two years.
+	maxRecurringBlockers = 104
 )
 
 // Reader determines the methods available to reading the calendars.
 type Reader interface {
 
-	// GetDoctorCalendar returns the doctor's daily calendar based on the given parameters.
+	// GetDoctor returns the doctor with the given UUID. Inactive doctors are reported as not found.
+	GetDoctor(ctx context.Context, doctorUUID uuid.UUID) (*Doctor, error)
+
+	// GetDoctorCalendar returns the doctor's daily calendar based on the given parameters, without the contact
+	// details of the patients.
 	GetDoctorCalendar(ctx context.Context, user auth.User, doctorUUID uuid.UUID, date time.Time) ([]Entry, error)
 
-	// GetAppointments returns the doctor's appointments based on the given date.
-	GetAppointments(ctx context.Context, user auth.User, date time.Time) ([]Entry, error)
+	// GetDoctorAvailability returns, for each day of the given range, whether the doctor has any available slot.
+	GetDoctorAvailability(ctx context.Context, user auth.User, doctorUUID uuid.UUID, dateRange DateRange) ([]DayAvailability, error)
+
+	// GetAppointments returns the doctor's appointments based on the given date, satisfying the given filter.
+	GetAppointments(ctx context.Context, user auth.User, date time.Time, filter EntryFilter) ([]Entry, error)
+
+	// GetMonthAppointments returns the doctor's appointments of the given month, along with their patients, grouped
+	// by day. Only the days with appointments are returned.
+	GetMonthAppointments(ctx context.Context, user auth.User, year int, month time.Month) ([]DayAppointments, error)
+
+	// GetAppointment returns the appointment with the given UUID, along with its doctor and patient. Only the
+	// appointment's patient or doctor can check it.
+	GetAppointment(ctx context.Context, user auth.User, appointmentUUID uuid.UUID) (*Appointment, error)
+
+	// GetPatientAppointments returns the appointments of the given patient with the authenticated doctor, from the
+	// latest to the earliest, keeping only the ones with the given status, if any. Patients who never had an
+	// appointment with the doctor are reported as not found.
+	GetPatientAppointments(ctx context.Context, user auth.User, patientUUID uuid.UUID, status AppointmentStatus) ([]*Appointment, error)
 }
 
 // Writer determines the methods available to write on calendars.
@@ -34,53 +64,265 @@ type Writer interface {
 
 	// InsertAppointment inserts an appointment to the doctor's calendar.
 	InsertAppointment(ctx context.Context, user auth.User, appointmentRequest AppointmentRequest) error
+
+	// CancelAppointment cancels one of the patient's appointments, releasing its slot.
+	CancelAppointment(ctx context.Context, user auth.User, appointmentUUID uuid.UUID) error
+
+	// UpdateAppointmentStatus moves one of the doctor's appointments to the given status, if the transition is
+	// allowed. Cancelled appointments release their slot. The request must carry the current version of the
+	// appointment, otherwise it was updated meanwhile and the request is rejected as a conflict.
+	UpdateAppointmentStatus(ctx context.Context, user auth.User, appointmentUUID uuid.UUID, request AppointmentStatusRequest) error
 }
 
 // Blocker determines the methods available to manage calendar's blockers.
 type Blocker interface {
 
-	// InsertBlocker creates a new calendar blocker.
-	InsertBlocker(ctx context.Context, user auth.User, blockPeriod BlockPeriod) error
+	// InsertBlocker creates a new calendar blocker, returning the appointments that fall inside it.
+	InsertBlocker(ctx context.Context, user auth.User, blockPeriod BlockPeriod) (*BlockerResult, error)
+
+	// InsertRecurringBlockers creates a calendar blocker for each week until the request's end date.
+	InsertRecurringBlockers(ctx context.Context, user auth.User, request RecurringBlockPeriodRequest) error
+
+	// ListBlockers returns the doctor's blockers overlapping the given range, ordered by their start date.
+	ListBlockers(ctx context.Context, user auth.User, dateRange DateRange) ([]*BlockPeriod, error)
+}
+
+// ExceptionManager determines the methods available to manage the doctors' availability exceptions.
+type ExceptionManager interface {
+
+	// InsertAvailabilityException adds working hours to the doctor's calendar on a day which is normally off.
+	InsertAvailabilityException(ctx context.Context, user auth.User, exception AvailabilityException) (*AvailabilityException, error)
+}
+
+// PatientManager determines the methods available to the patients to manage their own details.
+type PatientManager interface {
+
+	// UpdatePatientContact updates the contact details of the authenticated patient, returning the updated patient.
+	// The login email is kept, so changing the contact email doesn't change how the patient signs in.
+	UpdatePatientContact(ctx context.Context, user auth.User, request PatientContactRequest) (*Patient, error)
+}
+
+// Searcher determines the methods available to search for doctors.
+type Searcher interface {
+
+	// ListDoctorsBySpecialty returns the doctors with the given specialty, which is normalized and must be one of the
+	// KnownSpecialties. If no specialty is given, returns all the doctors. At most 100 doctors are returned.
+	ListDoctorsBySpecialty(ctx context.Context, specialty string) ([]DoctorSummary, error)
+
+	// FindDoctorByEmail returns the doctor with the given email, whether active or not.
+	FindDoctorByEmail(ctx context.Context, email string) (*Doctor, error)
+
+	// ListDoctors returns the given page of all the doctors, ordered by name, along with the total of doctors.
+	ListDoctors(ctx context.Context, pagination Pagination) (*DoctorPage, error)
 }
 
 // Service determines the methods used to manage the hospital calendar.
 type Service interface {
+	Searcher
 	Reader
 	Writer
 	Blocker
+	ExceptionManager
+	PatientManager
 }
 
 type defaultService struct {
 	repository Repository
 	config     configs.Config
+	notifier   Notifier
+	cache      CalendarCache
+}
+
+// ServiceOption determines the Functional Options used to create a new Service.
+type ServiceOption func(s *defaultService)
+
+// WithNotifier sets the Notifier called when the calendar events happen. By default, no one is notified.
+func WithNotifier(notifier Notifier) ServiceOption {
+	return func(s *defaultService) {
+		s.notifier = notifier
+	}
+}
+
+// WithCalendarCache sets the CalendarCache shared with the other services, so they can drop the calendars they
+// change. By default, the service creates its own one based on the configuration.
+func WithCalendarCache(cache CalendarCache) ServiceOption {
+	return func(s *defaultService) {
+		s.cache = cache
+	}
 }
 
 // NewService creates a new auth service.
-func NewService(config configs.Config, dbConn database.Connection) Service {
-	return &defaultService{
+func NewService(config configs.Config, dbConn database.Connection, opts ...ServiceOption) Service {
+	service := &defaultService{
 		config:     config,
 		repository: newRepository(dbConn),
+		notifier:   noopNotifier{},
+		cache:      NewCalendarCache(config),
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+func (d defaultService) ListDoctorsBySpecialty(ctx context.Context, specialty string) ([]DoctorSummary, error) {
+	specialty = strings.TrimSpace(specialty)
+	if specialty != "" {
+		parsed, err := ParseSpecialty(specialty)
+		if err != nil {
+			return nil, err
+		}
+		specialty = string(parsed)
+	}
+	doctors, err := d.repository.ListDoctorsBySpecialty(ctx, specialty)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	summaries := make([]DoctorSummary, 0, len(doctors))
+	for _, doctor := range doctors {
+		summaries = append(summaries, doctor.Summary())
+	}
+	return summaries, nil
+}
+
+func (d defaultService) ListDoctors(ctx context.Context, pagination Pagination) (*DoctorPage, error) {
+	doctors, err := d.repository.ListDoctorsPaged(ctx, pagination.Size, pagination.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	total, err := d.repository.CountDoctors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	return &DoctorPage{Items: doctors, Total: total, Page: pagination.Page, Size: pagination.Size}, nil
+}
+
+func (d defaultService) FindDoctorByEmail(ctx context.Context, email string) (*Doctor, error) {
+	email, err := ParseEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	doctor, err := d.repository.FindDoctorByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithCode(CodeDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
 	}
+	return doctor, nil
+}
+
+// withinPeriod checks if the given reference is within the half-open period [start, end).
+//
+// Blockers and appointments are both handled as half-open periods, so a period ending at some hour
+// doesn't take the slot starting at that same hour.
+func withinPeriod(reference, start, end time.Time) bool {
+	return !reference.Before(start) && reference.Before(end)
+}
+
+// slotTime returns the time in which the slot of the given hour starts, in the clinic timezone.
+func (d defaultService) slotTime(date time.Time, hour int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, d.config.Location())
+}
+
+// clinicTime reads the wall clock of the given time in the clinic timezone, since the dates are stored
+// without timezone.
+func (d defaultService) clinicTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), d.config.Location())
 }
 
 // hourIsBlocked checks if the given hour is blocked or not.
 func (d defaultService) hourIsBlocked(blockers []*BlockPeriod, date time.Time, hour int) bool {
-	reference := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, date.Location())
+	reference := d.slotTime(date, hour)
 	for _, v := range blockers {
-		if (reference.After(v.StartDate) || reference.Equal(v.StartDate)) && (reference.Before(v.EndDate) || reference.Equal(v.EndDate)) {
+		if withinPeriod(reference, d.clinicTime(v.StartDate), d.clinicTime(v.blockedUntil())) {
+			return true
+		}
+	}
+	return false
+}
+
+// hourIsAdded checks if the given hour is added by some availability exception.
+func (d defaultService) hourIsAdded(exceptions []*AvailabilityException, date time.Time, hour int) bool {
+	reference := d.slotTime(date, hour)
+	for _, v := range exceptions {
+		if withinPeriod(reference, d.clinicTime(v.StartDate), d.clinicTime(v.EndDate)) {
 			return true
 		}
 	}
 	return false
 }
 
+// isWorkingDay checks if the doctors work on the given date by default.
+func (d defaultService) isWorkingDay(date time.Time) bool {
+	for _, weekday := range d.config.WorkingDays() {
+		if date.Weekday() == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// workingHours returns the hours in which the doctor works on the given date: all the default ones on the working
+// days, otherwise only the ones added by the availability exceptions.
+func (d defaultService) workingHours(exceptions []*AvailabilityException, date time.Time) []int32 {
+	workingDay := d.isWorkingDay(date)
+	hours := make([]int32, 0, endWorkHour-startWorkHour+1)
+	for hour := startWorkHour; hour <= endWorkHour; hour++ {
+		if workingDay || d.hourIsAdded(exceptions, date, int(hour)) {
+			hours = append(hours, hour)
+		}
+	}
+	return hours
+}
+
+// listExceptions lists the doctor's availability exceptions overlapping the half-open period [start, end). As they
+// only matter on the days off, they are not loaded at all when every day of the period is a working day.
+func (d defaultService) listExceptions(ctx context.Context, doctorID int64, start, end time.Time) ([]*AvailabilityException, error) {
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !d.isWorkingDay(day) {
+			return d.repository.ListAvailabilityExceptionsInRange(ctx, doctorID, start, end)
+		}
+	}
+	return nil, nil
+}
+
+func (d defaultService) GetDoctor(ctx context.Context, doctorUUID uuid.UUID) (*Doctor, error) {
+	doctor, err := d.repository.FindDoctorByUUID(ctx, doctorUUID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil || !doctor.Active {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithCode(CodeDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	return doctor, nil
+}
+
 func (d defaultService) GetDoctorCalendar(ctx context.Context, user auth.User, doctorUUID uuid.UUID, date time.Time) ([]Entry, error) {
+	entries, err := d.doctorCalendar(ctx, doctorUUID, date, true)
+	if err != nil {
+		return nil, err
+	}
+	// the calendar is shown to the patients, who must never see the contacts of the others
+	for i := range entries {
+		entries[i] = entries[i].Sanitize()
+	}
+	return entries, nil
+}
+
+// doctorCalendar computes the doctor's available slots of the given date. The cached calendar is only used when
+// allowed, as the bookings must always check the slots against the stored appointments.
+func (d defaultService) doctorCalendar(ctx context.Context, doctorUUID uuid.UUID, date time.Time, useCache bool) ([]Entry, error) {
 	doctor, err := d.repository.FindDoctorByUUID(ctx, doctorUUID)
 	if err != nil {
 		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
-	if doctor == nil {
-		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	if doctor == nil || !doctor.Active {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithCode(CodeDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	entries, version, found := d.cache.Get(doctor.ID, date)
+	if useCache && found {
+		return entries, nil
 	}
 	appointments, err := d.repository.ListAppointments(ctx, doctor.ID, date)
 	if err != nil {
@@ -90,8 +332,26 @@ func (d defaultService) GetDoctorCalendar(ctx context.Context, user auth.User, d
 	if err != nil {
 		return nil, err
 	}
+	exceptions, err := d.listExceptions(ctx, doctor.ID, startOfDay(date), startOfDay(date).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	entries, err = d.availableEntries(ctx, appointments, blockers, exceptions, date)
+	if err != nil {
+		return nil, err
+	}
+	d.cache.Set(doctor.ID, date, version, entries)
+	return entries, nil
+}
+
+// availableEntries returns the working slots of the given date that are neither blocked nor taken by an appointment.
+func (d defaultService) availableEntries(ctx context.Context, appointments []*Appointment, blockers []*BlockPeriod, exceptions []*AvailabilityException, date time.Time) ([]Entry, error) {
 	entries := make([]Entry, 0, endWorkHour-startWorkHour)
-	for hour := startWorkHour; hour <= endWorkHour; hour++ {
+	for _, hour := range d.workingHours(exceptions, date) {
+		// stops early once the request is cancelled, e.g. when the client is gone
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		available := !d.hourIsBlocked(blockers, date, int(hour))
 		if !available {
 			continue
@@ -110,35 +370,79 @@ func (d defaultService) GetDoctorCalendar(ctx context.Context, user auth.User, d
 	return entries, nil
 }
 
+func (d defaultService) GetDoctorAvailability(ctx context.Context, user auth.User, doctorUUID uuid.UUID, dateRange DateRange) ([]DayAvailability, error) {
+	doctor, err := d.repository.FindDoctorByUUID(ctx, doctorUUID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil || !doctor.Active {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithCode(CodeDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	// the whole range is loaded at once, instead of querying the calendar of each day
+	start, end := startOfDay(dateRange.From), startOfDay(dateRange.To).AddDate(0, 0, 1)
+	appointments, err := d.repository.ListAppointmentsInRange(ctx, doctor.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	blockers, err := d.repository.ListBlockersInRange(ctx, doctor.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := d.listExceptions(ctx, doctor.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]DayAvailability, 0, dateRange.Days())
+	for day := d.slotTime(dateRange.From, 0); !day.After(d.slotTime(dateRange.To, 0)); day = day.AddDate(0, 0, 1) {
+		entries, err := d.availableEntries(ctx, appointments, blockers, exceptions, day)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, DayAvailability{Date: day.Format(dateLayout), Available: len(entries) > 0})
+	}
+	return days, nil
+}
+
 // hasAppointment checks if there is some appointment in the given date.
 func (d defaultService) hasAppointment(appointments []*Appointment, date time.Time, hour int) bool {
-	reference := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, time.Local)
+	return d.appointmentAt(appointments, date, hour) != nil
+}
+
+// appointmentAt gets the appointment taking the given hour, if there is one.
+func (d defaultService) appointmentAt(appointments []*Appointment, date time.Time, hour int) *Appointment {
+	reference := d.slotTime(date, hour)
 	for _, v := range appointments {
-		if reference.Equal(v.Date) {
-			return true
+		start := d.clinicTime(v.Date)
+		if withinPeriod(reference, start, start.Add(time.Duration(v.Slots())*slotDuration)) {
+			return v
 		}
 	}
-	return false
+	return nil
 }
 
-// getAppointmentPatient gets the appointment patient, if there is one.
-func (d defaultService) getAppointmentPatient(ctx context.Context, appointments []*Appointment, date time.Time, hour int) (*Patient, error) {
-	reference := time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, time.Local)
-	for _, v := range appointments {
-		if reference.Equal(v.Date) {
-			return d.repository.FindPatientByID(ctx, v.PatientID)
+// findAppointmentsPatients finds the patients of the given appointments in a single query.
+func (d defaultService) findAppointmentsPatients(ctx context.Context, appointments []*Appointment) (map[int64]*Patient, error) {
+	if len(appointments) == 0 {
+		return map[int64]*Patient{}, nil
+	}
+	seen := make(map[int64]bool, len(appointments))
+	IDs := make([]int64, 0, len(appointments))
+	for _, appointment := range appointments {
+		if !seen[appointment.PatientID] {
+			seen[appointment.PatientID] = true
+			IDs = append(IDs, appointment.PatientID)
 		}
 	}
-	return nil, nil
+	return d.repository.FindPatientsByIDs(ctx, IDs)
 }
 
-func (d defaultService) GetAppointments(ctx context.Context, user auth.User, date time.Time) ([]Entry, error) {
+func (d defaultService) GetAppointments(ctx context.Context, user auth.User, date time.Time, filter EntryFilter) ([]Entry, error) {
 	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if doctor == nil {
-		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCheckItsAppointments), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCheckItsAppointments), apierrors.WithCode(CodeOnlyDoctorCanCheckItsAppointments), apierrors.WithHTTPStatusCode(http.StatusForbidden))
 	}
 	appointments, err := d.repository.ListAppointments(ctx, doctor.ID, date)
 	if err != nil {
@@ -148,39 +452,84 @@ func (d defaultService) GetAppointments(ctx context.Context, user auth.User, dat
 	if err != nil {
 		return nil, err
 	}
+	exceptions, err := d.listExceptions(ctx, doctor.ID, startOfDay(date), startOfDay(date).AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	patients, err := d.findAppointmentsPatients(ctx, appointments)
+	if err != nil {
+		return nil, err
+	}
 	entries := make([]Entry, 0, endWorkHour-startWorkHour)
-	for hour := startWorkHour; hour <= endWorkHour; hour++ {
+	for _, hour := range d.workingHours(exceptions, date) {
+		// stops early once the request is cancelled, e.g. when the client is gone
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
 		available := !d.hourIsBlocked(blockers, date, int(hour))
 		var patient *Patient
+		var notes *string
 		if available {
-			available = !d.hasAppointment(appointments, date, int(hour))
-			if !available {
-				patient, err = d.getAppointmentPatient(ctx, appointments, date, int(hour))
-				if err != nil {
-					return nil, err
-				}
+			if appointment := d.appointmentAt(appointments, date, int(hour)); appointment != nil {
+				available = false
+				patient = patients[appointment.PatientID]
+				notes = appointment.Notes
 			}
 		}
 		entry := Entry{
 			Hour:      hour,
 			Available: available,
 			Patient:   patient,
+			Notes:     notes,
+		}
+		if !filter.Match(entry) {
+			continue
 		}
 		entries = append(entries, entry)
 	}
 	return entries, nil
 }
 
-func (d defaultService) InsertBlocker(ctx context.Context, user auth.User, blockPeriod BlockPeriod) error {
+func (d defaultService) GetMonthAppointments(ctx context.Context, user auth.User, year int, month time.Month) ([]DayAppointments, error) {
 	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
 	if err != nil {
-		return fmt.Errorf("an unexpected error occurred: %w", err)
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if doctor == nil {
-		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCreateBlocker), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCheckItsAppointments), apierrors.WithCode(CodeOnlyDoctorCanCheckItsAppointments), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	appointments, err := d.repository.ListAppointmentsByMonth(ctx, doctor.ID, year, month)
+	if err != nil {
+		return nil, err
+	}
+	patients, err := d.findAppointmentsPatients(ctx, appointments)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]DayAppointments, 0)
+	for _, appointment := range appointments {
+		appointment.Date = d.clinicTime(appointment.Date)
+		appointment.Patient = patients[appointment.PatientID]
+		// the appointments are ordered by date, so the ones of the same day are next to each other
+		day := appointment.Date.Format(dateLayout)
+		if len(days) == 0 || days[len(days)-1].Date != day {
+			days = append(days, DayAppointments{Date: day, Appointments: make([]*Appointment, 0)})
+		}
+		days[len(days)-1].Appointments = append(days[len(days)-1].Appointments, appointment)
+	}
+	return days, nil
+}
+
+func (d defaultService) InsertBlocker(ctx context.Context, user auth.User, blockPeriod BlockPeriod) (*BlockerResult, error) {
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCreateBlocker), apierrors.WithCode(CodeOnlyDoctorCanCreateBlocker), apierrors.WithHTTPStatusCode(http.StatusForbidden))
 	}
 	if err = blockPeriod.Validate(); err != nil {
-		return err
+		return nil, err
 	}
 	blocker := BlockPeriod{
 		Doctor:      doctor,
@@ -188,14 +537,131 @@ func (d defaultService) InsertBlocker(ctx context.Context, user auth.User, block
 		StartDate:   blockPeriod.StartDate.Truncate(time.Hour),
 		EndDate:     blockPeriod.EndDate.Truncate(time.Hour),
 		Description: blockPeriod.Description,
+		Inclusive:   blockPeriod.Inclusive,
 	}
 	err = d.repository.InsertBlocker(ctx, blocker)
 	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	// a blocker may span several days, so all the doctor's calendars are dropped
+	d.cache.InvalidateDoctor(doctor.ID)
+	metrics.BlockersCreated(1)
+	appointments, err := d.repository.ListAppointmentsInRange(ctx, doctor.ID, blocker.StartDate, blocker.blockedUntil())
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	result := &BlockerResult{UUID: blocker.UUID, AffectedAppointmentUUIDs: make([]uuid.UUID, 0, len(appointments))}
+	for _, appointment := range appointments {
+		// the dates are stored without timezone, so the overlap is checked on the clinic wall clock
+		if withinPeriod(d.clinicTime(appointment.Date), d.clinicTime(blocker.StartDate), d.clinicTime(blocker.blockedUntil())) {
+			result.AffectedAppointmentUUIDs = append(result.AffectedAppointmentUUIDs, appointment.UUID)
+		}
+	}
+	result.AffectedAppointments = len(result.AffectedAppointmentUUIDs)
+	return result, nil
+}
+
+func (d defaultService) ListBlockers(ctx context.Context, user auth.User, dateRange DateRange) ([]*BlockPeriod, error) {
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCheckItsBlockers), apierrors.WithCode(CodeOnlyDoctorCanCheckItsBlockers), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	// the blockers partially overlapping the first or the last day are listed as well
+	start, end := startOfDay(dateRange.From), startOfDay(dateRange.To).AddDate(0, 0, 1)
+	blockers, err := d.repository.ListBlockersInRange(ctx, doctor.ID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	for _, blocker := range blockers {
+		blocker.StartDate = d.clinicTime(blocker.StartDate)
+		blocker.EndDate = d.clinicTime(blocker.EndDate)
+	}
+	return blockers, nil
+}
+
+func (d defaultService) InsertAvailabilityException(ctx context.Context, user auth.User, exception AvailabilityException) (*AvailabilityException, error) {
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCreateException), apierrors.WithCode(CodeOnlyDoctorCanCreateException), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	if err = exception.Validate(); err != nil {
+		return nil, err
+	}
+	// the working days already have all the working hours
+	if d.isWorkingDay(exception.StartDate) {
+		return nil, apierrors.NewValidationError("start_date", "not a day off")
+	}
+	created := AvailabilityException{
+		Doctor:      doctor,
+		UUID:        uuid.New(),
+		StartDate:   exception.StartDate.Truncate(time.Hour),
+		EndDate:     exception.EndDate.Truncate(time.Hour),
+		Description: exception.Description,
+	}
+	if err = d.repository.InsertAvailabilityException(ctx, created); err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	d.cache.InvalidateDoctor(doctor.ID)
+	return &created, nil
+}
+
+func (d defaultService) InsertRecurringBlockers(ctx context.Context, user auth.User, request RecurringBlockPeriodRequest) error {
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCreateBlocker), apierrors.WithCode(CodeOnlyDoctorCanCreateBlocker), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	if err = request.Validate(); err != nil {
+		return err
+	}
+	blockers := d.recurringBlockers(doctor, request, time.Now())
+	if len(blockers) == 0 {
+		return apierrors.NewValidationError("weekday", "no occurrence until end_date")
+	}
+	if len(blockers) > maxRecurringBlockers {
+		return apierrors.NewValidationError("end_date", "max")
+	}
+	if err = d.repository.InsertBlockersBatch(ctx, blockers); err != nil {
 		return fmt.Errorf("an unexpected error occurred: %w", err)
 	}
+	d.cache.InvalidateDoctor(doctor.ID)
+	metrics.BlockersCreated(len(blockers))
 	return nil
 }
 
+// recurringBlockers generates the weekly blockers of the given request, from the next occurrence of its weekday
+// that hasn't started yet up to its end date. It stops once maxRecurringBlockers is exceeded.
+func (d defaultService) recurringBlockers(doctor *Doctor, request RecurringBlockPeriodRequest, now time.Time) []BlockPeriod {
+	weekday, _ := ParseWeekday(request.Weekday)
+	now = now.In(d.config.Location())
+	day := d.slotTime(now, 0)
+	day = day.AddDate(0, 0, (int(weekday)-int(day.Weekday())+7)%7)
+	if !d.slotTime(day, int(request.StartHour)).After(now) {
+		day = day.AddDate(0, 0, 7)
+	}
+	endDay := d.slotTime(request.EndDate, 0)
+	blockers := make([]BlockPeriod, 0)
+	for ; !day.After(endDay) && len(blockers) <= maxRecurringBlockers; day = day.AddDate(0, 0, 7) {
+		blockers = append(blockers, BlockPeriod{
+			Doctor:      doctor,
+			UUID:        uuid.New(),
+			StartDate:   d.slotTime(day, int(request.StartHour)),
+			EndDate:     d.slotTime(day, int(request.EndHour)),
+			Description: request.Description,
+			Inclusive:   request.Inclusive,
+		})
+	}
+	return blockers
+}
+
 // slotAvailable checks if the given slot is available or not.
 func (d defaultService) slotIsAvailable(entries []Entry, hour int32) bool {
 	for _, v := range entries {
@@ -206,42 +672,317 @@ func (d defaultService) slotIsAvailable(entries []Entry, hour int32) bool {
 	return false
 }
 
-func (d defaultService) InsertAppointment(ctx context.Context, user auth.User, appointmentRequest AppointmentRequest) error {
-	if err := appointmentRequest.Validate(); err != nil {
+// slotNotAvailableError tells whether the given slot is blocked by the doctor or already booked, since the
+// calendar only lists the available slots. The blockers are only loaded again once the booking is already rejected.
+func (d defaultService) slotNotAvailableError(ctx context.Context, doctor *Doctor, date time.Time, hour int32) error {
+	blockers, err := d.repository.ListBlockers(ctx, doctor.ID, date)
+	if err != nil {
 		return err
 	}
+	if d.hourIsBlocked(blockers, date, int(hour)) {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrSlotBlocked), apierrors.WithCode(CodeSlotBlocked), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	}
+	return apierrors.NewAPIError(apierrors.WithDetail(ErrSlotBooked), apierrors.WithCode(CodeSlotBooked), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+}
+
+// tooFarInAdvance checks if the given date is after the last day in which the appointments can be booked,
+// counted from today in the clinic timezone.
+func (d defaultService) tooFarInAdvance(date time.Time, now time.Time) bool {
+	lastDay := d.slotTime(now.In(d.config.Location()).AddDate(0, 0, d.config.MaxAdvanceDays()), 0)
+	return d.slotTime(date, 0).After(lastDay)
+}
+
+// checkLeadTime checks if the given slot starts in the future, at least the minimum lead time after now.
+func (d defaultService) checkLeadTime(startsAt time.Time, now time.Time) error {
+	if !startsAt.After(now) {
+		return apierrors.NewValidationError("date", "must be in the future")
+	}
+	if startsAt.Before(now.Add(d.config.MinLeadTime())) {
+		return apierrors.NewValidationError("date", "too soon")
+	}
+	return nil
+}
+
+func (d defaultService) InsertAppointment(ctx context.Context, user auth.User, appointmentRequest AppointmentRequest) error {
 	patient, err := d.repository.FindPatientByUserID(ctx, user.ID)
 	if err != nil {
 		return fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if patient == nil {
-		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyPatientCanCreateAppointment), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyPatientCanCreateAppointment), apierrors.WithCode(CodeOnlyPatientCanCreateAppointment), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	// a retry succeeds as the original request did, even if the request is no longer valid, e.g. the lead time passed
+	created, err := d.createdByIdempotencyKey(ctx, patient.ID, appointmentRequest.IdempotencyKey)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if created {
+		return nil
+	}
+	if err = appointmentRequest.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = d.checkLeadTime(appointmentRequest.StartsAt(), now); err != nil {
+		return err
+	}
+	if d.tooFarInAdvance(appointmentRequest.Date, now) {
+		return apierrors.NewValidationError("date", "too far in advance")
+	}
+	if !patient.ConsentGiven {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrConsentRequired), apierrors.WithCode(CodeConsentRequired), apierrors.WithHTTPStatusCode(http.StatusForbidden))
 	}
 	doctor, err := d.repository.FindDoctorByUUID(ctx, appointmentRequest.DoctorUUID)
 	if err != nil {
 		return fmt.Errorf("an unexpected error occurred: %w", err)
 	}
 	if doctor == nil {
-		return apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorNotFound), apierrors.WithCode(CodeDoctorNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	if !doctor.Active {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrDoctorInactive), apierrors.WithCode(CodeDoctorInactive), apierrors.WithHTTPStatusCode(http.StatusConflict))
 	}
-	entries, err := d.GetDoctorCalendar(ctx, user, appointmentRequest.DoctorUUID, appointmentRequest.Date)
+	entries, err := d.doctorCalendar(ctx, appointmentRequest.DoctorUUID, appointmentRequest.Date, false)
 	if err != nil {
 		return err
 	}
-	slotAvailable := d.slotIsAvailable(entries, appointmentRequest.Hour)
-	if !slotAvailable {
-		return apierrors.NewAPIError(apierrors.WithDetail(ErrSlotNotAvailable), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	// every slot covered by the appointment must be available, not only the first one
+	for hour := appointmentRequest.Hour; hour < appointmentRequest.Hour+appointmentRequest.Slots(); hour++ {
+		if !d.slotIsAvailable(entries, hour) {
+			// the slot may have been taken by a concurrent request with the same key
+			if created, findErr := d.createdByIdempotencyKey(ctx, patient.ID, appointmentRequest.IdempotencyKey); findErr == nil && created {
+				return nil
+			}
+			return d.slotNotAvailableError(ctx, doctor, appointmentRequest.Date, hour)
+		}
+	}
+	patientAppointments, err := d.repository.ListAppointmentsByPatientAndDate(ctx, patient.ID, appointmentRequest.Date)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	for hour := appointmentRequest.Hour; hour < appointmentRequest.Hour+appointmentRequest.Slots(); hour++ {
+		// a patient can't be at two appointments at the same time, even with different doctors
+		if d.hasAppointment(patientAppointments, appointmentRequest.Date, int(hour)) {
+			return apierrors.NewValidationError("hour", "patient already has an appointment at this hour")
+		}
 	}
-	date := appointmentRequest.Date
 	appointment := Appointment{
-		UUID:    uuid.New(),
-		Doctor:  doctor,
-		Patient: patient,
-		Date:    time.Date(date.Year(), date.Month(), date.Day(), int(appointmentRequest.Hour), 0, 0, 0, date.Location()),
+		UUID:     uuid.New(),
+		Doctor:   doctor,
+		Patient:  patient,
+		Date:     d.slotTime(appointmentRequest.Date, int(appointmentRequest.Hour)),
+		Notes:    appointmentRequest.Notes,
+		Duration: appointmentRequest.Slots(),
+	}
+	if appointmentRequest.IdempotencyKey != "" {
+		err = d.repository.InsertAppointmentWithIdempotencyKey(ctx, appointment, appointmentRequest.IdempotencyKey)
+	} else {
+		err = d.repository.InsertAppointment(ctx, appointment)
+	}
+	if database.IsUniqueViolation(err) {
+		// a concurrent request with the same key stored it first, so the appointment was already created by it
+		if created, findErr := d.createdByIdempotencyKey(ctx, patient.ID, appointmentRequest.IdempotencyKey); findErr == nil && created {
+			return nil
+		}
 	}
-	err = d.repository.InsertAppointment(ctx, appointment)
 	if err != nil {
 		return fmt.Errorf("an unexpected error occurred: %w", err)
 	}
+	d.cache.Invalidate(doctor.ID, appointmentRequest.Date)
+	metrics.AppointmentCreated(doctor.Specialty)
+	// the appointment is already booked, so a notification failure must not fail the request
+	if err = d.notifier.AppointmentCreated(ctx, appointment); err != nil {
+		logging.FromContext(ctx).Error(fmt.Errorf("an error occurred while notifying the appointment creation: %w", err))
+	}
+	return nil
+}
+
+// createdByIdempotencyKey checks whether an appointment was already created by a previous request of the given
+// patient with the given key, if any.
+func (d defaultService) createdByIdempotencyKey(ctx context.Context, patientID int64, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+	idempotencyKey, err := d.repository.FindByIdempotencyKey(ctx, patientID, key)
+	if err != nil {
+		return false, err
+	}
+	return idempotencyKey != nil, nil
+}
+
+func (d defaultService) GetAppointment(ctx context.Context, user auth.User, appointmentUUID uuid.UUID) (*Appointment, error) {
+	appointment, err := d.repository.FindAppointmentByUUID(ctx, appointmentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if appointment == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentNotFound), apierrors.WithCode(CodeAppointmentNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	doctor, err := d.repository.FindDoctorByID(ctx, appointment.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	patient, err := d.repository.FindPatientByID(ctx, appointment.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	ownedByPatient := user.Role == auth.PatientRole && patient != nil && patient.UserID == user.ID
+	ownedByDoctor := user.Role == auth.DoctorRole && doctor != nil && doctor.UserID == user.ID
+	if !ownedByPatient && !ownedByDoctor {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentAccessDenied), apierrors.WithCode(CodeAppointmentAccessDenied), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	appointment.Doctor = doctor
+	appointment.Patient = patient
+	appointment.Date = d.clinicTime(appointment.Date)
+	return appointment, nil
+}
+
+func (d defaultService) GetPatientAppointments(ctx context.Context, user auth.User, patientUUID uuid.UUID, status AppointmentStatus) ([]*Appointment, error) {
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanCheckItsAppointments), apierrors.WithCode(CodeOnlyDoctorCanCheckItsAppointments), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	patient, err := d.repository.FindPatientByUUID(ctx, patientUUID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if patient == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrPatientNotFound), apierrors.WithCode(CodePatientNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	appointments, err := d.repository.ListAppointmentsByDoctorAndPatient(ctx, doctor.ID, patient.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	// the patient is only visible to the doctors who treated it, so its existence isn't disclosed to the others
+	if len(appointments) == 0 {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrPatientNotFound), apierrors.WithCode(CodePatientNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	filtered := make([]*Appointment, 0, len(appointments))
+	for _, appointment := range appointments {
+		if status != "" && appointment.Status != status {
+			continue
+		}
+		appointment.Doctor = doctor
+		appointment.Patient = patient
+		appointment.Date = d.clinicTime(appointment.Date)
+		filtered = append(filtered, appointment)
+	}
+	return filtered, nil
+}
+
+func (d defaultService) CancelAppointment(ctx context.Context, user auth.User, appointmentUUID uuid.UUID) error {
+	patient, err := d.repository.FindPatientByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if patient == nil {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyPatientCanCancelAppointment), apierrors.WithCode(CodeOnlyPatientCanCancelAppointment), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	appointment, err := d.repository.FindAppointmentByUUID(ctx, appointmentUUID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if appointment == nil || appointment.PatientID != patient.ID {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentNotFound), apierrors.WithCode(CodeAppointmentNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	// completed and no-show appointments keep their outcome
+	if !appointment.Status.CanMoveTo(AppointmentCancelled) {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidStatusTransition), apierrors.WithCode(CodeInvalidStatusTransition), apierrors.WithHTTPStatusCode(http.StatusConflict))
+	}
+	cancelled, err := d.repository.CancelAppointment(ctx, appointment.ID, appointment.Version)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	// the appointment was updated by another request meanwhile
+	if !cancelled {
+		return appointmentVersionConflictError()
+	}
+	d.cache.Invalidate(appointment.DoctorID, d.clinicTime(appointment.Date))
+	return nil
+}
+
+// appointmentVersionConflictError is returned when the appointment was updated since it was read.
+func appointmentVersionConflictError() error {
+	return apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentVersionConflict), apierrors.WithCode(CodeAppointmentVersionConflict), apierrors.WithHTTPStatusCode(http.StatusConflict))
+}
+
+func (d defaultService) UpdatePatientContact(ctx context.Context, user auth.User, request PatientContactRequest) (*Patient, error) {
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+	patient, err := d.repository.FindPatientByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if patient == nil {
+		return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyPatientCanUpdateContact), apierrors.WithCode(CodeOnlyPatientCanUpdateContact), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	// the email was already checked along with the request
+	patient.Email, _ = ParseEmail(request.Email)
+	patient.MobilePhone = strings.TrimSpace(request.MobilePhone)
+	if err = d.repository.UpdatePatient(ctx, *patient); err != nil {
+		if database.IsUniqueViolation(err) {
+			return nil, apierrors.NewAPIError(apierrors.WithDetail(ErrEmailAlreadyInUse), apierrors.WithCode(CodeEmailAlreadyInUse), apierrors.WithHTTPStatusCode(http.StatusConflict))
+		}
+		return nil, fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	return patient, nil
+}
+
+func (d defaultService) UpdateAppointmentStatus(ctx context.Context, user auth.User, appointmentUUID uuid.UUID, request AppointmentStatusRequest) error {
+	if err := request.Validate(); err != nil {
+		return err
+	}
+	doctor, err := d.repository.FindDoctorByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if doctor == nil {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrOnlyDoctorCanUpdateStatus), apierrors.WithCode(CodeOnlyDoctorCanUpdateStatus), apierrors.WithHTTPStatusCode(http.StatusForbidden))
+	}
+	appointment, err := d.repository.FindAppointmentByUUID(ctx, appointmentUUID)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	if appointment == nil || appointment.DoctorID != doctor.ID {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentNotFound), apierrors.WithCode(CodeAppointmentNotFound), apierrors.WithHTTPStatusCode(http.StatusNotFound))
+	}
+	// the client decided the new status based on a stale appointment
+	if appointment.Version != request.Version {
+		return appointmentVersionConflictError()
+	}
+	status := AppointmentStatus(request.Status)
+	invalidTransition := apierrors.NewAPIError(apierrors.WithDetail(ErrInvalidStatusTransition), apierrors.WithCode(CodeInvalidStatusTransition), apierrors.WithHTTPStatusCode(http.StatusConflict))
+	if !appointment.Status.CanMoveTo(status) {
+		return invalidTransition
+	}
+	// the stored date is the clinic wall clock, and the patient may still show up until the appointment ends
+	appointmentEnd := d.clinicTime(appointment.Date).Add(time.Duration(appointment.Slots()) * slotDuration)
+	if status == AppointmentNoShow && !appointmentEnd.Before(time.Now()) {
+		return apierrors.NewAPIError(apierrors.WithDetail(ErrAppointmentNotHappenedYet), apierrors.WithCode(CodeAppointmentNotHappenedYet), apierrors.WithHTTPStatusCode(http.StatusBadRequest))
+	}
+	if status == AppointmentCancelled {
+		// cancelling is shared with the patients, so the slot is released the same way
+		cancelled, err := d.repository.CancelAppointment(ctx, appointment.ID, appointment.Version)
+		if err != nil {
+			return fmt.Errorf("an unexpected error occurred: %w", err)
+		}
+		if !cancelled {
+			return appointmentVersionConflictError()
+		}
+		d.cache.Invalidate(appointment.DoctorID, d.clinicTime(appointment.Date))
+		return nil
+	}
+	updated, err := d.repository.UpdateAppointmentStatus(ctx, appointment.ID, appointment.Version, status)
+	if err != nil {
+		return fmt.Errorf("an unexpected error occurred: %w", err)
+	}
+	// the appointment was updated by another request meanwhile, so the transition may no longer be allowed
+	if !updated {
+		return appointmentVersionConflictError()
+	}
 	return nil
 }
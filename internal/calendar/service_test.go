@@ -0,0 +1,172 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"hospital-booking/internal/apierrors"
+	"hospital-booking/internal/configs"
+	"hospital-booking/internal/mock"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+// cancelledLoopContext is seen as cancelled by the service loops through Err, while its Done channel never
+// closes, so the database queries issued before the loops still succeed.
+type cancelledLoopContext struct {
+	context.Context
+}
+
+func (c cancelledLoopContext) Err() error {
+	return context.Canceled
+}
+
+func TestServiceLoopsStopWhenCancelled(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	appointmentRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "patient_id", "date"}).AddRow(1, uuid.UUID{}, 1, 1, time.Date(2021, 8, 10, 9, 0, 0, 0, time.Local))
+	}
+	doctorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "specialty", "active"}).AddRow(1, uuid.UUID{}, 1, "John Doe", "doctor@hospital.com", "", "", true)
+	}
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		call          func(ctx context.Context, service Service) error
+	}{
+		{
+			name: "should stop getting the doctor's calendar",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUUIDResult(doctorRows()),
+				withListAppointmentsResult(appointmentRows()),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+			},
+			call: func(ctx context.Context, service Service) error {
+				_, err := service.GetDoctorCalendar(ctx, *mockPatientUser(), uuid.UUID{}, time.Date(2021, 8, 10, 0, 0, 0, 0, time.Local))
+				return err
+			},
+		},
+		{
+			name: "should stop getting the appointments",
+			dbMockOptions: []mock.DBResultOption{
+				withFindDoctorByUserIDResult(doctorRows()),
+				withListAppointmentsResult(appointmentRows()),
+				withListBlockersResult(sqlmock.NewRows([]string{"id", "uuid", "doctor_id", "start_date", "end_date", "description"})),
+				withFindPatientsByIDsResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+			},
+			call: func(ctx context.Context, service Service) error {
+				_, err := service.GetAppointments(ctx, *mockDoctorUser(), time.Date(2021, 8, 10, 0, 0, 0, 0, time.Local), AllEntries)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			err := tt.call(cancelledLoopContext{Context: context.Background()}, NewService(config, dbConn))
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error is incorrect, got %v, want %v", err, context.Canceled)
+			}
+			if err = dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+				t.Errorf("the queries before the loop should be issued: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckLeadTime(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	service := defaultService{config: config}
+	now := time.Date(2021, 8, 10, 10, 30, 0, 0, config.Location())
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "should accept a slot outside the lead time",
+			startsAt: time.Date(2021, 8, 10, 12, 0, 0, 0, config.Location()),
+			wantErr:  false,
+		},
+		{
+			name:     "should accept a slot starting exactly at the end of the lead time",
+			startsAt: now.Add(config.MinLeadTime()),
+			wantErr:  false,
+		},
+		{
+			name:     "should reject a slot inside the lead time",
+			startsAt: time.Date(2021, 8, 10, 11, 0, 0, 0, config.Location()),
+			wantErr:  true,
+		},
+		{
+			name:     "should reject a slot in the past",
+			startsAt: time.Date(2021, 8, 10, 9, 0, 0, 0, config.Location()),
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := service.checkLeadTime(tt.startsAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkLeadTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInsertAppointmentUsesTheRequestDoctorAndDate(t *testing.T) {
+	config := configs.MustLoad("./../../test/testdata/config_valid.json")
+	dbConn := mock.MustCreateConnectionMock()
+	doctorUUID := uuid.New()
+	mock.MockDBResults(dbConn,
+		withFindPatientByUserIDResult(sqlmock.NewRows([]string{"id", "uuid", "user_id", "name", "email", "mobile_phone", "consent_given"}).AddRow(1, uuid.UUID{}, 1, "Patient", "patient@hospital.com", "", true)),
+		func(dbConn mock.Connection) {
+			dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(findDoctorByUUIDQuery)).WithArgs(doctorUUID).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}))
+		},
+	)
+
+	// the doctor and the date are taken from the request itself, set by the handler from the URL
+	var writer Writer = NewService(config, dbConn)
+	err := writer.InsertAppointment(context.TODO(), *mockPatientUser(), AppointmentRequest{
+		Hour:       10,
+		DoctorUUID: doctorUUID,
+		Date:       time.Now().AddDate(0, 0, 2),
+	})
+
+	var apiErr *apierrors.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code() != CodeDoctorNotFound {
+		t.Errorf("InsertAppointment() error = %v, want %s", err, CodeDoctorNotFound)
+	}
+	if err = dbConn.SQLMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEntrySanitize(t *testing.T) {
+	patient := &Patient{ID: 1, Name: "Patient", Email: "patient@hospital.com", MobilePhone: "351123123123"}
+	entry := Entry{Hour: 10, Patient: patient}
+
+	sanitized := entry.Sanitize()
+	if sanitized.Patient.Email != "" || sanitized.Patient.MobilePhone != "" {
+		t.Errorf("patient contact details should be zeroed out, got %+v", sanitized.Patient)
+	}
+	if sanitized.Patient.Name != patient.Name {
+		t.Errorf("patient name is incorrect, got %s, want %s", sanitized.Patient.Name, patient.Name)
+	}
+	// the original patient may be shared with the cached calendars, so it must be kept intact
+	if patient.Email != "patient@hospital.com" || patient.MobilePhone != "351123123123" {
+		t.Errorf("the original patient should not be changed, got %+v", patient)
+	}
+	if sanitized := (Entry{Hour: 11, Available: true}).Sanitize(); sanitized.Patient != nil {
+		t.Errorf("entry without patient should be kept as is, got %+v", sanitized)
+	}
+}
@@ -0,0 +1,113 @@
+// Package compression contains the middleware used to compress the JSON responses.
+package compression
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+const (
+	AcceptEncodingHeader  = "Accept-Encoding"
+	ContentEncodingHeader = "Content-Encoding"
+)
+
+// acceptsGzip checks if the given Accept-Encoding header value accepts gzip, which isn't the case when its
+// quality is 0.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		parts := strings.Split(encoding, ";")
+		name := strings.TrimSpace(parts[0])
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range parts[1:] {
+			if value := strings.TrimSpace(param); strings.HasPrefix(value, "q=") && strings.Trim(value[2:], "0.") == "" {
+				rejected = true
+			}
+		}
+		return !rejected
+	}
+	return false
+}
+
+// isCompressible checks if the response with the given status and headers should be compressed. Only JSON
+// bodies not encoded yet are compressed.
+func isCompressible(status int, header http.Header) bool {
+	if status == http.StatusNoContent || status == http.StatusNotModified || header.Get(ContentEncodingHeader) != "" {
+		return false
+	}
+	contentType := strings.TrimSpace(strings.Split(header.Get("Content-Type"), ";")[0])
+	return strings.EqualFold(contentType, "application/json")
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	level       int
+	gz          *gzip.Writer
+	wroteHeader bool
+	compress    bool
+}
+
+// WriteHeader decides whether the response is compressed, based on the headers set so far.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.compress = isCompressible(status, w.Header())
+	if w.compress {
+		w.Header().Set(ContentEncodingHeader, "gzip")
+		// the length of the compressed body is unknown
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(p)
+	}
+	if w.gz == nil {
+		gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.level)
+		if err != nil {
+			return 0, err
+		}
+		w.gz = gz
+	}
+	return w.gz.Write(p)
+}
+
+// close flushes the compressed body, writing a valid gzip stream even if the body is empty.
+func (w *gzipResponseWriter) close() {
+	if !w.compress {
+		return
+	}
+	if w.gz == nil {
+		_, _ = w.Write(nil)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+// Middleware compresses the JSON responses with gzip at the given level, when the client accepts it through the
+// Accept-Encoding header. The Content-Type set by the handlers is kept, and Content-Encoding is added.
+func Middleware(level int) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", AcceptEncodingHeader)
+			if !acceptsGzip(r.Header.Get(AcceptEncodingHeader)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipResponseWriter{ResponseWriter: w, level: level}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestMiddleware(t *testing.T) {
+	body := "[" + strings.Repeat(`{"hour":9,"available":true},`, 100) + `{"hour":17,"available":true}]`
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{
+			name:           "should compress the response if the client accepts gzip",
+			acceptEncoding: "gzip, deflate, br",
+			wantGzip:       true,
+		},
+		{
+			name:           "should not compress the response if the client doesn't accept gzip",
+			acceptEncoding: "",
+			wantGzip:       false,
+		},
+		{
+			name:           "should not compress the response if the client rejects gzip",
+			acceptEncoding: "gzip;q=0, deflate",
+			wantGzip:       false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			router.Use(Middleware(gzip.DefaultCompression))
+			router.Use(middleware.SetHeader("Content-type", "application/json"))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(AcceptEncodingHeader, tt.acceptEncoding)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type is incorrect, got %q, want %q", got, "application/json")
+			}
+			gotGzip := recorder.Header().Get(ContentEncodingHeader) == "gzip"
+			if gotGzip != tt.wantGzip {
+				t.Fatalf("Content-Encoding is incorrect, got %q, want gzip %v", recorder.Header().Get(ContentEncodingHeader), tt.wantGzip)
+			}
+			got := recorder.Body.String()
+			if gotGzip {
+				reader, err := gzip.NewReader(recorder.Body)
+				if err != nil {
+					t.Fatalf("response body is not gzip encoded: %v", err)
+				}
+				decompressed, err := ioutil.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("an error occurred while decompressing the response body: %v", err)
+				}
+				got = string(decompressed)
+			}
+			if got != body {
+				t.Errorf("response body is incorrect, got %s, want %s", got, body)
+			}
+		})
+	}
+}
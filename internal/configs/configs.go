@@ -2,6 +2,10 @@
 package configs
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
@@ -10,28 +14,119 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type configData struct {
-	ServerPort     int32  `json:"port"`
-	DatabaseDSN    string `json:"database_dsn"`
-	DatabaseDriver string `json:"database_driver"`
-	PrivateKeyFile string `json:"private_key_file"`
+	ServerPort            int32    `json:"port"`
+	DatabaseDSN           string   `json:"database_dsn"`
+	DatabaseDriver        string   `json:"database_driver"`
+	PrivateKeyFile        string   `json:"private_key_file"`
+	LogFormat             string   `json:"log_format"`
+	LogLevel              string   `json:"log_level"`
+	QueryTimeout          int      `json:"query_timeout_seconds"`
+	MaxOpenConns          int      `json:"max_open_conns"`
+	MaxIdleConns          int      `json:"max_idle_conns"`
+	ConnMaxLifetime       int      `json:"conn_max_lifetime_seconds"`
+	AllowedOrigins        []string `json:"allowed_origins"`
+	LoginRateLimit        int      `json:"login_rate_limit"`
+	TokenIssuer           string   `json:"token_issuer"`
+	TokenAudience         string   `json:"token_audience"`
+	AllowedAudiences      []string `json:"allowed_audiences"`
+	Timezone              string   `json:"timezone"`
+	AppointmentWebhookURL string   `json:"appointment_webhook_url"`
+	MaxRequestBodyBytes   int64    `json:"max_request_body_bytes"`
+	MaxAdvanceDays        int      `json:"max_advance_days"`
+	MinLeadTimeHours      int      `json:"min_lead_time_hours"`
+	ShutdownTimeout       int      `json:"shutdown_timeout_seconds"`
+	RequestTimeout        int      `json:"request_timeout_seconds"`
+	AccessTokenTTL        int      `json:"access_token_ttl_seconds"`
+	RefreshTokenTTL       int      `json:"refresh_token_ttl_seconds"`
+	Algorithm             string   `json:"algorithm"`
+	DebugLogBodies        bool     `json:"debug_log_bodies"`
+	APIBasePath           string   `json:"api_base_path"`
+	CalendarCacheEnabled  bool     `json:"calendar_cache_enabled"`
+	TokenLeeway           *int     `json:"token_leeway_seconds"`
+	QueryRetries          *int     `json:"query_retries"`
+	ReadTimeout           int      `json:"read_timeout_seconds"`
+	WriteTimeout          int      `json:"write_timeout_seconds"`
+	IdleTimeout           int      `json:"idle_timeout_seconds"`
+	WorkingDays           []string `json:"working_days"`
+	PasswordHasher        string   `json:"password_hasher"`
 }
 
+const (
+	defaultQueryTimeout    = 5 * time.Second
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 3 * time.Minute
+	defaultLoginRateLimit  = 10
+	defaultTimezone        = "Local"
+	defaultLogLevel        = "INFO"
+	defaultMaxRequestBody  = 1 << 20
+	defaultMaxAdvanceDays  = 90
+	defaultMinLeadTime     = time.Hour
+	defaultShutdownTimeout = 5 * time.Second
+	defaultRequestTimeout  = 8 * time.Second
+	defaultAlgorithm       = "RS512"
+	defaultAPIBasePath     = "/api/v1"
+	defaultTokenLeeway     = 30 * time.Second
+	defaultQueryRetries    = 2
+	defaultReadTimeout     = 5 * time.Second
+	defaultWriteTimeout    = 10 * time.Second
+	defaultIdleTimeout     = 15 * time.Second
+	defaultPasswordHasher  = "bcrypt"
+)
+
 // Config holds the system configuration.
 type Config interface {
 	ServerPort() int32
 	DatabaseDSN() string
 	DatabaseDriver() string
 	PrivateKeyFile() string
-	PrivateKey() rsa.PrivateKey
+	PrivateKey() crypto.Signer
+	LogFormat() string
+	LogLevel() string
+	QueryTimeout() time.Duration
+	MaxOpenConns() int
+	MaxIdleConns() int
+	ConnMaxLifetime() time.Duration
+	AllowedOrigins() []string
+	LoginRateLimit() int
+	TokenIssuer() string
+	TokenAudience() string
+	AllowedAudiences() []string
+	Timezone() string
+	Location() *time.Location
+	AppointmentWebhookURL() string
+	MaxRequestBodyBytes() int64
+	MaxAdvanceDays() int
+	MinLeadTime() time.Duration
+	ShutdownTimeout() time.Duration
+	RequestTimeout() time.Duration
+	AccessTokenTTL() time.Duration
+	RefreshTokenTTL() time.Duration
+	Algorithm() string
+	DebugLogBodies() bool
+	APIBasePath() string
+	CalendarCacheEnabled() bool
+	TokenLeeway() time.Duration
+	QueryRetries() int
+	ReadTimeout() time.Duration
+	WriteTimeout() time.Duration
+	IdleTimeout() time.Duration
+	WorkingDays() []time.Weekday
+	PasswordHasher() string
 }
 
 type defaultConfig struct {
-	data       *configData
-	privateKey *rsa.PrivateKey
+	data        *configData
+	privateKey  crypto.Signer
+	location    *time.Location
+	workingDays []time.Weekday
 }
 
 func (c *defaultConfig) ServerPort() int32 {
@@ -50,33 +145,340 @@ func (c *defaultConfig) PrivateKeyFile() string {
 	return c.data.PrivateKeyFile
 }
 
-func (c *defaultConfig) PrivateKey() rsa.PrivateKey {
-	return *c.privateKey
+// PrivateKey gets the private key used to sign the tokens, either a RSA, an EC or an Ed25519 one.
+func (c *defaultConfig) PrivateKey() crypto.Signer {
+	return c.privateKey
 }
 
-func (c *defaultConfig) loadPrivateKey(configPath string) error {
+func (c *defaultConfig) LogFormat() string {
+	return c.data.LogFormat
+}
+
+// LogLevel gets the minimum level of the logged entries, either DEBUG, INFO, WARN or ERROR, which defaults to INFO.
+func (c *defaultConfig) LogLevel() string {
+	if c.data.LogLevel == "" {
+		return defaultLogLevel
+	}
+	return strings.ToUpper(c.data.LogLevel)
+}
+
+// QueryTimeout gets the timeout applied to the database queries, which defaults to 5 seconds.
+func (c *defaultConfig) QueryTimeout() time.Duration {
+	if c.data.QueryTimeout <= 0 {
+		return defaultQueryTimeout
+	}
+	return time.Duration(c.data.QueryTimeout) * time.Second
+}
+
+// QueryRetries gets how many times the reading queries are retried after a transient error, which defaults to 2.
+// The retries can be disabled by setting it to 0.
+func (c *defaultConfig) QueryRetries() int {
+	if c.data.QueryRetries == nil || *c.data.QueryRetries < 0 {
+		return defaultQueryRetries
+	}
+	return *c.data.QueryRetries
+}
+
+// MaxOpenConns gets the maximum number of open connections to the database, which defaults to 25.
+func (c *defaultConfig) MaxOpenConns() int {
+	if c.data.MaxOpenConns <= 0 {
+		return defaultMaxOpenConns
+	}
+	return c.data.MaxOpenConns
+}
+
+// MaxIdleConns gets the maximum number of idle connections to the database, which defaults to 5.
+func (c *defaultConfig) MaxIdleConns() int {
+	if c.data.MaxIdleConns <= 0 {
+		return defaultMaxIdleConns
+	}
+	return c.data.MaxIdleConns
+}
+
+// ConnMaxLifetime gets the maximum amount of time a connection may be reused, which defaults to 3 minutes.
+func (c *defaultConfig) ConnMaxLifetime() time.Duration {
+	if c.data.ConnMaxLifetime <= 0 {
+		return defaultConnMaxLifetime
+	}
+	return time.Duration(c.data.ConnMaxLifetime) * time.Second
+}
+
+// resolvePrivateKeyFile resolves the private key file path. Relative paths that can't be found from the
+// working directory are resolved against the directory of the given configuration file.
+func (c *defaultConfig) resolvePrivateKeyFile(configPath string) string {
 	path := c.PrivateKeyFile()
-	if _, err := os.Stat(c.PrivateKeyFile()); os.IsNotExist(err) {
-		path = fmt.Sprintf("%s/%s", configPath, path)
+	if _, err := os.Stat(path); os.IsNotExist(err) && !filepath.IsAbs(path) && configPath != "" {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	return path
+}
+
+// parsePrivateKey parses the given DER encoded private key, either a PKCS1 RSA key, a SEC1 EC key or a PKCS8
+// RSA, EC or Ed25519 key.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if pk, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return pk, nil
+	}
+	if pk, err := x509.ParseECPrivateKey(der); err == nil {
+		return pk, nil
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, errors.New("the given private key is neither a PKCS1, a SEC1 nor a PKCS8 encoded key")
+	}
+	switch pk := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		return pk, nil
+	case *ecdsa.PrivateKey:
+		return pk, nil
+	case ed25519.PrivateKey:
+		return pk, nil
+	}
+	return nil, errors.New("the given private key is neither a RSA, an EC nor an Ed25519 key")
+}
+
+// AllowedOrigins gets the origins allowed to perform cross-origin requests.
+func (c *defaultConfig) AllowedOrigins() []string {
+	return c.data.AllowedOrigins
+}
+
+// LoginRateLimit gets the number of login attempts allowed per IP per minute, which defaults to 10.
+func (c *defaultConfig) LoginRateLimit() int {
+	if c.data.LoginRateLimit <= 0 {
+		return defaultLoginRateLimit
+	}
+	return c.data.LoginRateLimit
+}
+
+// TokenIssuer gets the issuer of the tokens, if it has been overridden.
+func (c *defaultConfig) TokenIssuer() string {
+	return c.data.TokenIssuer
+}
+
+// TokenAudience gets the audience of the tokens, if it has been overridden.
+func (c *defaultConfig) TokenAudience() string {
+	return c.data.TokenAudience
+}
+
+// AllowedAudiences gets the audiences accepted besides the one of the tokens, e.g. when the same key serves
+// several apps.
+func (c *defaultConfig) AllowedAudiences() []string {
+	return c.data.AllowedAudiences
+}
+
+// Timezone gets the name of the clinic timezone, which defaults to the server one.
+func (c *defaultConfig) Timezone() string {
+	if c.data.Timezone == "" {
+		return defaultTimezone
+	}
+	return c.data.Timezone
+}
+
+// Location gets the clinic timezone, in which the calendar slots are computed.
+func (c *defaultConfig) Location() *time.Location {
+	return c.location
+}
+
+// AppointmentWebhookURL gets the URL notified when appointments are created, if there is one.
+func (c *defaultConfig) AppointmentWebhookURL() string {
+	return c.data.AppointmentWebhookURL
+}
+
+// MaxRequestBodyBytes gets the maximum size of the request bodies, which defaults to 1MB.
+func (c *defaultConfig) MaxRequestBodyBytes() int64 {
+	if c.data.MaxRequestBodyBytes <= 0 {
+		return defaultMaxRequestBody
+	}
+	return c.data.MaxRequestBodyBytes
+}
+
+// MaxAdvanceDays gets how many days in advance the appointments can be booked, which defaults to 90.
+func (c *defaultConfig) MaxAdvanceDays() int {
+	if c.data.MaxAdvanceDays <= 0 {
+		return defaultMaxAdvanceDays
+	}
+	return c.data.MaxAdvanceDays
+}
+
+// MinLeadTime gets how long before their start the appointments must be booked, which defaults to 1 hour.
+func (c *defaultConfig) MinLeadTime() time.Duration {
+	if c.data.MinLeadTimeHours <= 0 {
+		return defaultMinLeadTime
+	}
+	return time.Duration(c.data.MinLeadTimeHours) * time.Hour
+}
+
+// ShutdownTimeout gets how long the in-flight requests are waited for on shutdown, which defaults to 5 seconds.
+func (c *defaultConfig) ShutdownTimeout() time.Duration {
+	if c.data.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(c.data.ShutdownTimeout) * time.Second
+}
+
+// RequestTimeout gets how long a request can be handled, which defaults to 8 seconds, within the server write timeout.
+func (c *defaultConfig) RequestTimeout() time.Duration {
+	if c.data.RequestTimeout <= 0 {
+		return defaultRequestTimeout
+	}
+	return time.Duration(c.data.RequestTimeout) * time.Second
+}
+
+// AccessTokenTTL gets how long the access tokens are valid, if it has been overridden.
+func (c *defaultConfig) AccessTokenTTL() time.Duration {
+	return time.Duration(c.data.AccessTokenTTL) * time.Second
+}
+
+// RefreshTokenTTL gets how long the refresh tokens are valid, if it has been overridden.
+func (c *defaultConfig) RefreshTokenTTL() time.Duration {
+	return time.Duration(c.data.RefreshTokenTTL) * time.Second
+}
+
+// TokenLeeway gets how much the clocks may drift while checking the expiration and not before times of the tokens,
+// which defaults to 30 seconds. It can be disabled by setting it to 0.
+func (c *defaultConfig) TokenLeeway() time.Duration {
+	if c.data.TokenLeeway == nil || *c.data.TokenLeeway < 0 {
+		return defaultTokenLeeway
 	}
+	return time.Duration(*c.data.TokenLeeway) * time.Second
+}
+
+// Algorithm gets the algorithm used to sign the tokens, which must suit the private key and defaults to RS512.
+func (c *defaultConfig) Algorithm() string {
+	if c.data.Algorithm == "" {
+		return defaultAlgorithm
+	}
+	return c.data.Algorithm
+}
+
+// DebugLogBodies tells whether the request and response bodies must be logged, which is disabled by default.
+func (c *defaultConfig) DebugLogBodies() bool {
+	return c.data.DebugLogBodies
+}
+
+// CalendarCacheEnabled tells whether the doctors calendars are cached for a short while, which is disabled by
+// default.
+func (c *defaultConfig) CalendarCacheEnabled() bool {
+	return c.data.CalendarCacheEnabled
+}
+
+// APIBasePath gets the path under which the API routes are mounted, which defaults to /api/v1.
+func (c *defaultConfig) APIBasePath() string {
+	if c.data.APIBasePath == "" {
+		return defaultAPIBasePath
+	}
+	return c.data.APIBasePath
+}
+
+// ReadTimeout gets how long the server waits to read a whole request, including its body, which defaults to 5 seconds.
+func (c *defaultConfig) ReadTimeout() time.Duration {
+	if c.data.ReadTimeout <= 0 {
+		return defaultReadTimeout
+	}
+	return time.Duration(c.data.ReadTimeout) * time.Second
+}
+
+// WriteTimeout gets how long the server waits to write a response, which defaults to 10 seconds.
+func (c *defaultConfig) WriteTimeout() time.Duration {
+	if c.data.WriteTimeout <= 0 {
+		return defaultWriteTimeout
+	}
+	return time.Duration(c.data.WriteTimeout) * time.Second
+}
+
+// IdleTimeout gets how long the server keeps an idle keep-alive connection open, which defaults to 15 seconds.
+func (c *defaultConfig) IdleTimeout() time.Duration {
+	if c.data.IdleTimeout <= 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(c.data.IdleTimeout) * time.Second
+}
+
+// WorkingDays gets the weekdays in which the doctors work by default, which are all of them unless configured.
+func (c *defaultConfig) WorkingDays() []time.Weekday {
+	return c.workingDays
+}
+
+// PasswordHasher gets the algorithm used to hash the new passwords, either bcrypt or argon2id, which defaults to
+// bcrypt. The passwords already hashed keep being compared with the algorithm they were hashed with.
+func (c *defaultConfig) PasswordHasher() string {
+	if c.data.PasswordHasher == "" {
+		return defaultPasswordHasher
+	}
+	return strings.ToLower(c.data.PasswordHasher)
+}
+
+// parseWorkingDays parses the configured working days, given by their names ignoring case, e.g. monday.
+func (c *defaultConfig) parseWorkingDays() error {
+	if len(c.data.WorkingDays) == 0 {
+		c.workingDays = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+		return nil
+	}
+	c.workingDays = make([]time.Weekday, 0, len(c.data.WorkingDays))
+	for _, name := range c.data.WorkingDays {
+		weekday, found := parseWeekday(name)
+		if !found {
+			return fmt.Errorf("the working day %q must be a weekday name, e.g. monday", name)
+		}
+		c.workingDays = append(c.workingDays, weekday)
+	}
+	return nil
+}
+
+// parseWeekday parses the given weekday name, ignoring case.
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), strings.TrimSpace(name)) {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+func (c *defaultConfig) loadPrivateKey(configPath string) error {
+	path := c.resolvePrivateKeyFile(configPath)
 	pemFile, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return fmt.Errorf("an error occurred while loading private key file %q (resolved to %q): %w", c.PrivateKeyFile(), path, err)
 	}
 	privatePem, _ := pem.Decode(pemFile)
-	var parsedKey interface{}
-	parsedKey, err = x509.ParsePKCS1PrivateKey(privatePem.Bytes)
+	if privatePem == nil {
+		return fmt.Errorf("the private key file %q (resolved to %q) is not PEM encoded", c.PrivateKeyFile(), path)
+	}
+	pk, err := parsePrivateKey(privatePem.Bytes)
 	if err != nil {
-		return err
+		return fmt.Errorf("an error occurred while parsing private key file %q (resolved to %q): %w", c.PrivateKeyFile(), path, err)
 	}
-	pk, isPrivateKey := parsedKey.(*rsa.PrivateKey)
-	if !isPrivateKey {
-		return errors.New("the given private key is not valid")
+	if algorithm := keyAlgorithm(pk); algorithm != c.Algorithm() {
+		return fmt.Errorf("the private key file %q (resolved to %q) signs with %s, not %s", c.PrivateKeyFile(), path, algorithm, c.Algorithm())
 	}
 	c.privateKey = pk
 	return nil
 }
 
+// curveAlgorithms maps the elliptic curves supported to the algorithms used to sign the tokens with them.
+var curveAlgorithms = map[elliptic.Curve]string{
+	elliptic.P256(): "ES256",
+	elliptic.P384(): "ES384",
+	elliptic.P521(): "ES512",
+}
+
+// keyAlgorithm gets the algorithm used to sign the tokens with the given private key, based on its type.
+func keyAlgorithm(privateKey crypto.Signer) string {
+	switch pk := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return "RS512"
+	case *ecdsa.PrivateKey:
+		return curveAlgorithms[pk.Curve]
+	case ed25519.PrivateKey:
+		return "EdDSA"
+	}
+	return ""
+}
+
 // Load loads the given configuration file.
 func Load(configPath string) (Config, error) {
 	data := &configData{}
@@ -88,6 +490,81 @@ func Load(configPath string) (Config, error) {
 	data.DatabaseDriver = os.Getenv("DATABASE_DRIVER")
 	data.ServerPort = int32(serverPort)
 	data.PrivateKeyFile = os.Getenv("PRIVATE_KEY_FILE")
+	data.LogFormat = os.Getenv("LOG_FORMAT")
+	data.LogLevel = os.Getenv("LOG_LEVEL")
+	data.PasswordHasher = os.Getenv("PASSWORD_HASHER")
+	if queryTimeout, err := strconv.Atoi(os.Getenv("QUERY_TIMEOUT_SECONDS")); err == nil {
+		data.QueryTimeout = queryTimeout
+	}
+	if maxOpenConns, err := strconv.Atoi(os.Getenv("MAX_OPEN_CONNS")); err == nil {
+		data.MaxOpenConns = maxOpenConns
+	}
+	if maxIdleConns, err := strconv.Atoi(os.Getenv("MAX_IDLE_CONNS")); err == nil {
+		data.MaxIdleConns = maxIdleConns
+	}
+	if connMaxLifetime, err := strconv.Atoi(os.Getenv("CONN_MAX_LIFETIME_SECONDS")); err == nil {
+		data.ConnMaxLifetime = connMaxLifetime
+	}
+	if loginRateLimit, err := strconv.Atoi(os.Getenv("LOGIN_RATE_LIMIT")); err == nil {
+		data.LoginRateLimit = loginRateLimit
+	}
+	if maxRequestBodyBytes, err := strconv.ParseInt(os.Getenv("MAX_REQUEST_BODY_BYTES"), 10, 64); err == nil {
+		data.MaxRequestBodyBytes = maxRequestBodyBytes
+	}
+	if maxAdvanceDays, err := strconv.Atoi(os.Getenv("MAX_ADVANCE_DAYS")); err == nil {
+		data.MaxAdvanceDays = maxAdvanceDays
+	}
+	if minLeadTimeHours, err := strconv.Atoi(os.Getenv("MIN_LEAD_TIME_HOURS")); err == nil {
+		data.MinLeadTimeHours = minLeadTimeHours
+	}
+	if shutdownTimeout, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")); err == nil {
+		data.ShutdownTimeout = shutdownTimeout
+	}
+	if requestTimeout, err := strconv.Atoi(os.Getenv("REQUEST_TIMEOUT_SECONDS")); err == nil {
+		data.RequestTimeout = requestTimeout
+	}
+	if accessTokenTTL, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_TTL_SECONDS")); err == nil {
+		data.AccessTokenTTL = accessTokenTTL
+	}
+	if refreshTokenTTL, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_TTL_SECONDS")); err == nil {
+		data.RefreshTokenTTL = refreshTokenTTL
+	}
+	if tokenLeeway, err := strconv.Atoi(os.Getenv("TOKEN_LEEWAY_SECONDS")); err == nil {
+		data.TokenLeeway = &tokenLeeway
+	}
+	if queryRetries, err := strconv.Atoi(os.Getenv("QUERY_RETRIES")); err == nil {
+		data.QueryRetries = &queryRetries
+	}
+	if debugLogBodies, err := strconv.ParseBool(os.Getenv("DEBUG_LOG_BODIES")); err == nil {
+		data.DebugLogBodies = debugLogBodies
+	}
+	if calendarCacheEnabled, err := strconv.ParseBool(os.Getenv("CALENDAR_CACHE_ENABLED")); err == nil {
+		data.CalendarCacheEnabled = calendarCacheEnabled
+	}
+	data.Algorithm = os.Getenv("TOKEN_ALGORITHM")
+	data.APIBasePath = os.Getenv("API_BASE_PATH")
+	if readTimeout, err := strconv.Atoi(os.Getenv("READ_TIMEOUT_SECONDS")); err == nil {
+		data.ReadTimeout = readTimeout
+	}
+	if writeTimeout, err := strconv.Atoi(os.Getenv("WRITE_TIMEOUT_SECONDS")); err == nil {
+		data.WriteTimeout = writeTimeout
+	}
+	if idleTimeout, err := strconv.Atoi(os.Getenv("IDLE_TIMEOUT_SECONDS")); err == nil {
+		data.IdleTimeout = idleTimeout
+	}
+	data.TokenIssuer = os.Getenv("TOKEN_ISSUER")
+	data.TokenAudience = os.Getenv("TOKEN_AUDIENCE")
+	data.Timezone = os.Getenv("TIMEZONE")
+	data.AppointmentWebhookURL = os.Getenv("APPOINTMENT_WEBHOOK_URL")
+	if allowedOrigins := os.Getenv("ALLOWED_ORIGINS"); allowedOrigins != "" {
+		data.AllowedOrigins = strings.Split(allowedOrigins, ",")
+	}
+	if allowedAudiences := os.Getenv("ALLOWED_AUDIENCES"); allowedAudiences != "" {
+		data.AllowedAudiences = strings.Split(allowedAudiences, ",")
+	}
+	if workingDays := os.Getenv("WORKING_DAYS"); workingDays != "" {
+		data.WorkingDays = strings.Split(workingDays, ",")
+	}
 	if configPath != "" {
 		configFile, err := os.Open(configPath)
 		if err != nil {
@@ -99,6 +576,27 @@ func Load(configPath string) (Config, error) {
 		}
 	}
 	configuration := &defaultConfig{data: data}
+	if basePath := configuration.APIBasePath(); !strings.HasPrefix(basePath, "/") || strings.HasSuffix(basePath, "/") {
+		return nil, fmt.Errorf("the API base path %q must start with a slash and not end with one", basePath)
+	}
+	switch configuration.LogLevel() {
+	case "DEBUG", "INFO", "WARN", "ERROR":
+	default:
+		return nil, fmt.Errorf("the log level %q must be one of DEBUG, INFO, WARN or ERROR", configuration.LogLevel())
+	}
+	switch configuration.PasswordHasher() {
+	case "bcrypt", "argon2id":
+	default:
+		return nil, fmt.Errorf("the password hasher %q must be either bcrypt or argon2id", configuration.PasswordHasher())
+	}
+	if err := configuration.parseWorkingDays(); err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(configuration.Timezone())
+	if err != nil {
+		return nil, fmt.Errorf("an error occurred while loading timezone %q: %w", configuration.Timezone(), err)
+	}
+	configuration.location = location
 	if configuration.PrivateKeyFile() != "" {
 		if err := configuration.loadPrivateKey(configPath); err != nil {
 			return nil, err
@@ -1,7 +1,10 @@
 package configs
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -9,9 +12,10 @@ func TestLoad(t *testing.T) {
 		configPath string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name            string
+		args            args
+		wantErr         bool
+		wantErrContains string
 	}{
 		{
 			name: "should load the configuration file without errors",
@@ -20,6 +24,43 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "should load the configuration with a PKCS8 private key",
+			args: args{
+				configPath: "./../../test/testdata/config_pkcs8_private_key.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should load the configuration with an EC private key",
+			args: args{
+				configPath: "./../../test/testdata/config_ec_private_key.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should load the configuration with an Ed25519 private key",
+			args: args{
+				configPath: "./../../test/testdata/config_ed25519_private_key.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should not load the configuration because the private key does not suit the algorithm",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_algorithm.json",
+			},
+			wantErr:         true,
+			wantErrContains: "signs with RS512, not ES256",
+		},
+		{
+			name: "should not load the configuration because the API base path is not a valid path",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_api_base_path.json",
+			},
+			wantErr:         true,
+			wantErrContains: "must start with a slash",
+		},
 		{
 			name: "should not load the configuration due to wrong path",
 			args: args{
@@ -39,7 +80,54 @@ func TestLoad(t *testing.T) {
 			args: args{
 				configPath: "./../../test/testdata/config_invalid_private_key.json",
 			},
-			wantErr: true,
+			wantErr:         true,
+			wantErrContains: "./../../test/testdata/invalid.pem",
+		},
+		{
+			name: "should load the private key file relative to the configuration file",
+			args: args{
+				configPath: "./../../test/testdata/config_relative_private_key.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should load the configuration with the clinic timezone",
+			args: args{
+				configPath: "./../../test/testdata/config_timezone.json",
+			},
+			wantErr: false,
+		},
+		{
+			name: "should not load the configuration due to invalid timezone",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_timezone.json",
+			},
+			wantErr:         true,
+			wantErrContains: "Invalid/Timezone",
+		},
+		{
+			name: "should not load the configuration due to invalid log level",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_log_level.json",
+			},
+			wantErr:         true,
+			wantErrContains: "VERBOSE",
+		},
+		{
+			name: "should not load the configuration due to invalid working days",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_working_days.json",
+			},
+			wantErr:         true,
+			wantErrContains: "funday",
+		},
+		{
+			name: "should not load the configuration due to invalid password hasher",
+			args: args{
+				configPath: "./../../test/testdata/config_invalid_password_hasher.json",
+			},
+			wantErr:         true,
+			wantErrContains: "md5",
 		},
 	}
 	for _, tt := range tests {
@@ -49,6 +137,205 @@ func TestLoad(t *testing.T) {
 				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErrContains != "" && !strings.Contains(err.Error(), tt.wantErrContains) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.wantErrContains)
+			}
 		})
 	}
 }
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name       string
+		configPath string
+		want       string
+	}{
+		{
+			name:       "should default to the server timezone",
+			configPath: "./../../test/testdata/config_valid.json",
+			want:       "Local",
+		},
+		{
+			name:       "should load the configured timezone",
+			configPath: "./../../test/testdata/config_timezone.json",
+			want:       "America/Sao_Paulo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := MustLoad(tt.configPath)
+			if got := config.Location().String(); got != tt.want {
+				t.Errorf("Location() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxRequestBodyBytes(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.MaxRequestBodyBytes(); got != 1<<20 {
+		t.Errorf("MaxRequestBodyBytes() = %v, want %v", got, 1<<20)
+	}
+}
+
+func TestMaxAdvanceDays(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.MaxAdvanceDays(); got != 90 {
+		t.Errorf("MaxAdvanceDays() = %v, want %v", got, 90)
+	}
+}
+
+func TestMinLeadTime(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.MinLeadTime(); got != time.Hour {
+		t.Errorf("MinLeadTime() = %v, want %v", got, time.Hour)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.ShutdownTimeout(); got != 5*time.Second {
+		t.Errorf("ShutdownTimeout() = %v, want %v", got, 5*time.Second)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.RequestTimeout(); got != 8*time.Second {
+		t.Errorf("RequestTimeout() = %v, want %v", got, 8*time.Second)
+	}
+}
+
+func TestTokenTTL(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_token_ttl.json")
+	if got := config.AccessTokenTTL(); got != time.Hour {
+		t.Errorf("AccessTokenTTL() = %v, want %v", got, time.Hour)
+	}
+	if got := config.RefreshTokenTTL(); got != 48*time.Hour {
+		t.Errorf("RefreshTokenTTL() = %v, want %v", got, 48*time.Hour)
+	}
+	config = MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.AccessTokenTTL(); got != 0 {
+		t.Errorf("AccessTokenTTL() = %v, want %v", got, 0)
+	}
+}
+
+func TestTokenLeeway(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.TokenLeeway(); got != 30*time.Second {
+		t.Errorf("TokenLeeway() = %v, want %v", got, 30*time.Second)
+	}
+	config = MustLoad("./../../test/testdata/config_no_token_leeway.json")
+	if got := config.TokenLeeway(); got != 0 {
+		t.Errorf("TokenLeeway() = %v, want %v", got, 0)
+	}
+}
+
+func TestAllowedAudiences(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.AllowedAudiences(); len(got) != 0 {
+		t.Errorf("AllowedAudiences() = %v, want none", got)
+	}
+	config = MustLoad("./../../test/testdata/config_token_audience.json")
+	want := []string{"hospital_booking_mobile", "hospital_booking_web"}
+	if got := config.AllowedAudiences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedAudiences() = %v, want %v", got, want)
+	}
+}
+
+func TestQueryRetries(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.QueryRetries(); got != 2 {
+		t.Errorf("QueryRetries() = %v, want %v", got, 2)
+	}
+	config = MustLoad("./../../test/testdata/config_database.json")
+	if got := config.QueryRetries(); got != 3 {
+		t.Errorf("QueryRetries() = %v, want %v", got, 3)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.ReadTimeout(); got != 5*time.Second {
+		t.Errorf("ReadTimeout() = %v, want %v", got, 5*time.Second)
+	}
+	if got := config.WriteTimeout(); got != 10*time.Second {
+		t.Errorf("WriteTimeout() = %v, want %v", got, 10*time.Second)
+	}
+	if got := config.IdleTimeout(); got != 15*time.Second {
+		t.Errorf("IdleTimeout() = %v, want %v", got, 15*time.Second)
+	}
+	config = MustLoad("./../../test/testdata/config_server_timeouts.json")
+	if got := config.ReadTimeout(); got != 30*time.Second {
+		t.Errorf("ReadTimeout() = %v, want %v", got, 30*time.Second)
+	}
+	if got := config.WriteTimeout(); got != time.Minute {
+		t.Errorf("WriteTimeout() = %v, want %v", got, time.Minute)
+	}
+	if got := config.IdleTimeout(); got != 2*time.Minute {
+		t.Errorf("IdleTimeout() = %v, want %v", got, 2*time.Minute)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.LogLevel(); got != "INFO" {
+		t.Errorf("LogLevel() = %v, want %v", got, "INFO")
+	}
+	config = MustLoad("./../../test/testdata/config_log_level.json")
+	if got := config.LogLevel(); got != "WARN" {
+		t.Errorf("LogLevel() = %v, want %v", got, "WARN")
+	}
+}
+
+func TestWorkingDays(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.WorkingDays(); len(got) != 7 {
+		t.Errorf("WorkingDays() = %v, want all the weekdays", got)
+	}
+	config = MustLoad("./../../test/testdata/config_working_days.json")
+	want := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	if got := config.WorkingDays(); !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkingDays() = %v, want %v", got, want)
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.PasswordHasher(); got != "bcrypt" {
+		t.Errorf("PasswordHasher() = %v, want %v", got, "bcrypt")
+	}
+	config = MustLoad("./../../test/testdata/config_password_hasher.json")
+	if got := config.PasswordHasher(); got != "argon2id" {
+		t.Errorf("PasswordHasher() = %v, want %v", got, "argon2id")
+	}
+}
+
+func TestDebugLogBodies(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.DebugLogBodies(); got {
+		t.Errorf("DebugLogBodies() = %v, want %v", got, false)
+	}
+}
+
+func TestCalendarCacheEnabled(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.CalendarCacheEnabled(); got {
+		t.Errorf("CalendarCacheEnabled() = %v, want %v", got, false)
+	}
+	config = MustLoad("./../../test/testdata/config_calendar_cache.json")
+	if got := config.CalendarCacheEnabled(); !got {
+		t.Errorf("CalendarCacheEnabled() = %v, want %v", got, true)
+	}
+}
+
+func TestAPIBasePath(t *testing.T) {
+	config := MustLoad("./../../test/testdata/config_valid.json")
+	if got := config.APIBasePath(); got != "/api/v1" {
+		t.Errorf("APIBasePath() = %v, want %v", got, "/api/v1")
+	}
+	config = MustLoad("./../../test/testdata/config_api_base_path.json")
+	if got := config.APIBasePath(); got != "/api/v2" {
+		t.Errorf("APIBasePath() = %v, want %v", got, "/api/v2")
+	}
+}
@@ -0,0 +1,43 @@
+// Package contenttype contains the middleware used to check the media type of the request bodies.
+package contenttype
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/apierrors"
+	"mime"
+	"net/http"
+)
+
+// JSON is the only media type accepted for the request bodies.
+const JSON = "application/json"
+
+const (
+	ErrUnsupportedMediaType  = "unsupported media type, expected application/json"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+)
+
+// writeMethods are the methods whose request bodies are decoded as JSON by the handlers.
+var writeMethods = map[string]bool{
+	http.MethodPost:  true,
+	http.MethodPut:   true,
+	http.MethodPatch: true,
+}
+
+// Middleware rejects the write requests whose body isn't JSON with a 415 status, describing the reason through an
+// APIError body, instead of letting the handlers fail to decode it. Parameters as the charset are allowed, and
+// requests without a body are let through, as there is nothing to decode.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !writeMethods[r.Method] || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != JSON {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			_ = json.NewEncoder(w).Encode(apierrors.NewAPIError(apierrors.WithDetail(ErrUnsupportedMediaType), apierrors.WithCode(CodeUnsupportedMediaType)))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
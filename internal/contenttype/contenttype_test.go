@@ -0,0 +1,95 @@
+package contenttype
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		contentType  string
+		body         string
+		want         int
+		wantResponse string
+	}{
+		{
+			name:         "should pass a JSON body through",
+			method:       http.MethodPost,
+			contentType:  "application/json",
+			body:         `{"hour":9}`,
+			want:         http.StatusOK,
+			wantResponse: "done",
+		},
+		{
+			name:         "should pass a JSON body along with its charset through",
+			method:       http.MethodPut,
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"hour":9}`,
+			want:         http.StatusOK,
+			wantResponse: "done",
+		},
+		{
+			name:         "should reject a plain text body",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			body:         `{"hour":9}`,
+			want:         http.StatusUnsupportedMediaType,
+			wantResponse: "{\"message\":\"unsupported media type, expected application/json\",\"code\":\"UNSUPPORTED_MEDIA_TYPE\"}\n",
+		},
+		{
+			name:         "should reject a form encoded body",
+			method:       http.MethodPut,
+			contentType:  "application/x-www-form-urlencoded",
+			body:         "hour=9",
+			want:         http.StatusUnsupportedMediaType,
+			wantResponse: "{\"message\":\"unsupported media type, expected application/json\",\"code\":\"UNSUPPORTED_MEDIA_TYPE\"}\n",
+		},
+		{
+			name:         "should reject a body without a content type",
+			method:       http.MethodPost,
+			body:         `{"hour":9}`,
+			want:         http.StatusUnsupportedMediaType,
+			wantResponse: "{\"message\":\"unsupported media type, expected application/json\",\"code\":\"UNSUPPORTED_MEDIA_TYPE\"}\n",
+		},
+		{
+			name:         "should pass a write request without a body through",
+			method:       http.MethodPost,
+			contentType:  "text/plain",
+			want:         http.StatusOK,
+			wantResponse: "done",
+		},
+		{
+			name:         "should pass a read request through",
+			method:       http.MethodGet,
+			contentType:  "text/plain",
+			body:         "hour=9",
+			want:         http.StatusOK,
+			wantResponse: "done",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("done"))
+			}))
+			req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package cors contains the middleware used to allow cross-origin requests from the configured origins.
+package cors
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	AllowOriginHeader  = "Access-Control-Allow-Origin"
+	AllowMethodsHeader = "Access-Control-Allow-Methods"
+	AllowHeadersHeader = "Access-Control-Allow-Headers"
+	RequestMethod      = "Access-Control-Request-Method"
+)
+
+var (
+	defaultMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	defaultHeaders = []string{"Authorization", "Content-Type", "Idempotency-Key"}
+)
+
+type Option func(o *options)
+
+type options struct {
+	origins []string
+	methods []string
+	headers []string
+}
+
+// WithAllowedOrigins sets the origins allowed to perform cross-origin requests. The "*" origin allows any origin.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(o *options) {
+		o.origins = origins
+	}
+}
+
+// WithAllowedMethods sets the methods allowed in cross-origin requests.
+func WithAllowedMethods(methods ...string) Option {
+	return func(o *options) {
+		o.methods = methods
+	}
+}
+
+// WithAllowedHeaders sets the headers allowed in cross-origin requests.
+func WithAllowedHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.headers = headers
+	}
+}
+
+func (o *options) originAllowed(origin string) bool {
+	for _, allowed := range o.origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware adds the CORS headers to the requests coming from the allowed origins. By default, no origin
+// is allowed.
+//
+// Preflight requests are answered with a 204 status, without reaching the next handlers.
+func Middleware(opts ...Option) func(next http.Handler) http.Handler {
+	o := &options{methods: defaultMethods, headers: defaultHeaders}
+	for _, opt := range opts {
+		opt(o)
+	}
+	allowedMethods := strings.Join(o.methods, ", ")
+	allowedHeaders := strings.Join(o.headers, ", ")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			origin := request.Header.Get("Origin")
+			writer.Header().Add("Vary", "Origin")
+			allowed := origin != "" && o.originAllowed(origin)
+			if allowed {
+				writer.Header().Set(AllowOriginHeader, origin)
+			}
+			if request.Method == http.MethodOptions && request.Header.Get(RequestMethod) != "" {
+				if allowed {
+					writer.Header().Set(AllowMethodsHeader, allowedMethods)
+					writer.Header().Set(AllowHeadersHeader, allowedHeaders)
+				}
+				writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		origin          string
+		want            int
+		wantAllowOrigin string
+	}{
+		{
+			name:            "should answer the preflight request from an allowed origin",
+			method:          http.MethodOptions,
+			origin:          "https://app.hospital.com",
+			want:            http.StatusNoContent,
+			wantAllowOrigin: "https://app.hospital.com",
+		},
+		{
+			name:            "should answer the preflight request from a disallowed origin without allowing it",
+			method:          http.MethodOptions,
+			origin:          "https://evil.com",
+			want:            http.StatusNoContent,
+			wantAllowOrigin: "",
+		},
+		{
+			name:            "should allow the request from an allowed origin",
+			method:          http.MethodGet,
+			origin:          "https://app.hospital.com",
+			want:            http.StatusOK,
+			wantAllowOrigin: "https://app.hospital.com",
+		},
+		{
+			name:            "should not allow the request from a disallowed origin",
+			method:          http.MethodGet,
+			origin:          "https://evil.com",
+			want:            http.StatusOK,
+			wantAllowOrigin: "",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := chi.NewRouter()
+			router.Use(Middleware(WithAllowedOrigins("https://app.hospital.com")))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+			req, _ := http.NewRequest(tt.method, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set(RequestMethod, http.MethodGet)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+			response := recorder.Result()
+
+			if response.StatusCode != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", response.StatusCode, tt.want)
+			}
+			if got := response.Header.Get(AllowOriginHeader); got != tt.wantAllowOrigin {
+				t.Errorf("allow origin header is incorrect, got %s, want %s", got, tt.wantAllowOrigin)
+			}
+			if tt.method == http.MethodOptions && tt.wantAllowOrigin != "" && response.Header.Get(AllowMethodsHeader) == "" {
+				t.Errorf("allow methods header should be sent")
+			}
+		})
+	}
+}
@@ -5,23 +5,41 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"hospital-booking/internal/configs"
+	"hospital-booking/internal/metrics"
 	"log"
 	"reflect"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+)
+
+const (
+	// uniqueViolationCode is the Postgres error code returned when a unique constraint is violated.
+	uniqueViolationCode = "23505"
+	// foreignKeyViolationCode is the Postgres error code returned when a row is still referenced by another one.
+	foreignKeyViolationCode = "23503"
+	// connectionExceptionClass is the class of the Postgres error codes returned when the connection fails.
+	connectionExceptionClass = "08"
+	// retryBackoff is how long to wait before the first retry, doubled for each other.
+	retryBackoff = 50 * time.Millisecond
 )
 
 type defaultConnection struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
+	retries      int
 }
 
 // Connection holds a DB instance.
 type Connection interface {
 	DB() *sql.DB
 	CreateContext(ctx context.Context) (context.Context, context.CancelFunc)
+	CreateContextWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc)
+	Retries() int
 	Close()
 }
 
@@ -30,23 +48,40 @@ func (d *defaultConnection) DB() *sql.DB {
 	return d.db
 }
 
-// CreateContext creates a new context based on the given one, with a default timeout.
+// CreateContext creates a new context based on the given one, with the configured query timeout.
 func (d *defaultConnection) CreateContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	timeout := 5 * time.Second
+	return d.CreateContextWithTimeout(ctx, d.queryTimeout)
+}
+
+// CreateContextWithTimeout creates a new context based on the given one, with the given timeout.
+func (d *defaultConnection) CreateContextWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(ctx, timeout)
 }
 
+// Retries gets how many times the reading queries are retried after a transient error.
+func (d *defaultConnection) Retries() int {
+	return d.retries
+}
+
 // NewConnection creates a new DB instance based on the given configurations.
 func NewConnection(config configs.Config) (Connection, error) {
 	db, err := sql.Open(config.DatabaseDriver(), config.DatabaseDSN())
 	if err != nil {
 		return nil, fmt.Errorf("could not create a connection: %w", err)
 	}
-	db.SetConnMaxLifetime(time.Minute * 3)
+	dbConn := newConnection(db, config)
 	if err = db.Ping(); err != nil {
 		return nil, fmt.Errorf("database is not reachable: %w", err)
 	}
-	return &defaultConnection{db: db}, nil
+	return dbConn, nil
+}
+
+// newConnection wraps the given DB instance, applying the given configurations.
+func newConnection(db *sql.DB, config configs.Config) *defaultConnection {
+	db.SetMaxOpenConns(config.MaxOpenConns())
+	db.SetMaxIdleConns(config.MaxIdleConns())
+	db.SetConnMaxLifetime(config.ConnMaxLifetime())
+	return &defaultConnection{db: db, queryTimeout: config.QueryTimeout(), retries: config.QueryRetries()}
 }
 
 // Close closes the DB connection.
@@ -65,6 +100,103 @@ func CloseRows(rows *sql.Rows) {
 	}
 }
 
+// Transaction runs the given function in a transaction, which is committed if the function succeeds and rolled
+// back otherwise.
+func Transaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Printf("could not rollback the transaction %v\n", rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// IsUniqueViolation checks if the given error was caused by a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
+
+// IsForeignKeyViolation checks if the given error was caused by a foreign key constraint violation.
+func IsForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode
+}
+
+// IsTransient checks if the given error is a transient one, as a lost connection, so the query that caused it can
+// be retried.
+func IsTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == connectionExceptionClass
+}
+
+// Retry calls the given function until it succeeds or fails with an error that isn't transient, up to the given
+// number of retries. The wait between the calls starts at 50ms and is doubled for each retry, unless the given
+// context is done first.
+func Retry(ctx context.Context, retries int, fn func() error) error {
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !IsTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Timed calls the given function, recording how long it took as the duration of the query with the given name,
+// retries included. The name labels the metric, so it must be a short one out of a bounded set, never the SQL.
+func Timed(name string, fn func() error) error {
+	startedAt := time.Now()
+	err := fn()
+	metrics.ObserveQueryDuration(name, time.Since(startedAt))
+	return err
+}
+
+// Query runs the given reading query, retrying it after the transient errors as configured on the connection and
+// timing it under the given name.
+func Query(ctx context.Context, dbConn Connection, name string, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := Timed(name, func() error {
+		return Retry(ctx, dbConn.Retries(), func() error {
+			var err error
+			rows, err = dbConn.DB().QueryContext(ctx, query, args...)
+			return err
+		})
+	})
+	return rows, err
+}
+
+// Execer runs the writing queries, either through a *sql.DB or a *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Exec runs the given writing query, timing it under the given name. The writing queries are not retried, as they
+// may have been applied before the error.
+func Exec(ctx context.Context, execer Execer, name string, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := Timed(name, func() error {
+		var err error
+		result, err = execer.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
 // TransformRow transforms the current row given by the into the given struct.
 // The transformation is performed by reflection, using a field tag called dbfield for that.
 func TransformRow(rows *sql.Rows, model interface{}) error {
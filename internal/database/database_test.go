@@ -0,0 +1,380 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hospital-booking/internal/configs"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCreateContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		configPath string
+		want       time.Duration
+	}{
+		{
+			name:       "should apply the configured query timeout",
+			configPath: "./../../test/testdata/config_database.json",
+			want:       10 * time.Second,
+		},
+		{
+			name:       "should apply the default query timeout when it is not configured",
+			configPath: "./../../test/testdata/config_valid.json",
+			want:       5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, _, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dbConn := newConnection(db, configs.MustLoad(tt.configPath))
+
+			start := time.Now()
+			ctx, cancel := dbConn.CreateContext(context.Background())
+			defer cancel()
+
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("context should have a deadline")
+			}
+			if got := deadline.Sub(start); got < tt.want || got > tt.want+time.Second {
+				t.Errorf("context deadline is incorrect, got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateContextWithTimeout(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dbConn := newConnection(db, configs.MustLoad("./../../test/testdata/config_valid.json"))
+
+	start := time.Now()
+	ctx, cancel := dbConn.CreateContextWithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("context should have a deadline")
+	}
+	if got := deadline.Sub(start); got < time.Second || got > 2*time.Second {
+		t.Errorf("context deadline is incorrect, got %v, want %v", got, time.Second)
+	}
+}
+
+func TestNewConnectionPool(t *testing.T) {
+	tests := []struct {
+		name             string
+		configPath       string
+		wantMaxOpenConns int
+	}{
+		{
+			name:             "should apply the configured max open connections",
+			configPath:       "./../../test/testdata/config_database.json",
+			wantMaxOpenConns: 50,
+		},
+		{
+			name:             "should apply the default max open connections when it is not configured",
+			configPath:       "./../../test/testdata/config_valid.json",
+			wantMaxOpenConns: 25,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, _, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dbConn := newConnection(db, configs.MustLoad(tt.configPath))
+
+			if got := dbConn.DB().Stats().MaxOpenConnections; got != tt.wantMaxOpenConns {
+				t.Errorf("max open connections is incorrect, got %d, want %d", got, tt.wantMaxOpenConns)
+			}
+		})
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		fnErr   error
+		wantErr bool
+	}{
+		{
+			name:    "should commit the transaction",
+			fnErr:   nil,
+			wantErr: false,
+		},
+		{
+			name:    "should rollback the transaction",
+			fnErr:   errors.New("insert failed"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			mock.ExpectBegin()
+			if tt.fnErr != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+			err = Transaction(context.TODO(), db, func(tx *sql.Tx) error {
+				return tt.fnErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !IsUniqueViolation(fmt.Errorf("wrapped: %w", &pq.Error{Code: uniqueViolationCode})) {
+		t.Error("the unique violation should be detected")
+	}
+	if IsUniqueViolation(errors.New("another error")) {
+		t.Error("other errors should not be taken as unique violations")
+	}
+}
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	if !IsForeignKeyViolation(fmt.Errorf("wrapped: %w", &pq.Error{Code: foreignKeyViolationCode})) {
+		t.Error("the foreign key violation should be detected")
+	}
+	if IsForeignKeyViolation(&pq.Error{Code: uniqueViolationCode}) {
+		t.Error("other errors should not be taken as foreign key violations")
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "should take a closed connection as transient",
+			err:  fmt.Errorf("wrapped: %w", sql.ErrConnDone),
+			want: true,
+		},
+		{
+			name: "should take a bad connection as transient",
+			err:  driver.ErrBadConn,
+			want: true,
+		},
+		{
+			name: "should take a Postgres connection exception as transient",
+			err:  &pq.Error{Code: "08006"},
+			want: true,
+		},
+		{
+			name: "should not take a unique violation as transient",
+			err:  &pq.Error{Code: uniqueViolationCode},
+			want: false,
+		},
+		{
+			name: "should not take a missing row as transient",
+			err:  sql.ErrNoRows,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		retries   int
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "should not retry a successful call",
+			retries:   2,
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "should retry after a transient error",
+			retries:   2,
+			errs:      []error{sql.ErrConnDone, nil},
+			wantCalls: 2,
+		},
+		{
+			name:      "should give up once the retries are exhausted",
+			retries:   2,
+			errs:      []error{sql.ErrConnDone, sql.ErrConnDone, sql.ErrConnDone, nil},
+			wantCalls: 3,
+			wantErr:   sql.ErrConnDone,
+		},
+		{
+			name:      "should not retry an error that is not transient",
+			retries:   2,
+			errs:      []error{sql.ErrNoRows, nil},
+			wantCalls: 1,
+			wantErr:   sql.ErrNoRows,
+		},
+		{
+			name:      "should not retry when the retries are disabled",
+			retries:   0,
+			errs:      []error{sql.ErrConnDone, nil},
+			wantCalls: 1,
+			wantErr:   sql.ErrConnDone,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			err := Retry(context.Background(), tt.retries, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Retry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls are incorrect, got %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := Retry(ctx, 2, func() error {
+		calls++
+		return sql.ErrConnDone
+	})
+	if !errors.Is(err, sql.ErrConnDone) {
+		t.Errorf("Retry() error = %v, want %v", err, sql.ErrConnDone)
+	}
+	if calls != 1 {
+		t.Errorf("calls are incorrect, got %d, want %d", calls, 1)
+	}
+}
+
+func TestQueryRetriesTransientErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dbConn := newConnection(db, configs.MustLoad("./../../test/testdata/config_valid.json"))
+
+	mock.ExpectQuery("SELECT name FROM tb_doctor").WillReturnError(sql.ErrConnDone)
+	mock.ExpectQuery("SELECT name FROM tb_doctor").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("John Doe"))
+
+	rows, err := Query(context.Background(), dbConn, "list_doctor_names", "SELECT name FROM tb_doctor")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	defer CloseRows(rows)
+	name := ""
+	for rows.Next() {
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name != "John Doe" {
+		t.Errorf("query result is incorrect, got %s, want %s", name, "John Doe")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// countQueryDurationSamples gets how many durations were observed for the query with the given name, as exported
+// to Prometheus.
+func countQueryDurationSamples(t *testing.T, name string) uint64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "db_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "query" && label.GetValue() == name {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestQueriesAreTimed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dbConn := newConnection(db, configs.MustLoad("./../../test/testdata/config_valid.json"))
+
+	mock.ExpectQuery("SELECT name FROM tb_doctor").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("John Doe"))
+	mock.ExpectExec("UPDATE tb_doctor SET active = false").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rows, err := Query(context.Background(), dbConn, "timed_list_doctors", "SELECT name FROM tb_doctor")
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	CloseRows(rows)
+	if _, err = Exec(context.Background(), dbConn.DB(), "timed_deactivate_doctors", "UPDATE tb_doctor SET active = false"); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	for _, name := range []string{"timed_list_doctors", "timed_deactivate_doctors"} {
+		if got := countQueryDurationSamples(t, name); got != 1 {
+			t.Errorf("samples of the query %s are incorrect, got %d, want %d", name, got, 1)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
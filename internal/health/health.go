@@ -0,0 +1,53 @@
+// Package health contains the endpoints used to check whether the system is able to handle requests.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"hospital-booking/internal/database"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const pingTimeout = 2 * time.Second
+
+type status struct {
+	Database      string `json:"database"`
+	SchemaVersion int64  `json:"schema_version,omitempty"`
+	Message       string `json:"message,omitempty"`
+}
+
+type httpHandler struct {
+	dbConn database.Connection
+}
+
+// Setup setups the health routes.
+func Setup(router *chi.Mux, dbConn database.Connection) {
+	handler := &httpHandler{dbConn: dbConn}
+	router.Get("/health/ready", handler.Ready)
+}
+
+// Ready checks whether the database is reachable and its schema is up to date, responding with 503 if it is not.
+func (h *httpHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+	if err := h.dbConn.DB().PingContext(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(status{Database: "unavailable", Message: err.Error()})
+		return
+	}
+	version, err := SchemaVersion(ctx, h.dbConn)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(status{Database: "unavailable", Message: err.Error()})
+		return
+	}
+	if version < RequiredSchemaVersion {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(status{Database: "ok", SchemaVersion: version, Message: "schema outdated"})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(status{Database: "ok", SchemaVersion: version})
+}
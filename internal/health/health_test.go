@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hospital-booking/internal/mock"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+func withSchemaVersionResult(version int64) mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(schemaVersionQuery)).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+	}
+}
+
+func withSchemaVersionError() mock.DBResultOption {
+	return func(dbConn mock.Connection) {
+		dbConn.SQLMock.ExpectQuery(regexp.QuoteMeta(schemaVersionQuery)).WillReturnError(errors.New("relation does not exist"))
+	}
+}
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name              string
+		pingErr           error
+		dbMockOptions     []mock.DBResultOption
+		want              int
+		wantDatabase      string
+		wantSchemaVersion int64
+		wantMessage       string
+	}{
+		{
+			name:              "should be ready when the database is reachable and its schema is up to date",
+			dbMockOptions:     []mock.DBResultOption{withSchemaVersionResult(RequiredSchemaVersion)},
+			want:              http.StatusOK,
+			wantDatabase:      "ok",
+			wantSchemaVersion: RequiredSchemaVersion,
+		},
+		{
+			name:         "should not be ready when the database is not reachable",
+			pingErr:      errors.New("connection refused"),
+			want:         http.StatusServiceUnavailable,
+			wantDatabase: "unavailable",
+			wantMessage:  "connection refused",
+		},
+		{
+			name:              "should not be ready when the database schema is outdated",
+			dbMockOptions:     []mock.DBResultOption{withSchemaVersionResult(RequiredSchemaVersion - 1)},
+			want:              http.StatusServiceUnavailable,
+			wantDatabase:      "ok",
+			wantSchemaVersion: RequiredSchemaVersion - 1,
+			wantMessage:       "schema outdated",
+		},
+		{
+			name:          "should not be ready when the database schema version can not be got",
+			dbMockOptions: []mock.DBResultOption{withSchemaVersionError()},
+			want:          http.StatusServiceUnavailable,
+			wantDatabase:  "unavailable",
+			wantMessage:   "an error occurred while getting the schema version: relation does not exist",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMockWithPings()
+			dbConn.SQLMock.ExpectPing().WillReturnError(tt.pingErr)
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			router := chi.NewRouter()
+			Setup(router, dbConn)
+
+			req, _ := http.NewRequest("GET", "/health/ready", nil)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			got := status{}
+			if err := json.NewDecoder(recorder.Body).Decode(&got); err != nil {
+				t.Fatalf("response body is incorrect, got error %v", err)
+			}
+			if got.Database != tt.wantDatabase {
+				t.Errorf("database status is incorrect, got %s, want %s", got.Database, tt.wantDatabase)
+			}
+			if got.SchemaVersion != tt.wantSchemaVersion {
+				t.Errorf("schema version is incorrect, got %d, want %d", got.SchemaVersion, tt.wantSchemaVersion)
+			}
+			if got.Message != tt.wantMessage {
+				t.Errorf("message is incorrect, got %s, want %s", got.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name          string
+		dbMockOptions []mock.DBResultOption
+		wantErr       bool
+	}{
+		{
+			name:          "should accept the schema at the required version",
+			dbMockOptions: []mock.DBResultOption{withSchemaVersionResult(RequiredSchemaVersion)},
+		},
+		{
+			name:          "should accept the schema ahead of the required version",
+			dbMockOptions: []mock.DBResultOption{withSchemaVersionResult(RequiredSchemaVersion + 1)},
+		},
+		{
+			name:          "should reject the schema behind the required version",
+			dbMockOptions: []mock.DBResultOption{withSchemaVersionResult(0)},
+			wantErr:       true,
+		},
+		{
+			name:          "should reject the schema when its version can not be got",
+			dbMockOptions: []mock.DBResultOption{withSchemaVersionError()},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dbConn := mock.MustCreateConnectionMock()
+			mock.MockDBResults(dbConn, tt.dbMockOptions...)
+
+			if err := CheckSchema(context.TODO(), dbConn); (err != nil) != tt.wantErr {
+				t.Errorf("CheckSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"hospital-booking/internal/database"
+)
+
+// RequiredSchemaVersion is the minimum version of the database schema this code is able to work with. The schema
+// migrations are run out of band, recording their version in the schema_migrations table.
+const RequiredSchemaVersion = 9
+
+const schemaVersionQuery = "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+
+// SchemaVersion gets the current version of the database schema.
+func SchemaVersion(ctx context.Context, dbConn database.Connection) (int64, error) {
+	ctx, cancel := dbConn.CreateContext(ctx)
+	defer cancel()
+	var version int64
+	if err := dbConn.DB().QueryRowContext(ctx, schemaVersionQuery).Scan(&version); err != nil {
+		return 0, fmt.Errorf("an error occurred while getting the schema version: %w", err)
+	}
+	return version, nil
+}
+
+// CheckSchema checks whether the database schema is at least at the RequiredSchemaVersion.
+func CheckSchema(ctx context.Context, dbConn database.Connection) error {
+	version, err := SchemaVersion(ctx, dbConn)
+	if err != nil {
+		return err
+	}
+	if version < RequiredSchemaVersion {
+		return fmt.Errorf("the database schema version %d is behind the required version %d", version, RequiredSchemaVersion)
+	}
+	return nil
+}
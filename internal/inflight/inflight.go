@@ -0,0 +1,32 @@
+// Package inflight contains the middleware used to track the requests being handled, so the server can tell how
+// many of them are cut off on shutdown.
+package inflight
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Counter counts the requests being handled.
+type Counter struct {
+	active int64
+}
+
+// NewCounter creates a new Counter.
+func NewCounter() *Counter {
+	return &Counter{}
+}
+
+// Count gets how many requests are being handled.
+func (c *Counter) Count() int64 {
+	return atomic.LoadInt64(&c.active)
+}
+
+// Middleware counts the given request as in flight until the next handlers return, even if they panic.
+func (c *Counter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&c.active, 1)
+		defer atomic.AddInt64(&c.active, -1)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,33 @@
+package inflight
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCounterMiddleware(t *testing.T) {
+	counter := NewCounter()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := counter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		close(done)
+	}()
+
+	<-started
+	if got := counter.Count(); got != 1 {
+		t.Errorf("Count() while handling = %d, want %d", got, 1)
+	}
+	close(release)
+	<-done
+	if got := counter.Count(); got != 0 {
+		t.Errorf("Count() after handling = %d, want %d", got, 0)
+	}
+}
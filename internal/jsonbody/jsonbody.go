@@ -0,0 +1,47 @@
+// Package jsonbody contains the helpers used to read JSON request bodies.
+package jsonbody
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/apierrors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// unknownFieldPrefix prefixes the errors returned by the decoder when an unknown field is found, which
+	// aren't typed.
+	unknownFieldPrefix = "json: unknown field "
+
+	// bodyTooLargeMessage is the error returned by http.MaxBytesReader once the body exceeds its limit.
+	bodyTooLargeMessage = "http: request body too large"
+)
+
+const (
+	ErrBodyTooLarge  = "request body too large"
+	CodeBodyTooLarge = "REQUEST_BODY_TOO_LARGE"
+)
+
+// Decode decodes the request body into the given value, rejecting the fields it doesn't have, so a typo'd
+// field name isn't silently taken as its zero value. Unknown fields are returned as ValidationError, and bodies
+// exceeding the limit set by http.MaxBytesReader as an APIError with the 413 status code.
+func Decode(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		return nil
+	}
+	if err.Error() == bodyTooLargeMessage {
+		return apierrors.NewAPIError(apierrors.WithSource(err), apierrors.WithDetail(ErrBodyTooLarge), apierrors.WithCode(CodeBodyTooLarge), apierrors.WithHTTPStatusCode(http.StatusRequestEntityTooLarge))
+	}
+	if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		field := strings.TrimPrefix(err.Error(), unknownFieldPrefix)
+		if unquoted, unquoteErr := strconv.Unquote(field); unquoteErr == nil {
+			field = unquoted
+		}
+		return apierrors.NewValidationError(field, "unknown field")
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	redacted            = "[REDACTED]"
+	authorizationHeader = "Authorization"
+)
+
+// credentialKeys are the parts of the JSON keys whose values are credentials, such as the passwords and the access
+// and refresh tokens, matched ignoring their case.
+var credentialKeys = []string{"password", "token", "secret"}
+
+// isCredential checks if the given JSON key holds a credential.
+func isCredential(key string) bool {
+	key = strings.ToLower(key)
+	for _, credentialKey := range credentialKeys {
+		if strings.Contains(key, credentialKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact replaces the value of the credential fields found in the given JSON value, at any depth.
+func redact(v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if isCredential(key) {
+				value[key] = redacted
+				continue
+			}
+			redact(field)
+		}
+	case []interface{}:
+		for _, item := range value {
+			redact(item)
+		}
+	}
+}
+
+// redactBody gets the given body with its credential fields redacted. Bodies that are not JSON can't be redacted,
+// so only their size is given.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Sprintf("[%d bytes]", len(body))
+	}
+	redact(v)
+	redactedBody, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("[%d bytes]", len(body))
+	}
+	return string(redactedBody)
+}
+
+// redactHeaders gets a copy of the given headers with the Authorization one redacted.
+func redactHeaders(headers http.Header) http.Header {
+	redactedHeaders := headers.Clone()
+	if redactedHeaders.Get(authorizationHeader) != "" {
+		redactedHeaders.Set(authorizationHeader, redacted)
+	}
+	return redactedHeaders
+}
+
+// errorReader is a reader that always fails with the given error.
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// BodyMiddleware logs the request and response bodies through the given logger, for debugging purposes. The
+// credential fields and the Authorization header are redacted, and the request body is restored so the handlers
+// still read it. If the body couldn't be read, e.g. it exceeds the limit, the handlers get the same error once they
+// read what was read of it, so they respond as if the body was not logged.
+func BodyMiddleware(logger *log.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requestID := middleware.GetReqID(request.Context())
+			body, err := ioutil.ReadAll(request.Body)
+			var restoredBody io.Reader = bytes.NewReader(body)
+			if err != nil {
+				PrintlnError(logger, NewMessage(requestID, fmt.Errorf("an error occurred while reading the request body: %w", err)))
+				restoredBody = io.MultiReader(restoredBody, errorReader{err: err})
+			}
+			request.Body = ioutil.NopCloser(restoredBody)
+			PrintlnInfo(logger, NewMessage(requestID, fmt.Sprintf("request %s %s headers=%v body=%s", request.Method, request.URL.RequestURI(), redactHeaders(request.Header), redactBody(body))))
+
+			responseBody := &bytes.Buffer{}
+			wrappedWriter := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+			wrappedWriter.Tee(responseBody)
+			next.ServeHTTP(wrappedWriter, request)
+			PrintlnInfo(logger, NewMessage(requestID, fmt.Sprintf("response %d body=%s", wrappedWriter.Status(), redactBody(responseBody.Bytes()))))
+		})
+	}
+}
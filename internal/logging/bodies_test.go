@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"bytes"
+	"hospital-booking/internal/bodylimit"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		authorization string
+		response      string
+		wantLogged    []string
+		wantNotLogged []string
+	}{
+		{
+			name:          "should redact the password of the request body",
+			body:          `{"email":"patient@hospital.com","password":"secret123"}`,
+			response:      `{"access_token":"token"}`,
+			wantLogged:    []string{`"email":"patient@hospital.com"`, `"password":"[REDACTED]"`},
+			wantNotLogged: []string{"secret123"},
+		},
+		{
+			name:          "should redact the tokens of the response body",
+			body:          `{"email":"patient@hospital.com","password":"secret123"}`,
+			response:      `{"access_token":"secret-access","refresh_token":"secret-refresh"}`,
+			wantLogged:    []string{`response 200 body={"access_token":"[REDACTED]","refresh_token":"[REDACTED]"}`},
+			wantNotLogged: []string{"secret-access", "secret-refresh"},
+		},
+		{
+			name:          "should redact the refresh token of the request body",
+			body:          `{"refresh_token":"secret-refresh"}`,
+			wantLogged:    []string{`"refresh_token":"[REDACTED]"`},
+			wantNotLogged: []string{"secret-refresh"},
+		},
+		{
+			name:          "should redact the nested password fields",
+			body:          `{"old_password":"secret123","new_password":"secret456"}`,
+			wantLogged:    []string{`"old_password":"[REDACTED]"`, `"new_password":"[REDACTED]"`},
+			wantNotLogged: []string{"secret123", "secret456"},
+		},
+		{
+			name:          "should redact the authorization header",
+			authorization: "Bearer secret-token",
+			wantLogged:    []string{"Authorization:[[REDACTED]]"},
+			wantNotLogged: []string{"secret-token"},
+		},
+		{
+			name:          "should only log the size of the bodies that are not JSON",
+			body:          "password=secret123",
+			wantLogged:    []string{"body=[18 bytes]"},
+			wantNotLogged: []string{"secret123"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			logs := &bytes.Buffer{}
+			received := ""
+			handler := BodyMiddleware(log.New(logs, "", 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				received = string(body)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+
+			req, _ := http.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(tt.body))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if received != tt.body {
+				t.Errorf("handler body is incorrect, got %s, want %s", received, tt.body)
+			}
+			for _, want := range tt.wantLogged {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("logs should contain %s, got %s", want, logs.String())
+				}
+			}
+			for _, notWant := range tt.wantNotLogged {
+				if strings.Contains(logs.String(), notWant) {
+					t.Errorf("logs should not contain %s, got %s", notWant, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestBodyMiddlewareForwardsTheReadError(t *testing.T) {
+	var readErr error
+	handler := bodylimit.Middleware(10)(BodyMiddleware(log.New(ioutil.Discard, "", 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+	})))
+
+	req, _ := http.NewRequest("POST", "/api/v1/auth/login", strings.NewReader(strings.Repeat("a", 11)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if readErr == nil || readErr.Error() != "http: request body too large" {
+		t.Errorf("the handler should get the error that interrupted the reading, got %v", readErr)
+	}
+}
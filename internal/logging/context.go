@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type ctxKeyLogger string
+
+const LoggerContextKey ctxKeyLogger = "logger"
+
+// ContextLogger is a logger bound to a request, that prefixes every message with the request ID.
+type ContextLogger struct {
+	logger    *log.Logger
+	requestID string
+}
+
+// NewContext returns a copy of the given context associated with the given logger.
+func NewContext(ctx context.Context, logger *log.Logger) context.Context {
+	return context.WithValue(ctx, LoggerContextKey, logger)
+}
+
+// FromContext creates a ContextLogger based on the logger and the request ID associated with the given
+// context. If there is no logger associated with it, the standard logger is used.
+func FromContext(ctx context.Context) *ContextLogger {
+	logger, isLogger := ctx.Value(LoggerContextKey).(*log.Logger)
+	if !isLogger {
+		logger = log.Default()
+	}
+	return &ContextLogger{logger: logger, requestID: middleware.GetReqID(ctx)}
+}
+
+// Debug prints out the given value as a debug message.
+func (c *ContextLogger) Debug(v interface{}) {
+	PrintlnDebug(c.logger, NewMessage(c.requestID, v))
+}
+
+// Info prints out the given value as an info message.
+func (c *ContextLogger) Info(v interface{}) {
+	PrintlnInfo(c.logger, NewMessage(c.requestID, v))
+}
+
+// Warn prints out the given value as a warn message.
+func (c *ContextLogger) Warn(v interface{}) {
+	PrintlnWarn(c.logger, NewMessage(c.requestID, v))
+}
+
+// Error prints out the given error as an error message.
+func (c *ContextLogger) Error(err error) {
+	PrintlnError(c.logger, NewMessage(c.requestID, err))
+}
+
+// Middleware associates the given logger to the request's context, so handlers can get it
+// through FromContext.
+func Middleware(logger *log.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			ctx := NewContext(request.Context(), logger)
+			next.ServeHTTP(writer, request.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		log    func(logger *ContextLogger)
+	}{
+		{
+			name:   "should log an info line with the request ID",
+			format: TextFormat,
+			log: func(logger *ContextLogger) {
+				logger.Info("calendar requested")
+			},
+		},
+		{
+			name:   "should log an error line with the request ID",
+			format: TextFormat,
+			log: func(logger *ContextLogger) {
+				logger.Error(errors.New("not authorized"))
+			},
+		},
+		{
+			name:   "should log a JSON line with the request ID field",
+			format: JSONFormat,
+			log: func(logger *ContextLogger) {
+				logger.Warn("slow query")
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			requestID := ""
+
+			router := chi.NewRouter()
+			router.Use(middleware.RequestID)
+			router.Use(Middleware(New(tt.format, buf)))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				requestID = middleware.GetReqID(r.Context())
+				tt.log(FromContext(r.Context()))
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if requestID == "" {
+				t.Fatal("request ID was not populated by chi's middleware")
+			}
+			if !strings.Contains(buf.String(), requestID) {
+				t.Errorf("logged line doesn't contain the request ID %s, got %s", requestID, buf.String())
+			}
+		})
+	}
+}
+
+func TestFromContextWithIncomingRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "should log the request ID given by the upstream gateway",
+			format: TextFormat,
+			want:   "gateway-4f1c2b calendar requested",
+		},
+		{
+			name:   "should log the request ID given by the upstream gateway as the request ID field",
+			format: JSONFormat,
+			want:   `"request_id":"gateway-4f1c2b"`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			router := chi.NewRouter()
+			router.Use(middleware.RequestID)
+			router.Use(Middleware(New(tt.format, buf)))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				FromContext(r.Context()).Info("calendar requested")
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(middleware.RequestIDHeader, "gateway-4f1c2b")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("logged line doesn't contain %s, got %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	output := log.Writer()
+	defer log.SetOutput(output)
+
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	FromContext(req.Context()).Info("no logger")
+
+	if !strings.Contains(buf.String(), "no logger") {
+		t.Errorf("logged line is incorrect, got %s", buf.String())
+	}
+}
@@ -2,9 +2,14 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
 var color = true
@@ -21,22 +26,151 @@ const (
 	WarnColor  = "\033[33m"
 )
 
-func PrintlnInfo(logger *log.Logger, v interface{}) {
-	logger.Println(fmt.Sprint(v))
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Level represents the severity of a log entry.
+type Level string
+
+const (
+	DebugLevel Level = "DEBUG"
+	InfoLevel  Level = "INFO"
+	WarnLevel  Level = "WARN"
+	ErrorLevel Level = "ERROR"
+)
+
+// severities orders the levels, so the entries below the level of a logger can be suppressed.
+var severities = map[Level]int{
+	DebugLevel: 0,
+	InfoLevel:  1,
+	WarnLevel:  2,
+	ErrorLevel: 3,
 }
 
-func PrintlnWarn(logger *log.Logger, v interface{}) {
-	if color {
-		logger.Println(WarnColor + fmt.Sprint(v) + resetColor)
-		return
+// Enabled checks if the entries of the given level are printed by a logger with the level.
+func (l Level) Enabled(level Level) bool {
+	return severities[level] >= severities[l]
+}
+
+// Message represents a log message associated to a request.
+type Message struct {
+	RequestID string
+	Value     interface{}
+}
+
+// NewMessage creates a new Message based on the given params.
+func NewMessage(requestID string, v interface{}) Message {
+	return Message{RequestID: requestID, Value: v}
+}
+
+func (m Message) String() string {
+	if m.RequestID == "" {
+		return fmt.Sprint(m.Value)
 	}
-	logger.Println(fmt.Sprint(v))
+	return fmt.Sprint(m.RequestID, " ", m.Value)
 }
 
-func PrintlnError(logger *log.Logger, v interface{}) {
-	if color {
-		logger.Println(ErrorColor + fmt.Sprint(v) + resetColor)
+type jsonEntry struct {
+	Level     Level     `json:"level"`
+	Message   string    `json:"msg"`
+	RequestID string    `json:"request_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// jsonWriter writes log entries as JSON objects, one per line.
+type jsonWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// Write writes the lines printed directly through the logger, as the ones printed by the HTTP server.
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	if err := j.writeEntry(InfoLevel, strings.TrimSuffix(string(p), "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (j *jsonWriter) writeEntry(level Level, v interface{}) error {
+	entry := jsonEntry{Level: level, Timestamp: time.Now().UTC()}
+	if msg, isMessage := v.(Message); isMessage {
+		entry.RequestID = msg.RequestID
+		entry.Message = fmt.Sprint(msg.Value)
+	} else {
+		entry.Message = fmt.Sprint(v)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return json.NewEncoder(j.out).Encode(entry)
+}
+
+// NewJSONLogger creates a logger that emits one JSON object per line, with the level, msg, request_id
+// and timestamp fields.
+func NewJSONLogger(w io.Writer) *log.Logger {
+	return log.New(&jsonWriter{out: w}, "", 0)
+}
+
+// levelWriter suppresses the entries below its level, passing the others to the underlying writer.
+type levelWriter struct {
+	out   io.Writer
+	level Level
+}
+
+// Write writes the lines printed directly through the logger, which are never suppressed.
+func (l *levelWriter) Write(p []byte) (int, error) {
+	return l.out.Write(p)
+}
+
+// WithLevel creates a copy of the given logger that suppresses the entries below the given level.
+func WithLevel(logger *log.Logger, level Level) *log.Logger {
+	out := logger.Writer()
+	if writer, isLeveled := out.(*levelWriter); isLeveled {
+		out = writer.out
+	}
+	return log.New(&levelWriter{out: out, level: level}, logger.Prefix(), logger.Flags())
+}
+
+// New creates a logger that writes to the given writer using the given format, which defaults to text.
+func New(format string, w io.Writer) *log.Logger {
+	if format == JSONFormat {
+		return NewJSONLogger(w)
+	}
+	return log.New(w, "", log.LstdFlags)
+}
+
+func output(logger *log.Logger, level Level, levelColor string, v interface{}) {
+	out := logger.Writer()
+	if writer, isLeveled := out.(*levelWriter); isLeveled {
+		if !writer.level.Enabled(level) {
+			return
+		}
+		out = writer.out
+	}
+	if writer, isJSON := out.(*jsonWriter); isJSON {
+		_ = writer.writeEntry(level, v)
+		return
+	}
+	if color && levelColor != "" {
+		logger.Println(levelColor + fmt.Sprint(v) + resetColor)
 		return
 	}
 	logger.Println(fmt.Sprint(v))
 }
+
+func PrintlnDebug(logger *log.Logger, v interface{}) {
+	output(logger, DebugLevel, "", v)
+}
+
+func PrintlnInfo(logger *log.Logger, v interface{}) {
+	output(logger, InfoLevel, "", v)
+}
+
+func PrintlnWarn(logger *log.Logger, v interface{}) {
+	output(logger, WarnLevel, WarnColor, v)
+}
+
+func PrintlnError(logger *log.Logger, v interface{}) {
+	output(logger, ErrorLevel, ErrorColor, v)
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewJSONLogger(t *testing.T) {
+	type entry struct {
+		Level     string    `json:"level"`
+		Message   string    `json:"msg"`
+		RequestID string    `json:"request_id"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	tests := []struct {
+		name  string
+		print func(buf *bytes.Buffer)
+		want  entry
+	}{
+		{
+			name: "should emit an info entry",
+			print: func(buf *bytes.Buffer) {
+				PrintlnInfo(NewJSONLogger(buf), "server started")
+			},
+			want: entry{Level: "INFO", Message: "server started"},
+		},
+		{
+			name: "should emit a warn entry",
+			print: func(buf *bytes.Buffer) {
+				PrintlnWarn(NewJSONLogger(buf), "slow query")
+			},
+			want: entry{Level: "WARN", Message: "slow query"},
+		},
+		{
+			name: "should emit an error entry with the request ID as a field",
+			print: func(buf *bytes.Buffer) {
+				PrintlnError(NewJSONLogger(buf), NewMessage("host/abc-000001", errors.New("not authorized")))
+			},
+			want: entry{Level: "ERROR", Message: "not authorized", RequestID: "host/abc-000001"},
+		},
+		{
+			name: "should emit an entry for lines printed directly through the logger",
+			print: func(buf *bytes.Buffer) {
+				NewJSONLogger(buf).Println("http: TLS handshake error")
+			},
+			want: entry{Level: "INFO", Message: "http: TLS handshake error"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			tt.print(buf)
+
+			scanner := bufio.NewScanner(buf)
+			lines := 0
+			for scanner.Scan() {
+				lines++
+				got := entry{}
+				if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+					t.Fatalf("an error occurred while parsing the log entry %s: %v", scanner.Text(), err)
+				}
+				if got.Timestamp.IsZero() {
+					t.Errorf("timestamp is missing in %s", scanner.Text())
+				}
+				got.Timestamp = time.Time{}
+				if got != tt.want {
+					t.Errorf("log entry is incorrect, got %+v, want %+v", got, tt.want)
+				}
+			}
+			if lines != 1 {
+				t.Errorf("number of emitted lines is incorrect, got %d, want 1", lines)
+			}
+		})
+	}
+}
+
+func TestTextLogger(t *testing.T) {
+	color = false
+	buf := new(bytes.Buffer)
+	PrintlnError(New(TextFormat, buf), NewMessage("host/abc-000001", errors.New("not authorized")))
+	if !bytes.HasSuffix(buf.Bytes(), []byte("host/abc-000001 not authorized\n")) {
+		t.Errorf("log line is incorrect, got %s", buf.String())
+	}
+}
+
+func TestWithLevel(t *testing.T) {
+	color = false
+	tests := []struct {
+		name     string
+		format   string
+		level    Level
+		print    func(logger *log.Logger, v interface{})
+		wantLine bool
+	}{
+		{
+			name:     "should suppress the info message at warn level",
+			format:   TextFormat,
+			level:    WarnLevel,
+			print:    PrintlnInfo,
+			wantLine: false,
+		},
+		{
+			name:     "should emit the info message at info level",
+			format:   TextFormat,
+			level:    InfoLevel,
+			print:    PrintlnInfo,
+			wantLine: true,
+		},
+		{
+			name:     "should emit the error message at warn level",
+			format:   TextFormat,
+			level:    WarnLevel,
+			print:    PrintlnError,
+			wantLine: true,
+		},
+		{
+			name:     "should suppress the debug message at info level",
+			format:   TextFormat,
+			level:    InfoLevel,
+			print:    PrintlnDebug,
+			wantLine: false,
+		},
+		{
+			name:     "should emit the debug message at debug level",
+			format:   TextFormat,
+			level:    DebugLevel,
+			print:    PrintlnDebug,
+			wantLine: true,
+		},
+		{
+			name:     "should suppress the info JSON entry at warn level",
+			format:   JSONFormat,
+			level:    WarnLevel,
+			print:    PrintlnInfo,
+			wantLine: false,
+		},
+		{
+			name:     "should emit the info JSON entry at info level",
+			format:   JSONFormat,
+			level:    InfoLevel,
+			print:    PrintlnInfo,
+			wantLine: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := WithLevel(New(tt.format, buf), tt.level)
+			tt.print(logger, NewMessage("host/abc-000001", "appointment created"))
+			if got := strings.Contains(buf.String(), "appointment created"); got != tt.wantLine {
+				t.Errorf("message emitted = %v, want %v, got %q", got, tt.wantLine, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Appointments booked total counter, by the doctor's specialty
+var appointmentsCreated = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "appointments_created_total",
+		Help: "Appointments booked.",
+	},
+	[]string{"specialty"},
+)
+
+// Calendar blockers created total counter
+var blockersCreated = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "blockers_created_total",
+		Help: "Calendar blockers created.",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(appointmentsCreated, blockersCreated)
+}
+
+// AppointmentCreated counts an appointment booked with a doctor of the given specialty.
+func AppointmentCreated(specialty string) {
+	appointmentsCreated.WithLabelValues(specialty).Inc()
+}
+
+// BlockersCreated counts the given number of calendar blockers created.
+func BlockersCreated(count int) {
+	blockersCreated.Add(float64(count))
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Database queries duration, by query name
+var queryDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "db_query_duration_seconds",
+		Help: "Database Queries Duration",
+	},
+	[]string{"query"},
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration)
+}
+
+// ObserveQueryDuration records how long the query with the given name took. The name must be a short label out
+// of a bounded set, as "list_appointments", never the SQL itself.
+func ObserveQueryDuration(name string, duration time.Duration) {
+	queryDuration.WithLabelValues(name).Observe(duration.Seconds())
+}
@@ -13,6 +13,8 @@ import (
 type Connection struct {
 	db      *sql.DB
 	SQLMock sqlmock.Sqlmock
+	// QueryRetries is how many times the reading queries are retried, none by default.
+	QueryRetries int
 }
 
 func (m Connection) CreateContext(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -20,6 +22,14 @@ func (m Connection) CreateContext(ctx context.Context) (context.Context, context
 	return context.WithTimeout(ctx, timeout)
 }
 
+func (m Connection) CreateContextWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (m Connection) Retries() int {
+	return m.QueryRetries
+}
+
 func (m Connection) DB() *sql.DB {
 	return m.db
 }
@@ -39,6 +49,19 @@ func MustCreateConnectionMock() Connection {
 	}
 }
 
+// MustCreateConnectionMockWithPings creates a connection mock that also observes the pings performed
+// against the database, which can be mocked through ExpectPing.
+func MustCreateConnectionMockWithPings() Connection {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		panic(err)
+	}
+	return Connection{
+		db:      db,
+		SQLMock: mock,
+	}
+}
+
 type DBResultOption func(dbConn Connection)
 
 func MockDBResults(dbConn Connection, opts ...DBResultOption) {
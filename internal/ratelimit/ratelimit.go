@@ -0,0 +1,151 @@
+// Package ratelimit contains a token bucket rate limiter and the middleware used to apply it
+// to HTTP requests.
+package ratelimit
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/apierrors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ErrTooManyRequests  = "too many requests"
+	CodeTooManyRequests = "TOO_MANY_REQUESTS"
+)
+
+const (
+	LimitHeader      = "X-RateLimit-Limit"
+	RemainingHeader  = "X-RateLimit-Remaining"
+	ResetHeader      = "X-RateLimit-Reset"
+	RetryAfterHeader = "Retry-After"
+)
+
+// Status holds the budget of a client after taking a token from its bucket.
+type Status struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+	Allowed   bool
+	// RetryAfter is how long the client must wait until its next request is allowed, when it has no budget left.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter keyed by client. Each client can perform up to limit
+// requests per window, and its bucket is refilled continuously along the window.
+//
+// Buckets idle for a whole window are full again, so they are periodically removed to avoid unbounded memory usage.
+type Limiter struct {
+	mu          sync.Mutex
+	limit       int
+	window      time.Duration
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+// NewLimiter creates a new Limiter allowing the given limit of requests per window.
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// rate returns how many tokens are refilled per second.
+func (l *Limiter) rate() float64 {
+	return float64(l.limit) / l.window.Seconds()
+}
+
+// cleanup removes the buckets that have been idle for at least a window, which is performed once per window.
+func (l *Limiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < l.window {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// Take takes a token from the bucket associated to the given key, returning the resulting status.
+func (l *Limiter) Take(key string) Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.cleanup(now)
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: float64(l.limit), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.limit), b.tokens+now.Sub(b.last).Seconds()*l.rate())
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	reset := time.Duration((float64(l.limit) - b.tokens) / l.rate() * float64(time.Second))
+	var retryAfter time.Duration
+	if !allowed {
+		retryAfter = time.Duration((1 - b.tokens) / l.rate() * float64(time.Second))
+	}
+	return Status{
+		Limit:      l.limit,
+		Remaining:  int(math.Floor(b.tokens)),
+		Reset:      reset,
+		Allowed:    allowed,
+		RetryAfter: retryAfter,
+	}
+}
+
+// ClientIP gets the IP of the client that performed the given request. If the RealIP middleware is in
+// place, the IP given by the X-Forwarded-For or X-Real-IP headers is used.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeHeaders writes the rate limit headers based on the given status.
+func writeHeaders(w http.ResponseWriter, status Status) {
+	w.Header().Set(LimitHeader, strconv.Itoa(status.Limit))
+	w.Header().Set(RemainingHeader, strconv.Itoa(status.Remaining))
+	w.Header().Set(ResetHeader, strconv.Itoa(int(math.Ceil(status.Reset.Seconds()))))
+}
+
+// Middleware limits the requests per client IP using the given limiter, exposing the caller's current
+// budget through the X-RateLimit-* headers.
+//
+// If the client has no budget left, abort the request with a 429 status, telling through the Retry-After
+// header how many seconds the client must wait until its next request is allowed.
+func Middleware(limiter *Limiter) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			status := limiter.Take(ClientIP(request))
+			writeHeaders(writer, status)
+			if !status.Allowed {
+				writer.Header().Set(RetryAfterHeader, strconv.Itoa(int(math.Max(1, math.Ceil(status.RetryAfter.Seconds())))))
+				writer.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(writer).Encode(apierrors.NewAPIError(apierrors.WithDetail(ErrTooManyRequests), apierrors.WithCode(CodeTooManyRequests), apierrors.WithHTTPStatusCode(http.StatusTooManyRequests)))
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
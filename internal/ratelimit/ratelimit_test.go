@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddleware(t *testing.T) {
+	type request struct {
+		elapsed        time.Duration
+		want           int
+		wantRemaining  string
+		wantReset      string
+		wantRetryAfter string
+	}
+	tests := []struct {
+		name     string
+		limit    int
+		window   time.Duration
+		requests []request
+	}{
+		{
+			name:   "should decrement the remaining budget across successive requests",
+			limit:  3,
+			window: time.Minute,
+			requests: []request{
+				{want: http.StatusOK, wantRemaining: "2", wantReset: "20"},
+				{want: http.StatusOK, wantRemaining: "1", wantReset: "40"},
+				{want: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+			},
+		},
+		{
+			name:   "should abort the request when the budget is exhausted",
+			limit:  2,
+			window: time.Minute,
+			requests: []request{
+				{want: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+				{want: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+				{want: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "60", wantRetryAfter: "30"},
+			},
+		},
+		{
+			name:   "should tell the client to retry once a token is refilled",
+			limit:  2,
+			window: time.Minute,
+			requests: []request{
+				{want: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+				{want: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+				{elapsed: 10 * time.Second, want: http.StatusTooManyRequests, wantRemaining: "0", wantReset: "50", wantRetryAfter: "20"},
+				{elapsed: 20 * time.Second, want: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+			},
+		},
+		{
+			name:   "should reset the budget after the window",
+			limit:  2,
+			window: time.Minute,
+			requests: []request{
+				{want: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+				{want: http.StatusOK, wantRemaining: "0", wantReset: "60"},
+				{elapsed: time.Minute, want: http.StatusOK, wantRemaining: "1", wantReset: "30"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			current := time.Now()
+			limiter := NewLimiter(tt.limit, tt.window)
+			limiter.now = func() time.Time {
+				return current
+			}
+
+			router := chi.NewRouter()
+			router.Use(Middleware(limiter))
+			router.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+			for i, req := range tt.requests {
+				current = current.Add(req.elapsed)
+
+				httpReq, _ := http.NewRequest("GET", "/", nil)
+				httpReq.RemoteAddr = "10.0.0.1:1234"
+
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httpReq)
+				response := recorder.Result()
+
+				if response.StatusCode != req.want {
+					t.Errorf("request %d: response status is incorrect, got %d, want %d", i, response.StatusCode, req.want)
+				}
+				if got := response.Header.Get(LimitHeader); got != strconv.Itoa(tt.limit) {
+					t.Errorf("request %d: limit header is incorrect, got %s, want %d", i, got, tt.limit)
+				}
+				if got := response.Header.Get(RemainingHeader); got != req.wantRemaining {
+					t.Errorf("request %d: remaining header is incorrect, got %s, want %s", i, got, req.wantRemaining)
+				}
+				if got := response.Header.Get(ResetHeader); got != req.wantReset {
+					t.Errorf("request %d: reset header is incorrect, got %s, want %s", i, got, req.wantReset)
+				}
+				if got := response.Header.Get(RetryAfterHeader); got != req.wantRetryAfter {
+					t.Errorf("request %d: retry after header is incorrect, got %s, want %s", i, got, req.wantRetryAfter)
+				}
+				if req.want == http.StatusTooManyRequests {
+					if got, want := recorder.Body.String(), "{\"message\":\"too many requests\",\"code\":\"TOO_MANY_REQUESTS\"}\n"; got != want {
+						t.Errorf("request %d: response body is incorrect, got %s, want %s", i, got, want)
+					}
+				}
+				if req.wantRetryAfter != "" {
+					if seconds, err := strconv.Atoi(response.Header.Get(RetryAfterHeader)); err != nil || seconds <= 0 {
+						t.Errorf("request %d: retry after header should be a positive integer, got %s", i, response.Header.Get(RetryAfterHeader))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareKeysByClientIP(t *testing.T) {
+	limiter := NewLimiter(1, time.Minute)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(limiter))
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, remoteAddr := range []string{"10.0.0.1:1234", "10.0.0.2:1234"} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("response status for %s is incorrect, got %d, want %d", remoteAddr, recorder.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestLimiterCleanup(t *testing.T) {
+	current := time.Now()
+	limiter := NewLimiter(1, time.Minute)
+	limiter.now = func() time.Time {
+		return current
+	}
+
+	limiter.Take("10.0.0.1")
+	current = current.Add(30 * time.Second)
+	limiter.Take("10.0.0.2")
+	current = current.Add(40 * time.Second)
+	limiter.Take("10.0.0.3")
+
+	if _, found := limiter.buckets["10.0.0.1"]; found {
+		t.Errorf("idle bucket should be removed")
+	}
+	if _, found := limiter.buckets["10.0.0.2"]; !found {
+		t.Errorf("active bucket should be kept")
+	}
+}
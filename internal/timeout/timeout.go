@@ -0,0 +1,24 @@
+// Package timeout contains the middleware used to limit how long the requests can be handled.
+package timeout
+
+import (
+	"encoding/json"
+	"hospital-booking/internal/apierrors"
+	"net/http"
+	"time"
+)
+
+const (
+	ErrRequestTimeout  = "request timeout"
+	CodeRequestTimeout = "REQUEST_TIMEOUT"
+)
+
+// Middleware limits the requests to the given duration. Once it is exceeded, the request's context is cancelled,
+// so the pending database queries are aborted, and the request is answered with a 503 status, describing the
+// reason through an APIError body. Whatever the handler writes afterwards is discarded.
+func Middleware(timeout time.Duration) func(next http.Handler) http.Handler {
+	body, _ := json.Marshal(apierrors.NewAPIError(apierrors.WithDetail(ErrRequestTimeout), apierrors.WithCode(CodeRequestTimeout)))
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, string(body)+"\n")
+	}
+}
@@ -0,0 +1,51 @@
+package timeout
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		delay        time.Duration
+		want         int
+		wantResponse string
+	}{
+		{
+			name:         "should respond before the timeout",
+			delay:        0,
+			want:         http.StatusOK,
+			wantResponse: "done",
+		},
+		{
+			name:         "should abort a slow request",
+			delay:        time.Second,
+			want:         http.StatusServiceUnavailable,
+			wantResponse: "{\"message\":\"request timeout\",\"code\":\"REQUEST_TIMEOUT\"}\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			handler := Middleware(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(tt.delay):
+					_, _ = w.Write([]byte("done"))
+				case <-r.Context().Done():
+				}
+			}))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != tt.want {
+				t.Errorf("response status is incorrect, got %d, want %d", recorder.Code, tt.want)
+			}
+			if recorder.Body.String() != tt.wantResponse {
+				t.Errorf("response body is incorrect, got %s, want %s", recorder.Body.String(), tt.wantResponse)
+			}
+		})
+	}
+}